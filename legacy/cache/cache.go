@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"please/config"
+	"please/providers"
+	"please/types"
+)
+
+// Defaults used when types.CacheConfig leaves a value at zero
+const (
+	DefaultTTL     = 7 * 24 * time.Hour
+	DefaultMaxSize = 50 * 1024 * 1024
+)
+
+// statsFile keeps hit/miss counters alongside the cached entries
+const statsFile = "stats.json"
+
+// Cache stores generated scripts on disk, one JSON file per request key
+type Cache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+}
+
+// entry is the on-disk form of a cached response
+type entry struct {
+	CreatedAt time.Time            `json:"created_at"`
+	Response  types.ScriptResponse `json:"response"`
+}
+
+// counters are the persisted lookup statistics
+type counters struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// Stats summarises the cache contents for "please cache stats"
+type Stats struct {
+	Dir       string
+	Entries   int
+	Expired   int
+	SizeBytes int64
+	MaxBytes  int64
+	TTL       time.Duration
+	Oldest    time.Time
+	Newest    time.Time
+	Hits      int
+	Misses    int
+}
+
+// New creates a cache in dir; zero ttl or maxBytes use the defaults
+func New(dir string, ttl time.Duration, maxBytes int64) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxSize
+	}
+	return &Cache{dir: dir, ttl: ttl, maxBytes: maxBytes, now: time.Now}
+}
+
+// Open creates the cache under the config directory using the settings in cfg
+func Open(cfg *types.Config) (*Cache, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var settings types.CacheConfig
+	if cfg != nil {
+		settings = cfg.Cache
+	}
+
+	return New(
+		filepath.Join(configDir, "cache"),
+		time.Duration(settings.TTLHours)*time.Hour,
+		int64(settings.MaxSizeMB)*1024*1024,
+	), nil
+}
+
+// Key hashes the prompt sent to the provider together with model, provider and script type
+func Key(request *types.ScriptRequest) string {
+	parts := []string{
+		providers.CreateRequestPrompt(request),
+		request.Model,
+		request.Provider,
+		request.ScriptType,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached response for request, marked as Cached, if a fresh one exists
+func (c *Cache) Get(request *types.ScriptRequest) (*types.ScriptResponse, bool) {
+	path := c.entryPath(Key(request))
+
+	cached, err := readEntry(path)
+	if err != nil {
+		c.record(false)
+		return nil, false
+	}
+
+	if c.now().Sub(cached.CreatedAt) > c.ttl {
+		os.Remove(path)
+		c.record(false)
+		return nil, false
+	}
+
+	// Bump the modification time so eviction drops the least recently used entries first
+	now := c.now()
+	os.Chtimes(path, now, now)
+	c.record(true)
+
+	response := cached.Response
+	response.Cached = true
+	return &response, true
+}
+
+// Put stores response for request and evicts the oldest entries beyond the size cap.
+// The entry is keyed by the provider and model that produced the response, which differ from the
+// request's after a fallback, so a request for one model is never served another model's script.
+func (c *Cache) Put(request *types.ScriptRequest, response *types.ScriptResponse) error {
+	answered := *request
+	if response.Provider != "" {
+		answered.Provider = response.Provider
+	}
+	if response.Model != "" {
+		answered.Model = response.Model
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	stored := *response
+	stored.Cached = false
+	data, err := json.Marshal(entry{CreatedAt: c.now(), Response: stored})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %v", err)
+	}
+
+	if err := os.WriteFile(c.entryPath(Key(&answered)), data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+
+	return c.prune()
+}
+
+// Stats reports the number, size and age of cached entries along with hit/miss counts
+func (c *Cache) Stats() (Stats, error) {
+	stats := Stats{Dir: c.dir, MaxBytes: c.maxBytes, TTL: c.ttl}
+
+	files, err := c.entryFiles()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, file := range files {
+		cached, err := readEntry(file.path)
+		if err != nil {
+			continue
+		}
+
+		stats.Entries++
+		stats.SizeBytes += file.size
+		if c.now().Sub(cached.CreatedAt) > c.ttl {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || cached.CreatedAt.Before(stats.Oldest) {
+			stats.Oldest = cached.CreatedAt
+		}
+		if cached.CreatedAt.After(stats.Newest) {
+			stats.Newest = cached.CreatedAt
+		}
+	}
+
+	counts := c.readCounters()
+	stats.Hits = counts.Hits
+	stats.Misses = counts.Misses
+	return stats, nil
+}
+
+// Clear removes every cached entry and resets the counters, returning how many entries were removed
+func (c *Cache) Clear() (int, error) {
+	files, err := c.entryFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, file := range files {
+		if err := os.Remove(file.path); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry: %v", err)
+		}
+		removed++
+	}
+	os.Remove(filepath.Join(c.dir, statsFile))
+
+	return removed, nil
+}
+
+// prune deletes expired entries, then the least recently used ones until the cache fits maxBytes
+func (c *Cache) prune() error {
+	files, err := c.entryFiles()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+
+	for _, file := range files {
+		expired := c.now().Sub(file.modTime) > c.ttl
+		if !expired && total <= c.maxBytes {
+			continue
+		}
+		if err := os.Remove(file.path); err != nil {
+			return fmt.Errorf("failed to evict cache entry: %v", err)
+		}
+		total -= file.size
+	}
+
+	return nil
+}
+
+// cacheFile describes one entry file on disk
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entryFiles lists the cached entry files, ignoring the stats file
+func (c *Cache) entryFiles() ([]cacheFile, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory: %v", err)
+	}
+
+	var files []cacheFile
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || name == statsFile || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{path: filepath.Join(c.dir, name), size: info.Size(), modTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+// entryPath returns the file path for a cache key
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// record increments the hit or miss counter; failures only affect statistics
+func (c *Cache) record(hit bool) {
+	counts := c.readCounters()
+	if hit {
+		counts.Hits++
+	} else {
+		counts.Misses++
+	}
+
+	if data, err := json.Marshal(counts); err == nil {
+		if os.MkdirAll(c.dir, 0755) == nil {
+			os.WriteFile(filepath.Join(c.dir, statsFile), data, 0644)
+		}
+	}
+}
+
+// readCounters loads the persisted hit/miss counters
+func (c *Cache) readCounters() counters {
+	var counts counters
+	if data, err := os.ReadFile(filepath.Join(c.dir, statsFile)); err == nil {
+		json.Unmarshal(data, &counts)
+	}
+	return counts
+}
+
+// readEntry loads a cached entry from path
+func readEntry(path string) (*entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached entry
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"please/types"
+)
+
+func newTestRequest(task string) *types.ScriptRequest {
+	return &types.ScriptRequest{
+		TaskDescription: task,
+		ScriptType:      "bash",
+		Provider:        "openai",
+		Model:           "gpt-4",
+	}
+}
+
+func Test_when_response_is_cached_then_get_returns_it_marked_as_cached(t *testing.T) {
+	// Arrange
+	c := New(t.TempDir(), 0, 0)
+	request := newTestRequest("show disk usage by folder")
+	c.Put(request, &types.ScriptResponse{Script: "du -sh *", Model: "gpt-4", Provider: "openai"})
+
+	// Act
+	response, ok := c.Get(request)
+
+	// Assert
+	if !ok {
+		t.Fatal("Expected cache hit")
+	}
+	if response.Script != "du -sh *" || !response.Cached {
+		t.Errorf("Expected cached script marked as cached, got %+v", response)
+	}
+}
+
+func Test_when_response_came_from_fallback_provider_then_cache_it_under_that_provider(t *testing.T) {
+	// Arrange
+	c := New(t.TempDir(), 0, 0)
+	request := newTestRequest("list large files")
+	c.Put(request, &types.ScriptResponse{Script: "du -ah | sort -h", Model: "llama3.2", Provider: "ollama"})
+	fallback := *request
+	fallback.Provider = "ollama"
+	fallback.Model = "llama3.2"
+
+	// Act
+	_, requestedHit := c.Get(request)
+	_, fallbackHit := c.Get(&fallback)
+
+	// Assert
+	if requestedHit {
+		t.Error("Expected no hit for the requested provider and model")
+	}
+	if !fallbackHit {
+		t.Error("Expected a hit for the provider and model that answered")
+	}
+}
+
+func Test_when_model_or_provider_differs_then_cache_key_differs(t *testing.T) {
+	// Arrange
+	base := newTestRequest("clean docker images")
+	otherModel := *base
+	otherModel.Model = "gpt-3.5-turbo"
+	otherProvider := *base
+	otherProvider.Provider = "anthropic"
+
+	// Act & Assert
+	if Key(base) == Key(&otherModel) || Key(base) == Key(&otherProvider) {
+		t.Error("Expected model and provider to be part of the cache key")
+	}
+	if Key(base) != Key(newTestRequest("clean docker images")) {
+		t.Error("Expected identical requests to share a key")
+	}
+}
+
+func Test_when_entry_is_older_than_ttl_then_treat_as_miss(t *testing.T) {
+	// Arrange
+	c := New(t.TempDir(), time.Hour, 0)
+	start := time.Now()
+	c.now = func() time.Time { return start }
+	request := newTestRequest("list files")
+	c.Put(request, &types.ScriptResponse{Script: "ls"})
+
+	// Act
+	c.now = func() time.Time { return start.Add(2 * time.Hour) }
+	_, ok := c.Get(request)
+
+	// Assert
+	if ok {
+		t.Error("Expected expired entry to be a miss")
+	}
+}
+
+func Test_when_cache_exceeds_size_cap_then_evict_least_recently_used(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	c := New(dir, 0, 600)
+	first := newTestRequest("first task")
+	second := newTestRequest("second task")
+	c.Put(first, &types.ScriptResponse{Script: strings.Repeat("a", 200)})
+	os.Chtimes(filepath.Join(dir, Key(first)+".json"), time.Now().Add(-time.Minute), time.Now().Add(-time.Minute))
+
+	// Act
+	c.Put(second, &types.ScriptResponse{Script: strings.Repeat("b", 200)})
+
+	// Assert
+	if _, ok := c.Get(first); ok {
+		t.Error("Expected oldest entry to be evicted")
+	}
+	if _, ok := c.Get(second); !ok {
+		t.Error("Expected newest entry to be kept")
+	}
+}
+
+func Test_when_clearing_cache_then_stats_report_empty(t *testing.T) {
+	// Arrange
+	c := New(t.TempDir(), 0, 0)
+	request := newTestRequest("show uptime")
+	c.Put(request, &types.ScriptResponse{Script: "uptime"})
+	c.Get(request)
+	c.Get(newTestRequest("never cached"))
+
+	before, _ := c.Stats()
+
+	// Act
+	removed, err := c.Clear()
+	after, _ := c.Stats()
+
+	// Assert
+	if err != nil || removed != 1 {
+		t.Errorf("Expected one entry removed, got %d (%v)", removed, err)
+	}
+	if before.Entries != 1 || before.Hits != 1 || before.Misses != 1 {
+		t.Errorf("Unexpected stats before clear: %+v", before)
+	}
+	if after.Entries != 0 || after.Hits != 0 || after.SizeBytes != 0 {
+		t.Errorf("Expected empty stats after clear, got %+v", after)
+	}
+}
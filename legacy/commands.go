@@ -0,0 +1,663 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"please/cache"
+	"please/config"
+	"please/environment"
+	"please/models"
+	"please/policy"
+	"please/prompts"
+	"please/providers"
+	"please/script"
+	"please/types"
+	"please/ui"
+	"please/usage"
+)
+
+// openResponseCache returns the response cache for request, or nil when caching is disabled,
+// bypassed with --no-cache, or the request is served by offline fixtures anyway
+func openResponseCache(cfg *types.Config, request *types.ScriptRequest, noCache bool) *cache.Cache {
+	if noCache || cfg.Cache.Disabled || request.Provider == "mock" || request.Provider == "replay" {
+		return nil
+	}
+
+	responseCache, err := cache.Open(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Response cache unavailable (%v)\n", err)
+		return nil
+	}
+	return responseCache
+}
+
+// runCacheCommand handles "please cache stats" and "please cache clear"
+func runCacheCommand(action string) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.CreateDefault()
+	}
+
+	responseCache, err := cache.Open(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch action {
+	case "stats":
+		stats, err := responseCache.Stats()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		printCacheStats(stats, cfg.Cache.Disabled)
+	case "clear":
+		removed, err := responseCache.Clear()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s🧹 Cleared %d cached script(s)%s\n", ui.ColorGreen, removed, ui.ColorReset)
+	}
+}
+
+// printCacheStats shows the cache summary produced by "please cache stats"
+func printCacheStats(stats cache.Stats, disabled bool) {
+	fmt.Printf("%s📦 Response cache%s\n", ui.ColorBold+ui.ColorCyan, ui.ColorReset)
+	if disabled {
+		fmt.Printf("  %sCaching is disabled in config (cache.disabled)%s\n", ui.ColorYellow, ui.ColorReset)
+	}
+	fmt.Printf("  Location:  %s\n", stats.Dir)
+	fmt.Printf("  Entries:   %d (%d expired)\n", stats.Entries, stats.Expired)
+	fmt.Printf("  Size:      %s of %s\n", formatBytes(stats.SizeBytes), formatBytes(stats.MaxBytes))
+	fmt.Printf("  TTL:       %s\n", stats.TTL)
+
+	lookups := stats.Hits + stats.Misses
+	if lookups > 0 {
+		fmt.Printf("  Hit rate:  %d/%d (%.0f%%)\n", stats.Hits, lookups, float64(stats.Hits)*100/float64(lookups))
+	}
+	if stats.Entries > 0 {
+		fmt.Printf("  Oldest:    %s\n", stats.Oldest.Format(time.DateTime))
+		fmt.Printf("  Newest:    %s\n", stats.Newest.Format(time.DateTime))
+	}
+}
+
+// formatBytes renders a byte count in KB/MB/GB for humans
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1024*1024*1024))
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// recordUsage appends a fresh response's usage to the usage log; failures only warn
+func recordUsage(response *types.ScriptResponse) {
+	tracker, err := usage.Open()
+	if err == nil {
+		err = tracker.Append(response)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not record usage (%v)\n", err)
+	}
+}
+
+// runUsageCommand handles "please usage [--days=N]", reporting spend by day, provider and model
+func runUsageCommand(args []string) {
+	days := 30
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--days=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--days="))
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: --days must be a positive number\n")
+				os.Exit(1)
+			}
+			days = n
+		}
+	}
+
+	tracker, err := usage.Open()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	records, err := tracker.Load(since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s📊 Usage for the last %d day(s)%s\n", ui.ColorBold+ui.ColorCyan, days, ui.ColorReset)
+	if len(records) == 0 {
+		fmt.Printf("  No requests recorded in %s\n", tracker.Path())
+		return
+	}
+
+	total := usage.Summarize(records, func(usage.Record) string { return "total" })[0]
+	fmt.Printf("  %d requests · %d prompt + %d completion tokens · $%.4f\n",
+		total.Requests, total.PromptTokens, total.CompletionTokens, total.CostUSD)
+
+	printUsageSummaries("By day", usage.Summarize(records, usage.ByDay))
+	printUsageSummaries("By provider", usage.Summarize(records, usage.ByProvider))
+	printUsageSummaries("By model", usage.Summarize(records, usage.ByModel))
+}
+
+// printUsageSummaries prints one section of the usage report as an aligned table
+func printUsageSummaries(title string, summaries []usage.Summary) {
+	fmt.Printf("\n%s%s%s\n", ui.ColorBold+ui.ColorYellow, title, ui.ColorReset)
+	for _, summary := range summaries {
+		fmt.Printf("  %-40s %5d req %10d tok %8.1fs avg  $%.4f\n",
+			summary.Key,
+			summary.Requests,
+			summary.PromptTokens+summary.CompletionTokens,
+			summary.AverageLatency().Seconds(),
+			summary.CostUSD)
+	}
+}
+
+// runModelsCommand handles "please models list|explain|pin|stats"
+func runModelsCommand(args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.CreateDefault()
+	}
+
+	switch args[0] {
+	case "list":
+		runModelsList(cfg, args[1:])
+	case "explain":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "Usage: please models explain \"<task>\"\n")
+			os.Exit(1)
+		}
+		runModelsExplain(cfg, strings.Join(args[1:], " "))
+	case "stats":
+		runModelsStats()
+	case "pin":
+		if len(args) != 3 {
+			fmt.Fprintf(os.Stderr, "Usage: please models pin <category> <model>\n")
+			os.Exit(1)
+		}
+		if err := pinModel(cfg, args[1], args[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := config.Save(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to save config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s📌 %s tasks will now use %s%s\n", ui.ColorGreen, args[1], args[2], ui.ColorReset)
+	}
+}
+
+// pinModel records model as the override for a task category, or removes the override when
+// model is "auto"
+func pinModel(cfg *types.Config, category, model string) error {
+	if !models.IsTaskCategory(category) {
+		return fmt.Errorf("unknown category %q (expected one of: %s)", category, strings.Join(models.TaskCategories, ", "))
+	}
+
+	if model == "auto" {
+		delete(cfg.ModelOverrides, category)
+		return nil
+	}
+	if cfg.ModelOverrides == nil {
+		cfg.ModelOverrides = make(map[string]string)
+	}
+	cfg.ModelOverrides[category] = model
+	return nil
+}
+
+// runModelsList prints the models of one provider, or of every configured provider, with their
+// rank scores. "--refresh" bypasses the cached hosted model lists.
+func runModelsList(cfg *types.Config, args []string) {
+	refresh := false
+	var names []string
+	for _, arg := range args {
+		if arg == "--refresh" {
+			refresh = true
+			continue
+		}
+		names = append(names, arg)
+	}
+	if len(names) == 0 {
+		names = providers.BuiltinProviderNames()
+	}
+
+	catalog, err := providers.OpenModelCatalog(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, name := range names {
+		fmt.Printf("%s🧠 %s%s\n", ui.ColorBold+ui.ColorCyan, name, ui.ColorReset)
+
+		if _, err := providers.NewConfiguredProvider(name, cfg); err != nil {
+			fmt.Printf("  %s%v%s\n\n", ui.ColorDim, err, ui.ColorReset)
+			continue
+		}
+
+		var available []types.ModelInfo
+		if refresh {
+			available, err = catalog.Refresh(name)
+		} else {
+			available, err = catalog.Models(name)
+		}
+		if err != nil {
+			fmt.Printf("  %s%v%s\n\n", ui.ColorYellow, err, ui.ColorReset)
+			continue
+		}
+		if len(available) == 0 {
+			fmt.Printf("  %sNo models available%s\n\n", ui.ColorDim, ui.ColorReset)
+			continue
+		}
+
+		byName := make(map[string]types.ModelInfo, len(available))
+		for _, model := range available {
+			byName[model.Name] = model
+		}
+
+		for _, score := range models.ScoreModels(available, "", "general") {
+			model := byName[score.Name]
+			status := ""
+			if model.Loaded {
+				status = ui.ColorGreen + "loaded" + ui.ColorReset
+			} else if score.Excluded {
+				status = ui.ColorYellow + "too large" + ui.ColorReset
+			}
+			fmt.Printf("  %-40s %4d  %-10s %-8s %10s  %-10s %s\n",
+				score.Name,
+				score.Score,
+				model.Details.Family,
+				models.QuantizationLevel(model),
+				formatModelSize(model.Size),
+				formatModelDate(model.ModifiedAt),
+				status)
+		}
+		fmt.Println()
+	}
+}
+
+// runModelsExplain shows how a task is categorised and why the selected model wins
+func runModelsExplain(cfg *types.Config, taskDescription string) {
+	provider := config.DetermineProvider(cfg)
+	classification := models.ClassifyTask(taskDescription)
+	taskType := classification.Category
+
+	fmt.Printf("%s🔍 Model selection for:%s %s\n", ui.ColorBold+ui.ColorCyan, ui.ColorReset, taskDescription)
+	fmt.Printf("  Provider:  %s\n", provider)
+	fmt.Printf("  Category:  %s (%.0f%% confidence)\n", taskType, classification.Confidence*100)
+	if signals := formatCategoryScores(classification.Scores); signals != "" {
+		fmt.Printf("  Signals:   %s\n", signals)
+	}
+	if _, err := models.LoadRankingTable(); err != nil {
+		fmt.Printf("  %sRanking:   %v, using the shipped table%s\n", ui.ColorYellow, err, ui.ColorReset)
+	}
+
+	selected, err := models.SelectBestModel(cfg, taskDescription, provider)
+	if err != nil {
+		fmt.Printf("  %sSelection failed: %v%s\n", ui.ColorYellow, err, ui.ColorReset)
+		return
+	}
+
+	switch {
+	case provider == "ollama" && os.Getenv("OLLAMA_MODEL") != "":
+		fmt.Printf("  Reason:    OLLAMA_MODEL is set, ranking is skipped\n")
+	case cfg.ModelOverrides[taskType] != "":
+		fmt.Printf("  Reason:    pinned by model_overrides[%q], ranking is skipped\n", taskType)
+	default:
+		explainRanking(cfg, provider, taskDescription, taskType)
+	}
+
+	fmt.Printf("  %sSelected:  %s%s\n", ui.ColorGreen+ui.ColorBold, selected, ui.ColorReset)
+}
+
+// formatCategoryScores lists the classifier's evidence per category, strongest first
+func formatCategoryScores(scores map[string]float64) string {
+	var categories []string
+	for category, score := range scores {
+		if score > 0 {
+			categories = append(categories, category)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool { return scores[categories[i]] > scores[categories[j]] })
+
+	parts := make([]string, 0, len(categories))
+	for _, category := range categories {
+		parts = append(parts, fmt.Sprintf("%s %.1f", category, scores[category]))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// explainRanking prints each candidate's score and the reasons behind it
+func explainRanking(cfg *types.Config, provider, taskDescription, taskType string) {
+	if !providers.IsBuiltinProvider(provider) {
+		fmt.Printf("  Reason:    custom provider uses its configured model\n")
+		return
+	}
+
+	catalog, err := providers.OpenModelCatalog(cfg)
+	if err != nil {
+		fmt.Printf("  Reason:    model list unavailable (%v), using the built-in default\n", err)
+		return
+	}
+	available, err := catalog.Models(provider)
+	if err != nil || len(available) == 0 {
+		fmt.Printf("  Reason:    model list unavailable, using the built-in default for %s tasks\n", taskType)
+		return
+	}
+
+	if hardware := models.DetectHardware(); hardware != nil && provider == "ollama" {
+		fmt.Printf("  Memory:    %s free of %s\n", formatBytes(int64(hardware.AvailableMemory)), formatBytes(int64(hardware.TotalMemory)))
+	}
+	fmt.Printf("  Candidates:\n")
+	for i, score := range models.ScoreModels(available, taskDescription, taskType) {
+		marker := " "
+		if i == 0 {
+			marker = "▶"
+		}
+		fmt.Printf("   %s %-38s %4d  %s%s%s\n", marker, score.Name, score.Score,
+			ui.ColorDim, strings.Join(score.Reasons, ", "), ui.ColorReset)
+	}
+}
+
+// runModelsStats prints the learned outcome table and the score adjustment each row earns
+func runModelsStats() {
+	outcomes, err := models.LoadOutcomes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	table, _ := models.LoadRankingTable()
+	weight := 0
+	if table.Learning != nil {
+		weight = table.Learning.Weight
+	}
+
+	fmt.Printf("%s📈 Learned model outcomes%s\n", ui.ColorBold+ui.ColorCyan, ui.ColorReset)
+	rows := outcomes.Rows()
+	if len(rows) == 0 {
+		fmt.Printf("  No outcomes recorded yet in %s\n", outcomes.Path())
+		return
+	}
+	if weight == 0 {
+		fmt.Printf("  %sLearning is disabled in the ranking table (learning.weight)%s\n", ui.ColorYellow, ui.ColorReset)
+	}
+
+	fmt.Printf("  %-32s %-15s %4s %4s %4s %4s %4s %4s %6s %6s\n",
+		"Model", "Category", "Runs", "OK", "Fail", "Fix", "Edit", "Refn", "Rate", "Score")
+	for _, row := range rows {
+		fmt.Printf("  %-32s %-15s %4d %4d %4d %4d %4d %4d %5.0f%% %+6d\n",
+			row.Model,
+			row.Category,
+			row.Counts.Total(),
+			row.Counts.Success,
+			row.Counts.Failed,
+			row.Counts.AutoFixed,
+			row.Counts.Edited,
+			row.Counts.Refined,
+			row.Counts.SuccessRate()*100,
+			row.Counts.Adjustment(weight))
+	}
+}
+
+// formatModelSize renders an Ollama model size, leaving hosted models (size 0) blank
+func formatModelSize(size int64) string {
+	if size == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.1f GB", float64(size)/1e9)
+}
+
+// formatModelDate renders a model's modified date, leaving unknown dates blank
+func formatModelDate(modified time.Time) string {
+	if modified.IsZero() {
+		return ""
+	}
+	return modified.Local().Format(time.DateOnly)
+}
+
+// runContextCommand handles "please context", previewing the environment details sent with each request
+func runContextCommand(noContext bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.CreateDefault()
+	}
+
+	fmt.Printf("%s🖥️  Environment context%s\n", ui.ColorBold+ui.ColorCyan, ui.ColorReset)
+	if noContext || !environment.Enabled(cfg) {
+		fmt.Printf("  %sDisabled: nothing about this machine is sent with requests%s\n", ui.ColorYellow, ui.ColorReset)
+		fmt.Printf("  %sRe-enable by unsetting %s and environment_context.disabled in config%s\n", ui.ColorDim, environment.DisableEnvVar, ui.ColorReset)
+		return
+	}
+
+	summary := environment.ForRequest(cfg, config.DetermineScriptType(cfg))
+	fmt.Printf("%sThe following is added to every generation prompt:%s\n\n", ui.ColorDim, ui.ColorReset)
+	fmt.Println(summary)
+	fmt.Printf("\n%sOpt out with --no-context, %s=1 or environment_context.disabled; leave out sections with\n", ui.ColorDim, environment.DisableEnvVar)
+	fmt.Printf("environment_context.exclude (os, shell, package_manager, tools, cwd, git)%s\n", ui.ColorReset)
+}
+
+// runPromptsCommand handles "please prompts show|edit|reset", managing the prompt templates
+func runPromptsCommand(args []string) {
+	switch args[0] {
+	case "show":
+		if len(args) == 1 {
+			listPromptTemplates()
+			return
+		}
+		showPromptTemplate(args[1])
+	case "edit":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: please prompts edit <template>\n")
+			os.Exit(1)
+		}
+		editPromptTemplate(args[1])
+	case "reset":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: please prompts reset <template>\n")
+			os.Exit(1)
+		}
+		if err := prompts.Reset(args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s♻️  %s is back to the built-in template%s\n", ui.ColorGreen, args[1], ui.ColorReset)
+	}
+}
+
+// listPromptTemplates prints every template with whether it is overridden, outdated or broken
+func listPromptTemplates() {
+	fmt.Printf("%s📝 Prompt templates%s\n\n", ui.ColorBold+ui.ColorCyan, ui.ColorReset)
+	for _, name := range prompts.Names() {
+		template, err := prompts.Load(name)
+		if err != nil {
+			fmt.Printf("  %s%-20s%s %s%v%s\n", ui.ColorGreen, name, ui.ColorReset, ui.ColorRed, err, ui.ColorReset)
+			continue
+		}
+		fmt.Printf("  %s%-20s%s %s%s\n", ui.ColorGreen, name, ui.ColorReset, promptTemplateStatus(template), template.Description)
+	}
+	if dir, err := prompts.Dir(); err == nil {
+		fmt.Printf("\n%sOverrides live in %s; edit one with: please prompts edit <template>%s\n", ui.ColorDim, dir, ui.ColorReset)
+	}
+}
+
+// promptTemplateStatus describes where a template comes from, padded for the list
+func promptTemplateStatus(template *prompts.Template) string {
+	switch {
+	case template.Overridden && prompts.Validate(template.Name, template.Source) != nil:
+		return fmt.Sprintf("%s%-12s%s ", ui.ColorRed, "invalid", ui.ColorReset)
+	case template.Outdated():
+		return fmt.Sprintf("%s%-12s%s ", ui.ColorYellow, "outdated", ui.ColorReset)
+	case template.Overridden:
+		return fmt.Sprintf("%s%-12s%s ", ui.ColorCyan, "overridden", ui.ColorReset)
+	}
+	return fmt.Sprintf("%s%-12s%s ", ui.ColorDim, fmt.Sprintf("built-in v%d", template.Version), ui.ColorReset)
+}
+
+// showPromptTemplate prints the template Please uses for name, with any problem it has
+func showPromptTemplate(name string) {
+	template, err := prompts.Load(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s📝 %s%s\n", ui.ColorBold+ui.ColorCyan, name, ui.ColorReset)
+	if template.Overridden {
+		fmt.Printf("%sOverridden by %s%s\n", ui.ColorDim, template.Path, ui.ColorReset)
+	} else {
+		fmt.Printf("%sBuilt-in template, version %d%s\n", ui.ColorDim, template.Version, ui.ColorReset)
+	}
+	if template.Outdated() {
+		fmt.Printf("%s⚠️  Based on version %d of the built-in template, which is now at version %d; compare with it or run: please prompts reset %s%s\n",
+			ui.ColorYellow, template.OverrideVersion, template.Version, name, ui.ColorReset)
+	}
+	if err := prompts.Validate(name, template.Source); err != nil {
+		fmt.Printf("%s❌ %v; the built-in template is used until this is fixed%s\n", ui.ColorRed, err, ui.ColorReset)
+	}
+	fmt.Println()
+	fmt.Println(template.Source)
+}
+
+// editPromptTemplate opens the user's copy of a template, creating it from the built-in one first
+func editPromptTemplate(name string) {
+	path, err := prompts.Edit(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := script.EditFile(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Printf("%sEdit the template by hand at %s%s\n", ui.ColorDim, path, ui.ColorReset)
+		os.Exit(1)
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read prompt template: %v\n", err)
+		os.Exit(1)
+	}
+	if err := prompts.Validate(name, string(source)); err != nil {
+		fmt.Printf("%s❌ %v%s\n", ui.ColorRed, err, ui.ColorReset)
+		fmt.Printf("%sThe built-in template is used until this is fixed; run please prompts edit %s again%s\n", ui.ColorDim, name, ui.ColorReset)
+		return
+	}
+	fmt.Printf("%s✅ Saved %s; it is used for every new request%s\n", ui.ColorGreen, name, ui.ColorReset)
+}
+
+// policyReport is the output of "please policy test <script> --json", for editors and CI
+type policyReport struct {
+	Script     string           `json:"script"`
+	ScriptType string           `json:"script_type"`
+	Action     policy.Action    `json:"action"` // The strictest action the findings ask for
+	Findings   []policy.Finding `json:"findings"`
+}
+
+// runPolicyCommand handles "please policy test <script> [--json]", showing which risk rules fire on
+// a script and what Please would ask before running it; it exits with status 1 when the script is blocked
+func runPolicyCommand(args []string) {
+	source, err := os.ReadFile(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read script: %v\n", err)
+		os.Exit(1)
+	}
+	response := &types.ScriptResponse{Script: string(source), ScriptType: scriptTypeForFile(args[1])}
+	findings := script.AssessScript(response)
+	action := policy.Strictest(findings)
+
+	if len(args) == 3 && args[2] == "--json" {
+		report := policyReport{Script: args[1], ScriptType: response.ScriptType, Action: action, Findings: findings}
+		if report.Findings == nil {
+			report.Findings = []policy.Finding{}
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode findings: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("%s🛡️  Risk policy for %s (%s)%s\n\n", ui.ColorBold+ui.ColorCyan, args[1], response.ScriptType, ui.ColorReset)
+		printPolicyFiles()
+
+		fmt.Printf("\n%sRules that fire:%s\n", ui.ColorBold, ui.ColorReset)
+		if len(findings) == 0 {
+			fmt.Printf("  %snone%s\n", ui.ColorDim, ui.ColorReset)
+		}
+		for _, finding := range findings {
+			source := "built-in"
+			if finding.Source != "" {
+				source = finding.Source
+			}
+			fmt.Print(ui.FormatFinding(finding))
+			fmt.Printf("  %saction: %s, from %s%s\n\n", ui.ColorDim, finding.Action, source, ui.ColorReset)
+		}
+		fmt.Printf("%sPlease would %s%s\n", ui.ColorBold, policyActionDescription(action), ui.ColorReset)
+	}
+
+	if action == policy.ActionBlock {
+		os.Exit(1)
+	}
+}
+
+// printPolicyFiles lists the policy files in order of precedence and any that couldn't be read
+func printPolicyFiles() {
+	loaded, err := policy.Load()
+	fmt.Printf("%sPolicy files, later ones taking precedence:%s\n", ui.ColorBold, ui.ColorReset)
+	for _, layer := range loaded.Layers {
+		note := ""
+		if layer.StrictOnly {
+			note = ", may only make rules stricter"
+		}
+		fmt.Printf("  %s%-8s%s %s %s(%d rules%s)%s\n", ui.ColorGreen, layer.Name, ui.ColorReset, layer.Path, ui.ColorDim, len(layer.Rules), note, ui.ColorReset)
+	}
+	if len(loaded.Layers) == 0 {
+		userPath, _ := policy.UserPath()
+		fmt.Printf("  %snone; built-in rules only. Looked for %s, %s and .please/%s in this or a parent directory%s\n",
+			ui.ColorDim, policy.SystemPath(), userPath, policy.FileName, ui.ColorReset)
+	}
+	if err != nil {
+		fmt.Printf("  %s❌ %v%s\n", ui.ColorRed, err, ui.ColorReset)
+	}
+}
+
+// policyActionDescription says what Please does before running a script for action
+func policyActionDescription(action policy.Action) string {
+	switch action {
+	case policy.ActionBlock:
+		return "refuse to run this script"
+	case policy.ActionTypedExecute:
+		return "ask you to type EXECUTE before running this script"
+	case policy.ActionConfirm:
+		return "ask you to confirm before running this script"
+	default:
+		return "run this script after showing any warnings"
+	}
+}
+
+// scriptTypeForFile guesses a script's type from its extension, as Please names saved scripts
+func scriptTypeForFile(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ps1", ".psm1":
+		return "powershell"
+	default:
+		return "bash"
+	}
+}
@@ -78,6 +78,17 @@ func CreateDefault() *types.Config {
 
 // getConfigPath returns the path to the configuration file based on the platform
 func getConfigPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "config.json"), nil
+}
+
+// GetConfigDir returns the platform-specific directory holding config.json and other
+// Please data such as the response cache, creating it if needed
+func GetConfigDir() (string, error) {
 	var configDir string
 
 	// Cross-platform config directory
@@ -107,7 +118,7 @@ func getConfigPath() (string, error) {
 		return "", fmt.Errorf("failed to create config directory: %v", err)
 	}
 
-	return filepath.Join(configDir, "config.json"), nil
+	return configDir, nil
 }
 
 // DetermineScriptType determines what type of script to generate based on platform and config
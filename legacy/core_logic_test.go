@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -14,7 +17,7 @@ func TestGenerateScript_WhenUnsupportedProvider_ShouldReturnError(t *testing.T)
 		Provider: "invalid-provider",
 	}
 
-	_, err := generateScript(cfg, request)
+	_, err := generateScript(context.Background(), cfg, request)
 	if err == nil {
 		t.Error("Expected error for unsupported provider")
 	}
@@ -25,14 +28,49 @@ func TestGenerateScript_WhenUnsupportedProvider_ShouldReturnError(t *testing.T)
 	}
 }
 
+// TestGenerateScript_FallbackChain tests that an unconfigured provider falls through to the next one
+func TestGenerateScript_WhenPrimaryProviderUnconfigured_ShouldUseFallbackProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"echo fallback\"}}]}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	cfg := &types.Config{
+		FallbackProviders: []string{"gateway"},
+		CustomProviders: map[string]types.ProviderConfig{
+			"gateway": {URL: server.URL, Model: "local-model"},
+		},
+	}
+	request := &types.ScriptRequest{
+		TaskDescription: "say something",
+		ScriptType:      "bash",
+		Provider:        "openai", // no API key configured
+		Model:           "gpt-4",
+	}
+
+	response, err := generateScript(context.Background(), cfg, request)
+	if err != nil {
+		t.Fatalf("Expected fallback provider to succeed, got: %v", err)
+	}
+
+	if response.Provider != "gateway" || response.Model != "local-model" {
+		t.Errorf("Expected response from gateway/local-model, got %s/%s", response.Provider, response.Model)
+	}
+	if response.Script != "echo fallback" {
+		t.Errorf("Expected fallback script, got '%s'", response.Script)
+	}
+}
+
 // TestGetFallbackModel_AllProviders tests model selection logic
 func TestGetFallbackModel_ForEachProvider_ShouldReturnCorrectModel(t *testing.T) {
 	tests := []struct {
 		provider string
 		expected string
 	}{
-		{"openai", "gpt-3.5-turbo"},
-		{"anthropic", "claude-3-haiku-20240307"},
+		{"openai", "gpt-4o-mini"},
+		{"anthropic", "claude-3-5-haiku-20241022"},
 		{"ollama", "llama3.2"},
 		{"unknown", "llama3.2"}, // default case
 	}
@@ -128,3 +166,37 @@ func TestArguments_WhenLanguageAndThemeFlags_ShouldParseCorrectly(t *testing.T)
 		})
 	}
 }
+
+func TestPinModel_WhenCategoryKnown_ShouldSetAndClearOverride(t *testing.T) {
+	// Arrange
+	cfg := &types.Config{}
+
+	// Act
+	err := pinModel(cfg, "coding", "codellama:13b")
+
+	// Assert
+	if err != nil || cfg.ModelOverrides["coding"] != "codellama:13b" {
+		t.Fatalf("Expected coding override to be pinned, got %v (%v)", cfg.ModelOverrides, err)
+	}
+
+	// Act
+	err = pinModel(cfg, "coding", "auto")
+
+	// Assert
+	if _, exists := cfg.ModelOverrides["coding"]; err != nil || exists {
+		t.Errorf("Expected \"auto\" to remove the override, got %v (%v)", cfg.ModelOverrides, err)
+	}
+}
+
+func TestPinModel_WhenCategoryUnknown_ShouldReturnError(t *testing.T) {
+	// Arrange
+	cfg := &types.Config{}
+
+	// Act
+	err := pinModel(cfg, "gaming", "llama3")
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "unknown category") {
+		t.Errorf("Expected unknown category error, got %v", err)
+	}
+}
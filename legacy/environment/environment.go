@@ -0,0 +1,267 @@
+package environment
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"please/types"
+)
+
+// Section names accepted in types.EnvironmentConfig.Exclude
+const (
+	SectionOS             = "os"
+	SectionShell          = "shell"
+	SectionPackageManager = "package_manager"
+	SectionTools          = "tools"
+	SectionWorkingDir     = "cwd"
+	SectionGit            = "git"
+)
+
+// DisableEnvVar turns environment context off for a single run
+const DisableEnvVar = "PLEASE_NO_CONTEXT"
+
+// probeTimeout bounds each external command the probe runs
+const probeTimeout = 2 * time.Second
+
+// commonTools are reported as available or missing so the model picks commands that exist
+var commonTools = []string{"jq", "rsync", "docker", "git", "curl", "wget", "python3", "systemctl", "tar", "zip", "unzip"}
+
+// packageManagers are checked in order; the first one on PATH is reported
+var packageManagers = map[string][]string{
+	"linux":   {"apt", "dnf", "yum", "pacman", "zypper", "apk", "brew"},
+	"darwin":  {"brew", "port"},
+	"windows": {"winget", "choco", "scoop"},
+}
+
+// Context is what Please knows about the machine the script will run on
+type Context struct {
+	OS             string
+	OSRelease      string
+	Arch           string
+	Shell          string
+	ShellVersion   string
+	PackageManager string
+	Tools          []string // Common tools found on PATH
+	MissingTools   []string // Common tools not found on PATH
+	WorkingDir     string
+	GitBranch      string // Empty outside a git repository
+	GitDirty       int    // Number of changed files in the repository
+}
+
+// Prober gathers a Context; its functions are swappable so tests do not depend on the machine
+type Prober struct {
+	GOOS     string
+	LookPath func(file string) (string, error)
+	Run      func(name string, args ...string) (string, error)
+	ReadFile func(name string) ([]byte, error)
+	Getwd    func() (string, error)
+}
+
+// NewProber creates a prober for the current machine
+func NewProber() *Prober {
+	return &Prober{
+		GOOS:     runtime.GOOS,
+		LookPath: exec.LookPath,
+		Run:      runCommand,
+		ReadFile: os.ReadFile,
+		Getwd:    os.Getwd,
+	}
+}
+
+// Enabled reports whether environment context should be sent, honouring the config and PLEASE_NO_CONTEXT
+func Enabled(cfg *types.Config) bool {
+	if value := os.Getenv(DisableEnvVar); value != "" && value != "0" && value != "false" {
+		return false
+	}
+	return cfg == nil || !cfg.Environment.Disabled
+}
+
+// ForRequest probes the machine and returns the summary to attach to a request, or "" when disabled
+func ForRequest(cfg *types.Config, scriptType string) string {
+	if !Enabled(cfg) {
+		return ""
+	}
+	var exclude []string
+	if cfg != nil {
+		exclude = cfg.Environment.Exclude
+	}
+	return NewProber().Probe(scriptType).Summary(exclude)
+}
+
+// Probe inspects the machine for a script of scriptType ("bash" or "powershell")
+func (p *Prober) Probe(scriptType string) *Context {
+	c := &Context{OS: p.GOOS, Arch: runtime.GOARCH}
+
+	c.OSRelease = p.osRelease()
+	c.Shell, c.ShellVersion = p.shell(scriptType)
+
+	for _, manager := range packageManagers[p.GOOS] {
+		if _, err := p.LookPath(manager); err == nil {
+			c.PackageManager = manager
+			break
+		}
+	}
+
+	for _, tool := range commonTools {
+		if _, err := p.LookPath(tool); err == nil {
+			c.Tools = append(c.Tools, tool)
+		} else {
+			c.MissingTools = append(c.MissingTools, tool)
+		}
+	}
+
+	if dir, err := p.Getwd(); err == nil {
+		c.WorkingDir = dir
+	}
+
+	if branch, err := p.Run("git", "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		c.GitBranch = branch
+		if status, err := p.Run("git", "status", "--porcelain"); err == nil && status != "" {
+			c.GitDirty = len(strings.Split(status, "\n"))
+		}
+	}
+
+	return c
+}
+
+// osRelease describes the OS version: the distribution on Linux, the product version elsewhere
+func (p *Prober) osRelease() string {
+	switch p.GOOS {
+	case "linux":
+		data, err := p.ReadFile("/etc/os-release")
+		if err != nil {
+			return ""
+		}
+		return parseOSRelease(string(data))
+	case "darwin":
+		if version, err := p.Run("sw_vers", "-productVersion"); err == nil {
+			return "macOS " + version
+		}
+	case "windows":
+		if version, err := p.Run("cmd", "/c", "ver"); err == nil {
+			return version
+		}
+	}
+	return ""
+}
+
+// parseOSRelease extracts PRETTY_NAME (or NAME and VERSION_ID) from /etc/os-release content
+func parseOSRelease(content string) string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok {
+			values[key] = strings.Trim(value, `"'`)
+		}
+	}
+
+	if pretty := values["PRETTY_NAME"]; pretty != "" {
+		return pretty
+	}
+	return strings.TrimSpace(values["NAME"] + " " + values["VERSION_ID"])
+}
+
+// shell finds the interpreter that will run the script and its version
+func (p *Prober) shell(scriptType string) (string, string) {
+	if scriptType == "powershell" {
+		for _, name := range []string{"pwsh", "powershell"} {
+			if _, err := p.LookPath(name); err != nil {
+				continue
+			}
+			version, _ := p.Run(name, "-NoProfile", "-Command", "$PSVersionTable.PSVersion.ToString()")
+			return name, version
+		}
+		return "powershell", ""
+	}
+
+	output, err := p.Run("bash", "--version")
+	if err != nil {
+		return "bash", ""
+	}
+	return "bash", parseBashVersion(output)
+}
+
+// parseBashVersion finds "5.2.21" in "GNU bash, version 5.2.21(1)-release (x86_64-pc-linux-gnu)"
+func parseBashVersion(output string) string {
+	firstLine, _, _ := strings.Cut(output, "\n")
+	_, rest, ok := strings.Cut(firstLine, "version ")
+	if !ok {
+		return ""
+	}
+	version, _, _ := strings.Cut(rest, "(")
+	version, _, _ = strings.Cut(version, " ")
+	return version
+}
+
+// Summary renders the context as the lines sent to the model, leaving out excluded sections
+func (c *Context) Summary(exclude []string) string {
+	excluded := make(map[string]bool, len(exclude))
+	for _, section := range exclude {
+		excluded[section] = true
+	}
+
+	var lines []string
+	add := func(section, format string, args ...interface{}) {
+		if !excluded[section] {
+			lines = append(lines, "- "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	osLine := c.OS + "/" + c.Arch
+	if c.OSRelease != "" {
+		osLine = c.OSRelease + " (" + osLine + ")"
+	}
+	add(SectionOS, "OS: %s", osLine)
+
+	if c.ShellVersion != "" {
+		add(SectionShell, "Shell: %s %s", c.Shell, c.ShellVersion)
+	} else {
+		add(SectionShell, "Shell: %s", c.Shell)
+	}
+
+	if c.PackageManager != "" {
+		add(SectionPackageManager, "Package manager: %s", c.PackageManager)
+	} else {
+		add(SectionPackageManager, "Package manager: none detected")
+	}
+
+	if len(c.Tools) > 0 {
+		add(SectionTools, "Available tools: %s", strings.Join(c.Tools, ", "))
+	}
+	if len(c.MissingTools) > 0 {
+		add(SectionTools, "Not installed: %s", strings.Join(c.MissingTools, ", "))
+	}
+
+	if c.WorkingDir != "" {
+		add(SectionWorkingDir, "Working directory: %s", c.WorkingDir)
+	}
+
+	if c.GitBranch != "" {
+		state := "clean"
+		if c.GitDirty > 0 {
+			state = fmt.Sprintf("%d changed file(s)", c.GitDirty)
+		}
+		add(SectionGit, "Git repository: branch %s, %s", c.GitBranch, state)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// runCommand runs a probe command with a short timeout and returns its trimmed output
+func runCommand(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,92 @@
+package environment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"please/types"
+)
+
+// newFakeProber simulates a Fedora machine with bash 5.2, dnf, jq and git in a dirty repository
+func newFakeProber() *Prober {
+	installed := map[string]bool{"dnf": true, "jq": true, "git": true, "curl": true}
+	return &Prober{
+		GOOS: "linux",
+		LookPath: func(file string) (string, error) {
+			if installed[file] {
+				return "/usr/bin/" + file, nil
+			}
+			return "", errors.New("not found")
+		},
+		Run: func(name string, args ...string) (string, error) {
+			switch {
+			case name == "bash":
+				return "GNU bash, version 5.2.26(1)-release (x86_64-redhat-linux-gnu)\nCopyright (C) 2022", nil
+			case name == "git" && args[0] == "rev-parse":
+				return "main", nil
+			case name == "git" && args[0] == "status":
+				return " M main.go\n?? notes.txt", nil
+			}
+			return "", errors.New("unexpected command")
+		},
+		ReadFile: func(name string) ([]byte, error) {
+			return []byte("NAME=\"Fedora Linux\"\nVERSION_ID=40\nPRETTY_NAME=\"Fedora Linux 40 (Workstation Edition)\"\n"), nil
+		},
+		Getwd: func() (string, error) { return "/home/dev/project", nil },
+	}
+}
+
+func Test_when_probing_machine_then_summarize_os_shell_tools_and_git(t *testing.T) {
+	// Arrange
+	prober := newFakeProber()
+
+	// Act
+	summary := prober.Probe("bash").Summary(nil)
+
+	// Assert
+	for _, expected := range []string{
+		"OS: Fedora Linux 40 (Workstation Edition)",
+		"Shell: bash 5.2.26",
+		"Package manager: dnf",
+		"Available tools: jq, git, curl",
+		"Not installed: rsync, docker",
+		"Working directory: /home/dev/project",
+		"Git repository: branch main, 2 changed file(s)",
+	} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", expected, summary)
+		}
+	}
+}
+
+func Test_when_sections_are_excluded_then_leave_them_out_of_summary(t *testing.T) {
+	// Arrange
+	context := newFakeProber().Probe("bash")
+
+	// Act
+	summary := context.Summary([]string{SectionWorkingDir, SectionGit})
+
+	// Assert
+	if strings.Contains(summary, "/home/dev/project") || strings.Contains(summary, "Git repository") {
+		t.Errorf("Expected cwd and git to be excluded, got:\n%s", summary)
+	}
+	if !strings.Contains(summary, "Package manager: dnf") {
+		t.Errorf("Expected other sections to remain, got:\n%s", summary)
+	}
+}
+
+func Test_when_context_is_disabled_then_send_nothing(t *testing.T) {
+	// Arrange
+	cfg := &types.Config{Environment: types.EnvironmentConfig{Disabled: true}}
+
+	// Act & Assert
+	if ForRequest(cfg, "bash") != "" {
+		t.Error("Expected no context when disabled in config")
+	}
+
+	t.Setenv(DisableEnvVar, "1")
+	if Enabled(&types.Config{}) {
+		t.Error("Expected PLEASE_NO_CONTEXT to disable context")
+	}
+}
@@ -1,3 +1,5 @@
 module please
 
 go 1.24.4
+
+require mvdan.cc/sh/v3 v3.12.0
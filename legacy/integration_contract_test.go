@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/exec"
 	"strings"
 	"testing"
 
+	"please/providers"
+	"please/script"
 	"please/types"
 )
 
@@ -43,8 +47,8 @@ func testMainComponents(t *testing.T) {
 	
 	// 2. Fallback model selection
 	model := getFallbackModel("openai")
-	if model != "gpt-3.5-turbo" {
-		t.Errorf("Expected 'gpt-3.5-turbo', got '%s'", model)
+	if model != "gpt-4o-mini" {
+		t.Errorf("Expected 'gpt-4o-mini', got '%s'", model)
 	}
 	
 	// 3. Script generation (mock test)
@@ -127,3 +131,89 @@ func TestNaturalLanguageCommands_WhenLastScriptPatterns_ShouldRecognize(t *testi
 		})
 	}
 }
+
+// TestMockProviderPipeline_EndToEnd runs generate -> validate -> execute -> auto-fix offline using fixtures
+func TestMockProviderPipeline_WhenScriptFails_ShouldAutoFixFromFixtures(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	fixturesDir := t.TempDir()
+	t.Setenv("PLEASE_FIXTURES_DIR", fixturesDir)
+	t.Setenv("PLEASE_RECORD_PROVIDER", "")
+
+	brokenScript := "please_missing_command_for_test"
+	fixedScript := "echo fixed"
+	providers.SaveFixture(fixturesDir, &providers.Fixture{
+		TaskDescription: "run the broken thing",
+		ScriptType:      "bash",
+		Script:          brokenScript,
+	})
+
+	cfg := &types.Config{}
+	request := &types.ScriptRequest{
+		TaskDescription: "run the broken thing",
+		ScriptType:      "bash",
+		Provider:        "mock",
+		Model:           "mock",
+	}
+
+	// 1. Generate from the fixture
+	response, err := generateScript(context.Background(), cfg, request)
+	if err != nil {
+		t.Fatalf("Expected mock generation to succeed, got: %v", err)
+	}
+	if response.Script != brokenScript || response.Provider != "mock" {
+		t.Fatalf("Expected broken fixture script from mock, got %s from %s", response.Script, response.Provider)
+	}
+
+	// 2. Validate and execute, which fails
+	script.ValidateScript(response)
+	execErr := script.ExecuteScript(response)
+	if execErr == nil {
+		t.Fatal("Expected broken script to fail")
+	}
+
+	// 3. Auto-fix answers from a fixture keyed by the fix prompt hash
+	providers.SaveFixture(fixturesDir, &providers.Fixture{
+		TaskDescription: "auto-fix",
+		PromptHash: providers.PromptHash(&types.ScriptRequest{
+			TaskDescription: providers.CreateFixPrompt(brokenScript, execErr.Error()),
+			ScriptType:      "bash",
+		}),
+		Script: fixedScript,
+	})
+
+	fixed, err := providers.GenerateFixedScript(brokenScript, execErr.Error(), "bash", "mock", "mock", cfg)
+	if err != nil {
+		t.Fatalf("Expected mock auto-fix to succeed, got: %v", err)
+	}
+	if fixed != fixedScript {
+		t.Fatalf("Expected fixed fixture script, got '%s'", fixed)
+	}
+
+	// 4. The fixed script runs cleanly
+	response.Script = fixed
+	if err := script.ExecuteScript(response); err != nil {
+		t.Errorf("Expected fixed script to run, got: %v", err)
+	}
+}
+
+// TestMockProviderFixtures_Bundled checks the fixtures shipped in testdata replay without network
+func TestMockProviderFixtures_WhenBundledFixturesUsed_ShouldReplayScripts(t *testing.T) {
+	t.Setenv("PLEASE_FIXTURES_DIR", "testdata/fixtures")
+
+	request := &types.ScriptRequest{
+		TaskDescription: "List files in current directory",
+		ScriptType:      "bash",
+		Provider:        "mock",
+	}
+
+	response, err := generateScript(context.Background(), &types.Config{}, request)
+	if err != nil {
+		t.Fatalf("Expected bundled fixture to replay, got: %v", err)
+	}
+	if !strings.Contains(response.Script, "ls -la") || response.Model != "fixture" {
+		t.Errorf("Unexpected bundled fixture response: %+v", response)
+	}
+}
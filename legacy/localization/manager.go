@@ -180,6 +180,8 @@ func getFromConfig(cfg *types.LocalizationConfig, cat, field string) string {
 		case "exit_quick":
 			return cfg.Messages.Success.ExitQuick
 		}
+	case "risks":
+		return cfg.Messages.Risks[field]
 	}
 	return ""
 }
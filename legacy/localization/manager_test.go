@@ -190,3 +190,13 @@ func Test_when_getting_script_display_message_then_return_values(t *testing.T) {
 		t.Errorf("expected task label")
 	}
 }
+
+func Test_when_getting_risk_message_then_look_up_rule_in_risks(t *testing.T) {
+	mgr := &LocalizationManager{config: &types.LocalizationConfig{Messages: types.Messages{Risks: map[string]string{"recursive_delete": "Borrado recursivo"}}}}
+	if mgr.GetMessage("risks.recursive_delete") != "Borrado recursivo" {
+		t.Errorf("expected risk message")
+	}
+	if mgr.GetMessage("risks.shutdown") != "" {
+		t.Errorf("expected empty string for untranslated rule")
+	}
+}
@@ -1,25 +1,49 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 
 	"please/config"
+	"please/environment"
 	"please/localization"
 	"please/models"
 	"please/providers"
 	"please/script"
 	"please/types"
 	"please/ui"
+	"please/usage"
 )
 
 func main() {
+	// Dry runs start Please again inside new namespaces to set up the sandbox
+	if len(os.Args) > 1 && os.Args[1] == script.SandboxInitArg {
+		script.RunSandboxInit(os.Args[2:])
+	}
+
 	lang := "en-us"
 	theme := "default"
+	noCache := false
+	noContext := false
+	dryRun := false
 	args := []string{}
 	for _, arg := range os.Args[1:] {
+		if arg == "--no-cache" {
+			noCache = true
+			continue
+		}
+		if arg == "--no-context" {
+			noContext = true
+			continue
+		}
+		if arg == "--dry-run" {
+			dryRun = true
+			continue
+		}
 		if strings.HasPrefix(arg, "--language=") {
 			lang = strings.SplitN(arg, "=", 2)[1]
 			continue
@@ -65,6 +89,36 @@ func main() {
 		case "--test-monitor", "--monitor-tests":
 			runTestMonitor()
 			return
+		case "usage":
+			if len(args) == 1 || (len(args) == 2 && strings.HasPrefix(args[1], "--days=")) {
+				runUsageCommand(args[1:])
+				return
+			}
+		case "models":
+			if len(args) >= 2 && (args[1] == "list" || args[1] == "explain" || args[1] == "pin" || args[1] == "stats") {
+				runModelsCommand(args[1:])
+				return
+			}
+		case "context":
+			if len(args) == 1 {
+				runContextCommand(noContext)
+				return
+			}
+		case "prompts":
+			if len(args) >= 2 && (args[1] == "show" || args[1] == "edit" || args[1] == "reset") {
+				runPromptsCommand(args[1:])
+				return
+			}
+		case "policy":
+			if (len(args) == 3 || (len(args) == 4 && args[3] == "--json")) && args[1] == "test" {
+				runPolicyCommand(args[1:])
+				return
+			}
+		case "cache":
+			if len(args) == 2 && (args[1] == "stats" || args[1] == "clear") {
+				runCacheCommand(args[1])
+				return
+			}
 		}
 	}
 
@@ -95,73 +149,168 @@ func main() {
 	scriptType := config.DetermineScriptType(cfg)
 	provider := config.DetermineProvider(cfg)
 
-	// Select the best model for the task
-	model, err := models.SelectBestModel(cfg, taskDescription, provider)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Could not auto-select model (%v), using fallback\n", err)
-		// Use fallback based on provider
-		model = getFallbackModel(provider)
-	}
-
-	// Create the script request
+	// Create the script request with the best model for the task
 	request := &types.ScriptRequest{
 		TaskDescription: taskDescription,
 		ScriptType:      scriptType,
 		Provider:        provider,
-		Model:           model,
+		Model:           selectModel(cfg, taskDescription, provider),
+	}
+	if !noContext {
+		request.Environment = environment.ForRequest(cfg, scriptType)
+	}
+
+	// Serve repeated requests from the response cache
+	responseCache := openResponseCache(cfg, request, noCache)
+	if responseCache != nil {
+		if cached, ok := responseCache.Get(request); ok {
+			displayScriptAndConfirm(cached, dryRun)
+			return
+		}
 	}
 
-	// Generate script using the appropriate provider
-	response, err := generateScript(cfg, request)
+	// Ctrl+C cancels generation cleanly instead of killing the process mid-request
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	// Generate script using the appropriate provider, streaming it into the display box
+	response, err := generateScript(ctx, cfg, request)
+	cancel()
 	if err != nil {
+		if providers.IsCancelled(err) {
+			fmt.Fprintf(os.Stderr, "Script generation cancelled\n")
+			os.Exit(130)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if providerErr, ok := providers.AsProviderError(err); ok && providerErr.Remediation() != "" {
+			fmt.Fprintf(os.Stderr, "💡 %s\n", providerErr.Remediation())
+		}
 		os.Exit(1)
 	}
 
-	// Display the script with explanation and ask for confirmation
-	displayScriptAndConfirm(response)
+	// Price the call, log it for "please usage" and show it under the streamed script
+	usage.Apply(cfg, response)
+	recordUsage(response)
+	if response.Usage.PromptTokens+response.Usage.CompletionTokens > 0 {
+		fmt.Println()
+		printUsageLine(response)
+	}
+
+	if responseCache != nil {
+		if err := responseCache.Put(request, response); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not cache script (%v)\n", err)
+		}
+	}
+
+	// Finish the display and ask for confirmation
+	confirmScript(response, dryRun)
 }
 
-// generateScript creates a script using the appropriate provider
-func generateScript(cfg *types.Config, request *types.ScriptRequest) (*types.ScriptResponse, error) {
-	var provider providers.Provider
+// generateScript creates a script using the first provider in the fallback chain that can serve it,
+// stopping early if ctx is cancelled
+func generateScript(ctx context.Context, cfg *types.Config, request *types.ScriptRequest) (*types.ScriptResponse, error) {
+	chain := providers.FallbackChain(request.Provider, cfg)
+
+	var lastErr error
+	for i, providerName := range chain {
+		attempt := *request
+		if i > 0 {
+			fmt.Fprintf(os.Stderr, "⚠️  %s unavailable (%v), falling back to %s\n", chain[i-1], lastErr, providerName)
+			attempt.Provider = providerName
+			attempt.Model = selectModel(cfg, request.TaskDescription, providerName)
+		}
 
-	switch request.Provider {
-	case "ollama":
-		provider = providers.NewOllamaProvider(cfg)
-	case "openai":
-		provider = providers.NewOpenAIProvider(cfg)
-	case "anthropic":
-		provider = providers.NewAnthropicProvider(cfg)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", request.Provider)
+		response, streamed, err := generateScriptWithProvider(ctx, cfg, &attempt)
+		if err == nil {
+			return response, nil
+		}
+
+		// Once lines have been shown, switching provider would mix two scripts on screen
+		lastErr = err
+		if streamed || !providers.ShouldFallback(err) {
+			return nil, err
+		}
 	}
 
-	if !provider.IsConfigured(cfg) {
-		return nil, fmt.Errorf("provider %s is not properly configured", request.Provider)
+	return nil, lastErr
+}
+
+// generateScriptWithProvider streams a script from request.Provider into the display box and
+// reports whether any lines were shown before an error
+func generateScriptWithProvider(ctx context.Context, cfg *types.Config, request *types.ScriptRequest) (*types.ScriptResponse, bool, error) {
+	provider, err := providers.NewConfiguredProvider(request.Provider, cfg)
+	if err != nil {
+		return nil, false, err
 	}
 
-	// Show progress indication during script generation
-	stopProgress := ui.ShowProviderProgress(request.Provider, "Generating script")
-	defer stopProgress()
+	// Show progress indication until the first line of the script arrives
+	stopProgress := ui.ShowCancellableProviderProgress(ctx, request.Provider, "Generating script")
+
+	lineNum := 0
+	response, err := providers.GenerateScriptStreaming(ctx, provider, request, func(line string) {
+		if lineNum == 0 {
+			stopProgress(nil)
+			printScriptHeader(&types.ScriptResponse{
+				TaskDescription: request.TaskDescription,
+				Model:           request.Model,
+				Provider:        request.Provider,
+				ScriptType:      request.ScriptType,
+			})
+		}
+		lineNum++
+		printScriptLine(lineNum, line)
+	})
+	stopProgress(err)
+	if err != nil {
+		return nil, lineNum > 0, err
+	}
 
-	return provider.GenerateScript(request)
+	// Empty scripts still get a header so the confirmation has context
+	if lineNum == 0 {
+		printScriptHeader(response)
+	}
+
+	return response, true, nil
+}
+
+// selectModel picks the best model for the task on provider, falling back to the provider default
+func selectModel(cfg *types.Config, taskDescription, provider string) string {
+	model, err := models.SelectBestModel(cfg, taskDescription, provider)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not auto-select model (%v), using fallback\n", err)
+		// Use fallback based on provider
+		model = getFallbackModel(provider)
+	}
+	return model
 }
 
 // getFallbackModel returns a fallback model based on provider
 func getFallbackModel(provider string) string {
 	switch provider {
 	case "openai":
-		return "gpt-3.5-turbo"
+		return models.SelectOpenAIModel("general")
 	case "anthropic":
-		return "claude-3-haiku-20240307"
+		return models.SelectAnthropicModel("general")
 	default:
 		return "llama3.2"
 	}
 }
 
 // displayScriptAndConfirm shows the generated script with explanation and interactive menu
-func displayScriptAndConfirm(response *types.ScriptResponse) {
+func displayScriptAndConfirm(response *types.ScriptResponse, dryRun bool) {
+	printScriptHeader(response)
+
+	// Display the script with line numbers
+	lines := strings.Split(response.Script, "\n")
+	for i, line := range lines {
+		printScriptLine(i+1, line)
+	}
+
+	confirmScript(response, dryRun)
+}
+
+// printScriptHeader prints the banner, task details and the script box title, labelling cache hits
+// and showing token usage when the response already has it
+func printScriptHeader(response *types.ScriptResponse) {
 	fmt.Printf("╔══════════════════════════════════════════════════════════════════════════════╗\n")
 	fmt.Printf("║                           🤖 Please Script Generator                         ║\n")
 	fmt.Printf("╚══════════════════════════════════════════════════════════════════════════════╝\n\n")
@@ -187,28 +336,66 @@ func displayScriptAndConfirm(response *types.ScriptResponse) {
 		scriptHeader = "📋 Generated Script"
 	}
 
-	successMessage := ui.GetLocalizedMessage("script_display.success_message")
-	if successMessage == "" {
-		successMessage = "✅ Script generated successfully!"
-	}
-
 	fmt.Printf("%s %s\n", taskLabel, response.TaskDescription)
 	fmt.Printf("%s %s (%s)\n", modelLabel, response.Model, response.Provider)
+	if response.Usage.PromptTokens+response.Usage.CompletionTokens > 0 {
+		printUsageLine(response)
+	}
 	fmt.Printf("%s %s script\n", platformLabel, response.ScriptType)
+	if response.Cached {
+		cachedLabel := ui.GetLocalizedMessage("script_display.cached_label")
+		if cachedLabel == "" {
+			cachedLabel = "⚡ Cached response (use --no-cache for a fresh one)"
+		}
+		fmt.Printf("%s\n", cachedLabel)
+	}
 
 	fmt.Printf("\n╔══════════════════════════════════════════════════════════════════════════════╗\n")
 	fmt.Printf("║                              %s                             ║\n", scriptHeader)
 	fmt.Printf("╚══════════════════════════════════════════════════════════════════════════════╝\n\n")
+}
 
-	// Display the script with line numbers
-	lines := strings.Split(response.Script, "\n")
-	for i, line := range lines {
-		lineNum := fmt.Sprintf("%3d", i+1)
-		fmt.Printf("\033[90m%s│\033[0m %s\n", lineNum, line)
+// printUsageLine shows token counts, latency and cost for the response
+func printUsageLine(response *types.ScriptResponse) {
+	usageLabel := ui.GetLocalizedMessage("script_display.usage_label")
+	if usageLabel == "" {
+		usageLabel = "📊 Usage:"
+	}
+
+	line := fmt.Sprintf("%s %d prompt + %d completion tokens", usageLabel, response.Usage.PromptTokens, response.Usage.CompletionTokens)
+	if response.Usage.Latency > 0 {
+		line += fmt.Sprintf(" · %.1fs", response.Usage.Latency.Seconds())
 	}
+	if response.Usage.CostUSD > 0 {
+		line += fmt.Sprintf(" · $%.4f", response.Usage.CostUSD)
+	}
+	if response.Cached {
+		line += " (not charged again)"
+	}
+	fmt.Printf("\033[90m%s\033[0m\n", line)
+}
 
+// printScriptLine prints a single numbered line of the script listing
+func printScriptLine(lineNum int, line string) {
+	fmt.Printf("\033[90m%3d│\033[0m %s\n", lineNum, line)
+}
+
+// confirmScript prints the success message and shows the interactive script menu, first running
+// the script in the sandbox with --dry-run
+func confirmScript(response *types.ScriptResponse, dryRun bool) {
+	successMessage := ui.GetLocalizedMessage("script_display.success_message")
+	if successMessage == "" {
+		successMessage = "✅ Script generated successfully!"
+	}
+
+	ui.PrintScriptNotes(response)
 	fmt.Printf("\n%s\n", successMessage)
 
+	if dryRun {
+		fmt.Println()
+		ui.DryRunScript(response)
+	}
+
 	// Show interactive menu
 	ui.ShowScriptMenu(response)
 }
@@ -314,21 +501,9 @@ func runTestMonitor() {
 	providerName := config.DetermineProvider(cfg)
 
 	// Create the appropriate provider
-	var provider providers.Provider
-	switch providerName {
-	case "ollama":
-		provider = providers.NewOllamaProvider(cfg)
-	case "openai":
-		provider = providers.NewOpenAIProvider(cfg)
-	case "anthropic":
-		provider = providers.NewAnthropicProvider(cfg)
-	default:
-		fmt.Fprintf(os.Stderr, "Error: Unsupported provider: %s\n", providerName)
-		os.Exit(1)
-	}
-
-	if !provider.IsConfigured(cfg) {
-		fmt.Fprintf(os.Stderr, "Error: Provider %s is not properly configured\n", providerName)
+	provider, err := providers.NewConfiguredProvider(providerName, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
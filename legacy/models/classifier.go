@@ -0,0 +1,383 @@
+package models
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"please/config"
+)
+
+// defaultLexicons are the keyword lexicons shipped with Please
+//
+//go:embed lexicons.json
+var defaultLexicons []byte
+
+// LexiconFileName is the user override for the classifier lexicons, stored in the config directory
+const LexiconFileName = "lexicons.json"
+
+// categoryOrder breaks ties between equally scored categories, most specific intent first
+var categoryOrder = []string{"coding", "network", "sysadmin", "filemanagement"}
+
+// Lexicons configure the task classifier. Every language's lexicon is applied to each task, so
+// a Spanish request is understood even when the interface is in English.
+type Lexicons struct {
+	MinimumScore   float64             `json:"minimum_score"`   // Evidence needed before a task leaves "general"
+	NegationWindow int                 `json:"negation_window"` // Words after a negation that do not count
+	Languages      map[string]Language `json:"languages"`
+}
+
+// Language is one language's keywords, phrases and grammar hints
+type Language struct {
+	Suffixes      []string                   `json:"suffixes"`       // Inflections accepted after a keyword
+	Negations     []string                   `json:"negations"`      // Words such as "not" or "sans"
+	ScopeBreakers []string                   `json:"scope_breakers"` // Words such as "but" that end a negation
+	Categories    map[string]CategoryLexicon `json:"categories"`
+}
+
+// CategoryLexicon weights the words and phrases that signal one category
+type CategoryLexicon struct {
+	Keywords map[string]float64 `json:"keywords"`
+	Phrases  map[string]float64 `json:"phrases"`
+}
+
+// Classification is the classifier's verdict on a task description
+type Classification struct {
+	Category   string             // Best category, "general" when nothing matched strongly enough
+	Confidence float64            // Share of the evidence held by Category, 0-1
+	Scores     map[string]float64 // Weighted evidence per category
+}
+
+// Labels returns every category holding at least minShare of the evidence, best first. A
+// "general" task has no trustworthy categories and is labelled "general" alone.
+func (c Classification) Labels(minShare float64) []string {
+	if c.Category == "general" {
+		return []string{c.Category}
+	}
+
+	total := 0.0
+	for _, score := range c.Scores {
+		total += score
+	}
+	var labels []string
+	for _, category := range rankedCategories(c.Scores) {
+		if c.Scores[category]/total >= minShare {
+			labels = append(labels, category)
+		}
+	}
+	return labels
+}
+
+// CategorizeTask determines the type of task to help with model selection
+func CategorizeTask(description string) string {
+	return ClassifyTask(description).Category
+}
+
+// ClassifyTask scores a task description against the shipped lexicons and the user's lexicons.json
+func ClassifyTask(description string) Classification {
+	lexicons, _ := LoadLexicons()
+	return lexicons.Classify(description)
+}
+
+// DefaultLexicons returns the lexicons shipped with Please
+func DefaultLexicons() *Lexicons {
+	var lexicons Lexicons
+	if err := json.Unmarshal(defaultLexicons, &lexicons); err != nil {
+		panic(fmt.Sprintf("invalid embedded lexicons: %v", err))
+	}
+	return &lexicons
+}
+
+// LoadLexicons returns the shipped lexicons merged with the user's lexicons.json, if any. If the
+// user file cannot be read, the shipped lexicons are returned along with the error.
+func LoadLexicons() (*Lexicons, error) {
+	lexicons := DefaultLexicons()
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return lexicons, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, LexiconFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lexicons, nil
+		}
+		return lexicons, fmt.Errorf("failed to read lexicon file: %v", err)
+	}
+
+	var override Lexicons
+	if err := json.Unmarshal(data, &override); err != nil {
+		return lexicons, fmt.Errorf("failed to parse lexicon file: %v", err)
+	}
+
+	lexicons.Merge(&override)
+	return lexicons, nil
+}
+
+// Merge applies override on top of the lexicons. Keyword and phrase weights replace ours, and a
+// weight of 0 removes the entry; word lists are extended.
+func (l *Lexicons) Merge(override *Lexicons) {
+	if override.MinimumScore != 0 {
+		l.MinimumScore = override.MinimumScore
+	}
+	if override.NegationWindow != 0 {
+		l.NegationWindow = override.NegationWindow
+	}
+	if l.Languages == nil {
+		l.Languages = make(map[string]Language)
+	}
+
+	for code, extra := range override.Languages {
+		language := l.Languages[code]
+		language.Suffixes = append(language.Suffixes, extra.Suffixes...)
+		language.Negations = append(language.Negations, extra.Negations...)
+		language.ScopeBreakers = append(language.ScopeBreakers, extra.ScopeBreakers...)
+		if language.Categories == nil {
+			language.Categories = make(map[string]CategoryLexicon)
+		}
+
+		for category, words := range extra.Categories {
+			lexicon := language.Categories[category]
+			lexicon.Keywords = mergeWeights(lexicon.Keywords, words.Keywords)
+			lexicon.Phrases = mergeWeights(lexicon.Phrases, words.Phrases)
+			language.Categories[category] = lexicon
+		}
+		l.Languages[code] = language
+	}
+}
+
+// mergeWeights copies override into base, deleting entries whose weight is 0
+func mergeWeights(base, override map[string]float64) map[string]float64 {
+	if base == nil {
+		base = make(map[string]float64)
+	}
+	for word, weight := range override {
+		if weight == 0 {
+			delete(base, word)
+		} else {
+			base[word] = weight
+		}
+	}
+	return base
+}
+
+// Classify tokenizes the description and adds up the weights of the keywords and phrases it
+// contains, ignoring those within a negation such as "without deleting anything". A word listed
+// by several languages (such as "script") only counts once, with its highest weight.
+func (l *Lexicons) Classify(description string) Classification {
+	tokens := tokenize(description)
+	negated := l.negatedTokens(tokens)
+	scores := make(map[string]float64)
+
+	for i, token := range tokens {
+		if token == "" || negated[i] {
+			continue
+		}
+		best := make(map[string]float64)
+		for _, language := range l.Languages {
+			for category, lexicon := range language.Categories {
+				if weight, ok := language.matchKeyword(lexicon.Keywords, token); ok && weight > best[category] {
+					best[category] = weight
+				}
+			}
+		}
+		for category, weight := range best {
+			scores[category] += weight
+		}
+	}
+
+	matched := make(map[string]bool)
+	for _, language := range l.Languages {
+		for category, lexicon := range language.Categories {
+			for phrase, weight := range lexicon.Phrases {
+				words := tokenize(phrase)
+				key := category + "\x00" + strings.Join(words, " ")
+				if matched[key] {
+					continue
+				}
+				for i := 0; i+len(words) <= len(tokens); i++ {
+					if !negated[i] && equalTokens(tokens[i:i+len(words)], words) {
+						scores[category] += weight
+						matched[key] = true
+					}
+				}
+			}
+		}
+	}
+
+	result := Classification{Category: "general", Confidence: 1, Scores: scores}
+	ranked := rankedCategories(scores)
+	if len(ranked) == 0 {
+		return result
+	}
+
+	total := 0.0
+	for _, score := range scores {
+		total += score
+	}
+	best := ranked[0]
+	if scores[best] < l.MinimumScore {
+		// Some evidence, but too little to trust; the more there is, the less sure "general" is
+		result.Confidence = 1 - scores[best]/l.MinimumScore
+		return result
+	}
+
+	result.Category = best
+	result.Confidence = scores[best] / total
+	return result
+}
+
+// negatedTokens marks the tokens that fall within NegationWindow words after a negation in any
+// language, stopping at punctuation and scope breakers such as "but"
+func (l *Lexicons) negatedTokens(tokens []string) []bool {
+	negations := make(map[string]bool)
+	breakers := make(map[string]bool)
+	for _, language := range l.Languages {
+		for word := range foldedSet(language.Negations) {
+			negations[word] = true
+		}
+		for word := range foldedSet(language.ScopeBreakers) {
+			breakers[word] = true
+		}
+	}
+
+	negated := make([]bool, len(tokens))
+	remaining := 0
+	for i, token := range tokens {
+		switch {
+		case token == "" || breakers[token]:
+			remaining = 0
+		case negations[token]:
+			remaining = l.NegationWindow
+		case remaining > 0:
+			negated[i] = true
+			remaining--
+		}
+	}
+	return negated
+}
+
+// matchKeyword looks token up directly and with each of the language's suffixes removed, also
+// restoring a silent "e" so that "moving" matches "move". Keywords may be written with accents.
+func (language Language) matchKeyword(keywords map[string]float64, token string) (float64, bool) {
+	candidates := []string{token}
+	for _, suffix := range language.Suffixes {
+		if stem, ok := strings.CutSuffix(token, foldAccents(suffix)); ok && len(stem) >= 3 {
+			candidates = append(candidates, stem, stem+"e")
+		}
+	}
+
+	for keyword, weight := range keywords {
+		folded := foldAccents(strings.ToLower(keyword))
+		for _, candidate := range candidates {
+			if candidate == folded {
+				return weight, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// tokenize lower-cases and accent-folds text into words. Apostrophes are dropped ("don't" becomes
+// "dont") and sentence punctuation becomes an empty token that ends a negation's scope.
+func tokenize(text string) []string {
+	var tokens []string
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range foldAccents(strings.ToLower(text)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		case r == '\'' || r == '’':
+			// Part of a contraction
+		case strings.ContainsRune(",.;:!?()", r):
+			flush()
+			tokens = append(tokens, "")
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// accentFolds maps the accented letters used by the shipped languages to their base letter
+var accentFolds = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// foldAccents strips accents so "télécharger" and "telecharger" match
+func foldAccents(s string) string {
+	return accentFolds.Replace(s)
+}
+
+// foldedSet builds a lookup set of lower-cased, accent-folded words
+func foldedSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		for _, token := range tokenize(word) {
+			set[token] = true
+		}
+	}
+	return set
+}
+
+// equalTokens reports whether two token sequences are identical
+func equalTokens(a, b []string) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// rankedCategories returns the categories with evidence, highest score first; ties follow categoryOrder
+func rankedCategories(scores map[string]float64) []string {
+	var categories []string
+	for category, score := range scores {
+		if score > 0 {
+			categories = append(categories, category)
+		}
+	}
+
+	order := func(category string) int {
+		for i, known := range categoryOrder {
+			if category == known {
+				return i
+			}
+		}
+		return len(categoryOrder)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if scores[categories[i]] != scores[categories[j]] {
+			return scores[categories[i]] > scores[categories[j]]
+		}
+		if order(categories[i]) != order(categories[j]) {
+			return order(categories[i]) < order(categories[j])
+		}
+		return categories[i] < categories[j]
+	})
+	return categories
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"please/types"
+)
+
+// Hardware describes the memory available for running a local model
+type Hardware struct {
+	TotalMemory     uint64 // Bytes of RAM, or the container's limit if lower
+	AvailableMemory uint64 // Bytes that can be used without swapping
+}
+
+// Linux sources for memory information; variables so tests can point them at fixtures
+var (
+	meminfoPath       = "/proc/meminfo"
+	cgroupMaxPath     = "/sys/fs/cgroup/memory.max"
+	cgroupCurrentPath = "/sys/fs/cgroup/memory.current"
+)
+
+// defaultContextSize is Ollama's default num_ctx; a model's maximum context is rarely allocated
+const defaultContextSize = 4096
+
+// DetectHardware reads the machine's memory. It returns nil when the platform does not expose
+// /proc/meminfo, in which case selection ignores hardware as before.
+func DetectHardware() *Hardware {
+	file, err := os.Open(meminfoPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	hardware := parseMeminfo(file)
+	if hardware == nil {
+		return nil
+	}
+
+	// Containers (CI runners in particular) may be limited well below the host's RAM
+	if limit, ok := readCgroupValue(cgroupMaxPath); ok && limit < hardware.TotalMemory {
+		hardware.TotalMemory = limit
+		if used, ok := readCgroupValue(cgroupCurrentPath); ok && used < limit {
+			hardware.AvailableMemory = min(hardware.AvailableMemory, limit-used)
+		}
+	}
+	return hardware
+}
+
+// parseMeminfo reads MemTotal and MemAvailable (in kB) from /proc/meminfo content
+func parseMeminfo(r io.Reader) *Hardware {
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if kb, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[strings.TrimSuffix(fields[0], ":")] = kb * 1024
+		}
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return nil
+	}
+	available, ok := values["MemAvailable"]
+	if !ok {
+		// Kernels before 3.14 lack MemAvailable
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return &Hardware{TotalMemory: total, AvailableMemory: available}
+}
+
+// readCgroupValue reads a cgroup v2 memory file; "max" means unlimited
+func readCgroupValue(path string) (uint64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// EstimateMemory approximates the memory a model needs once loaded: its weights plus a share
+// for the runtime and the KV cache, which grows with the context Ollama allocates
+func EstimateMemory(model types.ModelInfo, overhead float64) uint64 {
+	if model.Size <= 0 {
+		return 0
+	}
+
+	context := defaultContextSize
+	if model.ContextLength > 0 && model.ContextLength < context {
+		context = model.ContextLength
+	}
+
+	weights := float64(model.Size)
+	// Roughly 128KB of KV cache per token for a 7-8B model, scaled by model size
+	kvCache := float64(context) * 128 * 1024 * (weights / 4.5e9)
+	return uint64(weights*(1+overhead) + kvCache)
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"please/config"
+)
+
+// OutcomesFileName is where learned model outcomes are stored in the config directory
+const OutcomesFileName = "model_outcomes.json"
+
+// Outcome is what happened to a generated script after it was shown to the user
+type Outcome string
+
+const (
+	OutcomeSuccess   Outcome = "success"    // Executed without errors
+	OutcomeFailed    Outcome = "failed"     // Execution failed
+	OutcomeAutoFixed Outcome = "auto_fixed" // A failed script was repaired by the AI
+	OutcomeEdited    Outcome = "edited"     // The user changed the script by hand
+	OutcomeRefined   Outcome = "refined"    // The user asked the AI to change the script
+)
+
+// OutcomeCounts tallies the outcomes of one model for one task category
+type OutcomeCounts struct {
+	Success   int `json:"success"`
+	Failed    int `json:"failed"`
+	AutoFixed int `json:"auto_fixed"`
+	Edited    int `json:"edited"`
+	Refined   int `json:"refined"`
+}
+
+// ModelOutcomes holds a model's counts per task category
+type ModelOutcomes struct {
+	Provider   string                    `json:"provider"`
+	Categories map[string]*OutcomeCounts `json:"categories"`
+}
+
+// OutcomeTable is the learned record of how each model's scripts fared
+type OutcomeTable struct {
+	Models map[string]*ModelOutcomes `json:"models"`
+	path   string
+}
+
+// OutcomeRow is one model/category line of the learned table, as shown by "please models stats"
+type OutcomeRow struct {
+	Model    string
+	Provider string
+	Category string
+	Counts   OutcomeCounts
+}
+
+// Add increments the counter for outcome
+func (c *OutcomeCounts) Add(outcome Outcome) {
+	switch outcome {
+	case OutcomeSuccess:
+		c.Success++
+	case OutcomeFailed:
+		c.Failed++
+	case OutcomeAutoFixed:
+		c.AutoFixed++
+	case OutcomeEdited:
+		c.Edited++
+	case OutcomeRefined:
+		c.Refined++
+	}
+}
+
+// Total returns the number of recorded outcomes; auto-fixes follow a failure and are not counted again
+func (c OutcomeCounts) Total() int {
+	return c.Success + c.Failed + c.Edited + c.Refined
+}
+
+// SuccessRate returns a Laplace-smoothed share of scripts that worked as generated. Failures
+// count fully against the model; edits and refinements count half, since the script was
+// usable but not quite right. Auto-fixes are already counted as failures. With no data the
+// rate is 0.5, and it moves away from that only as outcomes accumulate.
+func (c OutcomeCounts) SuccessRate() float64 {
+	good := float64(c.Success)
+	bad := float64(c.Failed) + 0.5*float64(c.Edited+c.Refined)
+	return (good + 1) / (good + bad + 2)
+}
+
+// Adjustment converts the success rate into a score change of at most ±weight/2
+func (c OutcomeCounts) Adjustment(weight int) int {
+	return int(math.Round((c.SuccessRate() - 0.5) * float64(weight)))
+}
+
+// NewOutcomeTable creates an empty table stored at path
+func NewOutcomeTable(path string) *OutcomeTable {
+	return &OutcomeTable{Models: make(map[string]*ModelOutcomes), path: path}
+}
+
+// LoadOutcomes reads the learned table from the config directory; a missing file is an empty table
+func LoadOutcomes() (*OutcomeTable, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadOutcomeTable(filepath.Join(configDir, OutcomesFileName))
+}
+
+// LoadOutcomeTable reads the learned table from path
+func LoadOutcomeTable(path string) (*OutcomeTable, error) {
+	table := NewOutcomeTable(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return table, nil
+		}
+		return table, fmt.Errorf("failed to read model outcomes: %v", err)
+	}
+
+	if err := json.Unmarshal(data, table); err != nil {
+		return NewOutcomeTable(path), fmt.Errorf("failed to parse model outcomes: %v", err)
+	}
+	if table.Models == nil {
+		table.Models = make(map[string]*ModelOutcomes)
+	}
+	return table, nil
+}
+
+// RecordOutcome adds one outcome for model in the category of taskDescription and saves the table
+func RecordOutcome(model, provider, taskDescription string, outcome Outcome) error {
+	if model == "" {
+		return nil
+	}
+
+	table, err := LoadOutcomes()
+	if err != nil {
+		return err
+	}
+	table.Record(model, provider, CategorizeTask(taskDescription), outcome)
+	return table.Save()
+}
+
+// Record adds one outcome for model in category
+func (t *OutcomeTable) Record(model, provider, category string, outcome Outcome) {
+	entry, ok := t.Models[model]
+	if !ok {
+		entry = &ModelOutcomes{Categories: make(map[string]*OutcomeCounts)}
+		t.Models[model] = entry
+	}
+	if provider != "" {
+		entry.Provider = provider
+	}
+
+	counts, ok := entry.Categories[category]
+	if !ok {
+		counts = &OutcomeCounts{}
+		entry.Categories[category] = counts
+	}
+	counts.Add(outcome)
+}
+
+// Counts returns the recorded counts for model in category
+func (t *OutcomeTable) Counts(model, category string) (OutcomeCounts, bool) {
+	if t == nil {
+		return OutcomeCounts{}, false
+	}
+	entry, ok := t.Models[model]
+	if !ok {
+		return OutcomeCounts{}, false
+	}
+	counts, ok := entry.Categories[category]
+	if !ok {
+		return OutcomeCounts{}, false
+	}
+	return *counts, true
+}
+
+// Rows lists every model/category pair, sorted by model then category
+func (t *OutcomeTable) Rows() []OutcomeRow {
+	var rows []OutcomeRow
+	for model, entry := range t.Models {
+		for category, counts := range entry.Categories {
+			rows = append(rows, OutcomeRow{Model: model, Provider: entry.Provider, Category: category, Counts: *counts})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Model != rows[j].Model {
+			return rows[i].Model < rows[j].Model
+		}
+		return rows[i].Category < rows[j].Category
+	})
+	return rows
+}
+
+// Path returns where the table is stored
+func (t *OutcomeTable) Path() string {
+	return t.path
+}
+
+// Save writes the table back to disk
+func (t *OutcomeTable) Save() error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal model outcomes: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(t.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write model outcomes: %v", err)
+	}
+	return nil
+}
@@ -1,79 +1,441 @@
 package models
 
 import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"please/config"
 	"please/types"
 )
 
+// defaultRanking is the ranking table shipped with Please
+//
+//go:embed ranking.json
+var defaultRanking []byte
+
+// RankingFileName is the user override for the ranking table, stored in the config directory
+const RankingFileName = "ranking.json"
+
+// RankingTable describes how models are scored for script generation. Patterns are matched
+// against the lower-cased model name: a plain pattern matches anywhere in the name, a pattern
+// containing * or ? is a glob over the name without its ":tag", and a "re:" prefix marks a
+// regular expression searched in the full name.
+// When several patterns match, the most specific one (the most literal characters) wins.
+type RankingTable struct {
+	DefaultScore   int                       `json:"default_score"`
+	Families       []FamilyRule              `json:"families"`
+	SizeBonuses    []SizeBonus               `json:"size_bonuses"`
+	Recency        *RecencyBonus             `json:"recency,omitempty"`
+	CategoryBoosts map[string][]CategoryRule `json:"category_boosts"`
+	Quantization   map[string]int            `json:"quantization"`
+	Learning       *LearningWeight           `json:"learning,omitempty"`
+	Hardware       *HardwareRules            `json:"hardware,omitempty"`
+}
+
+// FamilyRule gives every model matching a pattern a base score
+type FamilyRule struct {
+	Match string `json:"match"`
+	Score int    `json:"score"`
+	Note  string `json:"note,omitempty"`
+}
+
+// SizeBonus rewards models larger than a size on disk; the first (largest) threshold that applies wins
+type SizeBonus struct {
+	OverBytes int64 `json:"over_bytes"`
+	Bonus     int   `json:"bonus"`
+}
+
+// RecencyBonus rewards models pulled or updated within the last Days days
+type RecencyBonus struct {
+	Days  int `json:"days"`
+	Bonus int `json:"bonus"`
+}
+
+// CategoryRule boosts models matching a pattern for one task category
+type CategoryRule struct {
+	Match string `json:"match"`
+	Bonus int    `json:"bonus"`
+}
+
+// LearningWeight controls how much recorded execution outcomes move a model's score; the
+// adjustment ranges over ±Weight/2. A weight of 0 turns learning off.
+type LearningWeight struct {
+	Weight int `json:"weight"`
+}
+
+// HardwareRules adapt local model scores to the machine's memory. Models whose estimated
+// footprint exceeds total memory are excluded; those needing more than TightFit of the
+// available memory are penalized, and size bonuses only apply to models that fit comfortably.
+type HardwareRules struct {
+	Overhead     float64 `json:"overhead"`  // Memory beyond the weights, as a fraction of the model size
+	TightFit     float64 `json:"tight_fit"` // Share of available memory above which a model is penalized
+	TightPenalty int     `json:"tight_penalty"`
+	LoadedBonus  int     `json:"loaded_bonus"` // Avoids the cold-load delay of models not in memory
+}
+
+// ModelScore is a model's rank score together with the reasons that produced it. Excluded
+// models would not fit in memory and are only chosen when nothing else is available.
+type ModelScore struct {
+	Name     string
+	Score    int
+	Reasons  []string
+	Excluded bool
+}
+
+// quantizationPattern finds a quantization suffix such as "q4_K_M" or "fp16" in a model tag
+var quantizationPattern = regexp.MustCompile(`(?:^|[-_:.])((?:q\d(?:_[a-z0-9]+)*)|fp16|f16|bf16|fp32|f32)(?:$|[-_.])`)
+
+// DefaultRankingTable returns the ranking table shipped with Please
+func DefaultRankingTable() *RankingTable {
+	var table RankingTable
+	if err := json.Unmarshal(defaultRanking, &table); err != nil {
+		panic(fmt.Sprintf("invalid embedded ranking table: %v", err))
+	}
+	return &table
+}
+
+// LoadRankingTable returns the shipped table merged with the user's ranking.json, if any.
+// If the user file cannot be read, the shipped table is returned along with the error.
+func LoadRankingTable() (*RankingTable, error) {
+	table := DefaultRankingTable()
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return table, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, RankingFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return table, nil
+		}
+		return table, fmt.Errorf("failed to read ranking file: %v", err)
+	}
+
+	var override RankingTable
+	if err := json.Unmarshal(data, &override); err != nil {
+		return table, fmt.Errorf("failed to parse ranking file: %v", err)
+	}
+	if err := override.validate(); err != nil {
+		return table, err
+	}
+
+	table.Merge(&override)
+	return table, nil
+}
+
+// Merge applies override on top of the table: its family rules take precedence (replacing rules
+// with the same pattern), and any size, recency, category or quantization settings it sets replace ours
+func (t *RankingTable) Merge(override *RankingTable) {
+	if override.DefaultScore != 0 {
+		t.DefaultScore = override.DefaultScore
+	}
+
+	overridden := make(map[string]bool, len(override.Families))
+	for _, rule := range override.Families {
+		overridden[rule.Match] = true
+	}
+	families := append([]FamilyRule{}, override.Families...)
+	for _, rule := range t.Families {
+		if !overridden[rule.Match] {
+			families = append(families, rule)
+		}
+	}
+	t.Families = families
+
+	if override.SizeBonuses != nil {
+		t.SizeBonuses = override.SizeBonuses
+	}
+	if override.Recency != nil {
+		t.Recency = override.Recency
+	}
+	if override.Learning != nil {
+		t.Learning = override.Learning
+	}
+	if override.Hardware != nil {
+		t.Hardware = override.Hardware
+	}
+	for category, rules := range override.CategoryBoosts {
+		if t.CategoryBoosts == nil {
+			t.CategoryBoosts = make(map[string][]CategoryRule)
+		}
+		t.CategoryBoosts[category] = rules
+	}
+	for level, bonus := range override.Quantization {
+		if t.Quantization == nil {
+			t.Quantization = make(map[string]int)
+		}
+		t.Quantization[level] = bonus
+	}
+}
+
+// validate checks that every pattern in the table compiles
+func (t *RankingTable) validate() error {
+	patterns := make([]string, 0, len(t.Families))
+	for _, rule := range t.Families {
+		patterns = append(patterns, rule.Match)
+	}
+	for _, rules := range t.CategoryBoosts {
+		for _, rule := range rules {
+			patterns = append(patterns, rule.Match)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if _, _, err := compilePattern(pattern); err != nil {
+			return fmt.Errorf("invalid ranking pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
 // RankModels selects the best model based on task type and model capabilities
 func RankModels(models []types.ModelInfo, taskDescription, taskType string) string {
-	// Model preference ranking based on script generation capability
-	modelPriority := map[string]int{
-		"codegemma":      100, // Specialized for code generation
-		"codellama":      95,  // Code-focused model
-		"deepseek-coder": 90,  // Another code-focused model
-		"llama3.1":       85,  // Latest general model with good coding
-		"llama3.2":       80,  // Good general model
-		"llama3":         75,  // Older but reliable
-		"qwen2.5-coder":  85,  // Code-focused
-		"phi3":           70,  // Smaller but capable
-		"mistral":        65,  // Good general model
-		"gemma2":         60,  // Decent general model
-	}
-
-	// Boost priority for code-related tasks
-	if taskType == "coding" {
-		for name := range modelPriority {
-			if strings.Contains(strings.ToLower(name), "code") {
-				modelPriority[name] += 20
-			}
-		}
+	scores := ScoreModels(models, taskDescription, taskType)
+	if len(scores) == 0 {
+		return ""
 	}
+	return scores[0].Name
+}
 
-	bestModel := ""
-	bestScore := -1
+// ScoreModels scores every model with the loaded ranking table, learned outcomes and the
+// machine's memory, best first
+func ScoreModels(models []types.ModelInfo, taskDescription, taskType string) []ModelScore {
+	table, _ := LoadRankingTable()
+	outcomes, _ := LoadOutcomes()
+	return table.ScoreModels(models, taskType, outcomes, DetectHardware())
+}
 
+// ScoreModels scores every model for the task type, adjusted by how the model's scripts fared in
+// this category before, best first; outcomes and hardware may be nil. Excluded models sort last,
+// and models with equal scores keep their original order, so the first listed model wins a tie.
+func (t *RankingTable) ScoreModels(models []types.ModelInfo, taskType string, outcomes *OutcomeTable, hardware *Hardware) []ModelScore {
+	scores := make([]ModelScore, 0, len(models))
 	for _, model := range models {
-		score := 0
-		modelName := strings.ToLower(model.Name)
-
-		// Check for exact matches in our priority list
-		for priorityModel, priorityScore := range modelPriority {
-			if strings.Contains(modelName, priorityModel) {
-				score = priorityScore
-				break
-			}
+		score := t.ScoreModel(model, taskType, hardware)
+		t.applyOutcomes(&score, taskType, outcomes)
+		scores = append(scores, score)
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Excluded != scores[j].Excluded {
+			return !scores[i].Excluded
 		}
+		return scores[i].Score > scores[j].Score
+	})
+	return scores
+}
+
+// applyOutcomes blends the model's smoothed success rate for the category into its score
+func (t *RankingTable) applyOutcomes(score *ModelScore, taskType string, outcomes *OutcomeTable) {
+	if t.Learning == nil || t.Learning.Weight == 0 {
+		return
+	}
+	counts, ok := outcomes.Counts(score.Name, taskType)
+	if !ok {
+		return
+	}
+	if adjustment := counts.Adjustment(t.Learning.Weight); adjustment != 0 {
+		score.Score += adjustment
+		score.Reasons = append(score.Reasons, fmt.Sprintf("%.0f%% success over %d %s runs (%+d)",
+			counts.SuccessRate()*100, counts.Total(), taskType, adjustment))
+	}
+}
+
+// ScoreModel computes one model's score for a task type on hardware, which may be nil when unknown
+func (t *RankingTable) ScoreModel(model types.ModelInfo, taskType string, hardware *Hardware) ModelScore {
+	result := ModelScore{Name: model.Name}
+	modelName := strings.ToLower(model.Name)
+
+	if rule, ok := t.matchFamily(modelName); ok {
+		result.Score = rule.Score
+		result.Reasons = append(result.Reasons, fmt.Sprintf("ranked as %s (%d)", rule.Match, rule.Score))
+	} else {
+		result.Score = t.DefaultScore
+		result.Reasons = append(result.Reasons, fmt.Sprintf("not in ranking table (%d)", t.DefaultScore))
+	}
+
+	if rule, ok := matchCategory(t.CategoryBoosts[taskType], modelName); ok {
+		result.Score += rule.Bonus
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s model for a %s task (%+d)", rule.Match, taskType, rule.Bonus))
+	}
 
-		// If no specific match, give a base score
-		if score == 0 {
-			score = 50
+	fitsComfortably := t.applyHardware(&result, model, hardware)
+
+	// Prefer larger models (generally more capable) as long as the machine can run them
+	for _, size := range t.SizeBonuses {
+		if !fitsComfortably {
+			break
+		}
+		if model.Size > size.OverBytes {
+			result.Score += size.Bonus
+			result.Reasons = append(result.Reasons, fmt.Sprintf("larger than %s (%+d)", formatGB(size.OverBytes), size.Bonus))
+			break
 		}
+	}
 
-		// Prefer larger models (generally more capable)
-		if model.Size > 7000000000 { // > 7GB
-			score += 10
-		} else if model.Size > 4000000000 { // > 4GB
-			score += 5
+	// Prefer the higher-precision build of the same family
+	if level := QuantizationLevel(model); level != "" {
+		if bonus, ok := t.quantizationBonus(level); ok && bonus != 0 {
+			result.Score += bonus
+			result.Reasons = append(result.Reasons, fmt.Sprintf("%s quantization (%+d)", level, bonus))
 		}
+	}
+
+	// Prefer newer models (more recent modified date)
+	if t.Recency != nil && time.Since(model.ModifiedAt) < time.Duration(t.Recency.Days)*24*time.Hour {
+		result.Score += t.Recency.Bonus
+		result.Reasons = append(result.Reasons, fmt.Sprintf("updated in the last %d days (%+d)", t.Recency.Days, t.Recency.Bonus))
+	}
+
+	return result
+}
 
-		// Prefer newer models (more recent modified date)
-		if time.Since(model.ModifiedAt) < 30*24*time.Hour { // Less than 30 days old
-			score += 5
+// applyHardware rewards loaded models and penalizes or excludes those too large for the
+// machine's memory, reporting whether the model fits comfortably
+func (t *RankingTable) applyHardware(score *ModelScore, model types.ModelInfo, hardware *Hardware) bool {
+	if t.Hardware == nil {
+		return true
+	}
+
+	if model.Loaded {
+		score.Score += t.Hardware.LoadedBonus
+		score.Reasons = append(score.Reasons, fmt.Sprintf("already loaded (%+d)", t.Hardware.LoadedBonus))
+		return true
+	}
+
+	needed := EstimateMemory(model, t.Hardware.Overhead)
+	if hardware == nil || needed == 0 {
+		return true
+	}
+
+	if needed > hardware.TotalMemory {
+		score.Excluded = true
+		score.Reasons = append(score.Reasons, fmt.Sprintf("needs ~%s but the machine has %s (excluded)",
+			formatGB(int64(needed)), formatGB(int64(hardware.TotalMemory))))
+		return false
+	}
+	if float64(needed) > t.Hardware.TightFit*float64(hardware.AvailableMemory) {
+		score.Score -= t.Hardware.TightPenalty
+		score.Reasons = append(score.Reasons, fmt.Sprintf("needs ~%s with %s free (%+d)",
+			formatGB(int64(needed)), formatGB(int64(hardware.AvailableMemory)), -t.Hardware.TightPenalty))
+		return false
+	}
+	return true
+}
+
+// matchFamily returns the most specific family rule matching name; earlier rules win ties
+func (t *RankingTable) matchFamily(name string) (FamilyRule, bool) {
+	best, bestSpecificity, found := FamilyRule{}, -1, false
+	for _, rule := range t.Families {
+		matches, specificity := matchPattern(rule.Match, name)
+		if matches && specificity > bestSpecificity {
+			best, bestSpecificity, found = rule, specificity, true
 		}
+	}
+	return best, found
+}
 
-		if score > bestScore {
-			bestScore = score
-			bestModel = model.Name
+// matchCategory returns the most specific category rule matching name
+func matchCategory(rules []CategoryRule, name string) (CategoryRule, bool) {
+	best, bestSpecificity, found := CategoryRule{}, -1, false
+	for _, rule := range rules {
+		matches, specificity := matchPattern(rule.Match, name)
+		if matches && specificity > bestSpecificity {
+			best, bestSpecificity, found = rule, specificity, true
 		}
 	}
+	return best, found
+}
+
+// quantizationBonus looks up level by its longest matching key, so "q4_k_m" uses "q4"
+func (t *RankingTable) quantizationBonus(level string) (int, bool) {
+	best := ""
+	for key := range t.Quantization {
+		if strings.HasPrefix(level, strings.ToLower(key)) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return 0, false
+	}
+	return t.Quantization[best], true
+}
+
+// QuantizationLevel returns the model's lower-cased quantization, from Ollama's details or its tag
+func QuantizationLevel(model types.ModelInfo) string {
+	if level := model.Details.QuantizationLevel; level != "" {
+		return strings.ToLower(level)
+	}
+	if match := quantizationPattern.FindStringSubmatch(strings.ToLower(model.Name)); match != nil {
+		return match[1]
+	}
+	return ""
+}
 
-	// Fallback if no model found
-	if bestModel == "" && len(models) > 0 {
-		bestModel = models[0].Name
+// matchPattern reports whether a ranking pattern matches name and how specific the pattern is
+func matchPattern(pattern, name string) (bool, int) {
+	re, specificity, err := compilePattern(pattern)
+	if err != nil {
+		return false, 0
 	}
+	switch {
+	case re == nil:
+		return strings.Contains(name, strings.ToLower(pattern)), specificity
+	case strings.HasPrefix(pattern, "re:"):
+		return re.MatchString(name), specificity
+	}
+
+	family, _, _ := strings.Cut(name, ":")
+	return re.MatchString(family), specificity
+}
+
+// compilePattern turns globs and "re:" patterns into regular expressions; plain patterns
+// return a nil expression and are matched as substrings
+func compilePattern(pattern string) (*regexp.Regexp, int, error) {
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile("(?i)" + expr)
+		literal := 0
+		for _, r := range expr {
+			if !strings.ContainsRune(`\.+*?()|[]{}^$`, r) {
+				literal++
+			}
+		}
+		return re, literal, err
+	}
+
+	if !strings.ContainsAny(pattern, "*?") {
+		return nil, len(pattern), nil
+	}
+
+	var expr strings.Builder
+	literal := 0
+	expr.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			literal++
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	return re, literal, err
+}
 
-	return bestModel
+// formatGB renders a byte count for score explanations, such as "7GB" or "5.3GB"
+func formatGB(bytes int64) string {
+	return strings.TrimSuffix(strconv.FormatFloat(float64(bytes)/1e9, 'f', 1, 64), ".0") + "GB"
 }
@@ -3,12 +3,15 @@ package models
 import (
 	"fmt"
 	"os"
-	"strings"
 
 	"please/providers"
 	"please/types"
 )
 
+// secondaryLabelShare is the share of the classifier's evidence a category needs before its
+// model override applies to a task whose main category has none
+const secondaryLabelShare = 0.35
+
 // SelectBestModel automatically chooses the most appropriate model for the given task
 func SelectBestModel(config *types.Config, taskDescription, provider string) (string, error) {
 	// Check if user has manually overridden via environment
@@ -16,20 +19,26 @@ func SelectBestModel(config *types.Config, taskDescription, provider string) (st
 		return m, nil
 	}
 
-	// Check for task-specific overrides in config
-	taskType := CategorizeTask(taskDescription)
-	if override, exists := config.ModelOverrides[taskType]; exists {
-		return override, nil
+	// Check for task-specific overrides in config, for the main category first and then for any
+	// other category with a strong share of the evidence ("download and parse the JSON feed")
+	classification := ClassifyTask(taskDescription)
+	taskType := classification.Category
+	for _, label := range classification.Labels(secondaryLabelShare) {
+		if override, exists := config.ModelOverrides[label]; exists {
+			return override, nil
+		}
 	}
 
 	// Provider-specific model selection
 	switch provider {
 	case "openai":
-		return SelectOpenAIModel(taskType), nil
+		return SelectHostedModel(config, provider, taskDescription, taskType, SelectOpenAIModel(taskType)), nil
 	case "anthropic":
-		return SelectAnthropicModel(taskType), nil
+		return SelectHostedModel(config, provider, taskDescription, taskType, SelectAnthropicModel(taskType)), nil
 	case "ollama":
 		return SelectOllamaModel(config, taskDescription, taskType)
+	case "mock", "replay":
+		return "mock", nil
 	default:
 		// Check custom providers
 		if providerConfig, exists := config.CustomProviders[provider]; exists {
@@ -41,20 +50,39 @@ func SelectBestModel(config *types.Config, taskDescription, provider string) (st
 	}
 }
 
-// SelectOpenAIModel chooses the best OpenAI model for the task
+// SelectHostedModel ranks the models a hosted provider currently offers (via its cached
+// /v1/models list) and falls back to the static choice if the list is unavailable
+func SelectHostedModel(config *types.Config, provider, taskDescription, taskType, fallback string) string {
+	catalog, err := providers.OpenModelCatalog(config)
+	if err != nil {
+		return fallback
+	}
+
+	models, err := catalog.Models(provider)
+	if err != nil || len(models) == 0 {
+		return fallback
+	}
+
+	if best := RankModels(models, taskDescription, taskType); best != "" {
+		return best
+	}
+	return fallback
+}
+
+// SelectOpenAIModel chooses the best OpenAI model for the task when the live model list is unavailable
 func SelectOpenAIModel(taskType string) string {
 	if taskType == "coding" {
-		return "gpt-4" // Best for coding tasks
+		return "gpt-4.1" // Best for coding tasks
 	}
-	return "gpt-3.5-turbo" // Good general purpose model
+	return "gpt-4o-mini" // Good general purpose model
 }
 
-// SelectAnthropicModel chooses the best Anthropic model for the task
+// SelectAnthropicModel chooses the best Anthropic model for the task when the live model list is unavailable
 func SelectAnthropicModel(taskType string) string {
 	if taskType == "coding" {
-		return "claude-3-sonnet-20240229" // Good for coding
+		return "claude-sonnet-4-20250514" // Good for coding
 	}
-	return "claude-3-haiku-20240307" // Fast and efficient
+	return "claude-3-5-haiku-20241022" // Fast and efficient
 }
 
 // SelectOllamaModel chooses the best Ollama model for the task
@@ -85,36 +113,16 @@ func SelectOllamaModel(config *types.Config, taskDescription, taskType string) (
 	return bestModel, nil
 }
 
-// CategorizeTask determines the type of task to help with model selection
-func CategorizeTask(description string) string {
-	desc := strings.ToLower(description)
-
-	// Code-related tasks
-	if strings.Contains(desc, "script") || strings.Contains(desc, "function") ||
-		strings.Contains(desc, "code") || strings.Contains(desc, "program") {
-		return "coding"
-	}
-
-	// Network/web related tasks (check before file management since download can involve files)
-	if strings.Contains(desc, "web") || strings.Contains(desc, "http") ||
-		strings.Contains(desc, "url") || strings.Contains(desc, "download") ||
-		strings.Contains(desc, "network") || strings.Contains(desc, "api") {
-		return "network"
-	}
+// TaskCategories lists the categories CategorizeTask can return, which are also the keys of
+// Config.ModelOverrides
+var TaskCategories = []string{"coding", "network", "sysadmin", "filemanagement", "general"}
 
-	// System administration tasks
-	if strings.Contains(desc, "system") || strings.Contains(desc, "server") ||
-		strings.Contains(desc, "service") || strings.Contains(desc, "process") ||
-		strings.Contains(desc, "registry") || strings.Contains(desc, "install") {
-		return "sysadmin"
-	}
-
-	// File management tasks
-	if strings.Contains(desc, "file") || strings.Contains(desc, "folder") ||
-		strings.Contains(desc, "directory") || strings.Contains(desc, "copy") ||
-		strings.Contains(desc, "move") || strings.Contains(desc, "delete") {
-		return "filemanagement"
+// IsTaskCategory reports whether category is one of TaskCategories
+func IsTaskCategory(category string) bool {
+	for _, known := range TaskCategories {
+		if category == known {
+			return true
+		}
 	}
-
-	return "general"
+	return false
 }
@@ -1,12 +1,24 @@
 package models
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"please/config"
+	"please/providers"
 	"please/types"
 )
 
+// TestMain keeps ranking results independent of the memory of the machine running the tests;
+// hardware-aware scoring is tested with explicit Hardware values
+func TestMain(m *testing.M) {
+	meminfoPath = filepath.Join(os.TempDir(), "please-no-meminfo")
+	os.Exit(m.Run())
+}
+
 func TestSelectBestModel(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -133,14 +145,14 @@ func TestSelectOpenAIModel(t *testing.T) {
 		expected string
 	}{
 		{
-			name:     "Coding task uses GPT-4",
+			name:     "Coding task uses GPT-4.1",
 			taskType: "coding",
-			expected: "gpt-4",
+			expected: "gpt-4.1",
 		},
 		{
-			name:     "General task uses GPT-3.5-turbo",
+			name:     "General task uses GPT-4o-mini",
 			taskType: "general",
-			expected: "gpt-3.5-turbo",
+			expected: "gpt-4o-mini",
 		},
 	}
 
@@ -164,12 +176,12 @@ func TestSelectAnthropicModel(t *testing.T) {
 		{
 			name:     "Coding task uses Claude Sonnet",
 			taskType: "coding",
-			expected: "claude-3-sonnet-20240229",
+			expected: "claude-sonnet-4-20250514",
 		},
 		{
 			name:     "General task uses Claude Haiku",
 			taskType: "general",
-			expected: "claude-3-haiku-20240307",
+			expected: "claude-3-5-haiku-20241022",
 		},
 	}
 
@@ -364,3 +376,316 @@ func Test_when_categorizing_task_then_prioritize_network_over_file_for_download(
 		t.Errorf("Download tasks should be categorized as network, got: %s", result)
 	}
 }
+
+func Test_when_ranking_hosted_models_then_use_most_specific_priority(t *testing.T) {
+	models := []types.ModelInfo{
+		{Name: "gpt-4"},
+		{Name: "gpt-4o-mini-2024-07-18"},
+	}
+
+	result := RankModels(models, "list files", "general")
+
+	// gpt-4o-mini must be scored as itself, not as the older gpt-4
+	if result != "gpt-4o-mini-2024-07-18" {
+		t.Errorf("Expected gpt-4o-mini to outrank gpt-4, got: %s", result)
+	}
+}
+
+func Test_when_hosted_model_list_is_cached_then_select_from_discovered_models(t *testing.T) {
+	// Arrange - isolate the config directory and seed the discovered model cache
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	catalog, err := providers.OpenModelCatalog(&types.Config{})
+	if err != nil {
+		t.Fatalf("Expected catalog to open, got: %v", err)
+	}
+	dir := filepath.Dir(catalog.CachePath("openai"))
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(catalog.CachePath("openai"), []byte(`{"fetched_at":"`+time.Now().Format(time.RFC3339)+`","models":[{"name":"gpt-3.5-turbo"},{"name":"gpt-4o"},{"name":"gpt-4o-mini"}]}`), 0644)
+
+	cfg := &types.Config{
+		ModelOverrides:  make(map[string]string),
+		CustomProviders: make(map[string]types.ProviderConfig),
+	}
+
+	// Act
+	result, err := SelectBestModel(cfg, "write a script to rotate logs", "openai")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("SelectBestModel should not error: %v", err)
+	}
+	if result != "gpt-4o" {
+		t.Errorf("Expected best discovered model gpt-4o, got %s", result)
+	}
+}
+
+func Test_when_scoring_models_then_order_best_first_with_reasons(t *testing.T) {
+	// Arrange
+	available := []types.ModelInfo{
+		{Name: "mistral:7b", Size: 4100000000},
+		{Name: "codellama:13b", Size: 7400000000},
+		{Name: "unknown-model"},
+	}
+
+	// Act
+	scores := ScoreModels(available, "write a script", "coding")
+
+	// Assert
+	if len(scores) != 3 || scores[0].Name != "codellama:13b" || scores[2].Name != "unknown-model" {
+		t.Fatalf("Unexpected order: %+v", scores)
+	}
+	if scores[0].Score != 95+20+10 {
+		t.Errorf("Expected codellama score 125, got %d", scores[0].Score)
+	}
+	reasons := strings.Join(scores[0].Reasons, ", ")
+	if !strings.Contains(reasons, "codellama") || !strings.Contains(reasons, "coding task") || !strings.Contains(reasons, "7GB") {
+		t.Errorf("Expected reasons to explain the score, got %q", reasons)
+	}
+}
+
+func Test_when_ranking_table_uses_globs_and_regexes_then_score_new_families(t *testing.T) {
+	// Arrange
+	table := DefaultRankingTable()
+
+	tests := []struct {
+		name     string
+		expected int
+	}{
+		{"qwen2.5-coder:7b", 85},      // glob qwen*-coder
+		{"qwen3-coder:30b", 92},       // explicit entry is more specific than the glob
+		{"gemma3:4b", 70},             // explicit entry beats gemma*
+		{"gemma2:9b", 60},             // glob gemma*
+		{"deepseek-coder-v2:16b", 90}, // regex entry
+		{"some-new-model:1b", 50},     // default score
+	}
+
+	for _, tt := range tests {
+		// Act
+		score := table.ScoreModel(types.ModelInfo{Name: tt.name}, "general", nil)
+
+		// Assert
+		if score.Score != tt.expected {
+			t.Errorf("Expected %s to score %d, got %d (%v)", tt.name, tt.expected, score.Score, score.Reasons)
+		}
+	}
+}
+
+func Test_when_same_family_has_several_quantizations_then_prefer_higher_precision(t *testing.T) {
+	// Arrange
+	available := []types.ModelInfo{
+		{Name: "llama3.1:8b-instruct-q4_K_M"},
+		{Name: "llama3.1:8b-instruct-q8_0"},
+	}
+	available[0].Details.QuantizationLevel = "Q4_K_M"
+
+	// Act
+	scores := DefaultRankingTable().ScoreModels(available, "general", nil, nil)
+
+	// Assert
+	if scores[0].Name != "llama3.1:8b-instruct-q8_0" {
+		t.Errorf("Expected q8 build to outrank q4, got %+v", scores)
+	}
+	if QuantizationLevel(available[1]) != "q8_0" {
+		t.Errorf("Expected quantization parsed from tag, got %q", QuantizationLevel(available[1]))
+	}
+}
+
+func Test_when_user_ranking_file_exists_then_merge_over_defaults(t *testing.T) {
+	// Arrange
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	configDir, _ := config.GetConfigDir()
+	os.MkdirAll(configDir, 0755)
+	os.WriteFile(filepath.Join(configDir, RankingFileName), []byte(`{
+		"families": [{"match": "re:^mistral-(small|large)", "score": 99}],
+		"category_boosts": {"coding": []}
+	}`), 0644)
+
+	// Act
+	table, err := LoadRankingTable()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected ranking file to load, got: %v", err)
+	}
+	if score := table.ScoreModel(types.ModelInfo{Name: "mistral-small:24b"}, "general", nil).Score; score != 99 {
+		t.Errorf("Expected user rule to score 99, got %d", score)
+	}
+	if score := table.ScoreModel(types.ModelInfo{Name: "codellama"}, "coding", nil).Score; score != 95 {
+		t.Errorf("Expected default family kept and coding boost removed, got %d", score)
+	}
+}
+
+func Test_when_user_ranking_file_is_invalid_then_fall_back_to_defaults(t *testing.T) {
+	// Arrange
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	configDir, _ := config.GetConfigDir()
+	os.MkdirAll(configDir, 0755)
+	os.WriteFile(filepath.Join(configDir, RankingFileName), []byte(`{"families": [{"match": "re:(", "score": 1}]}`), 0644)
+
+	// Act
+	table, err := LoadRankingTable()
+
+	// Assert
+	if err == nil {
+		t.Error("Expected invalid pattern to be reported")
+	}
+	if len(table.Families) != len(DefaultRankingTable().Families) {
+		t.Error("Expected the shipped table to be used")
+	}
+}
+
+func Test_when_model_outcomes_are_recorded_then_adjust_ranking_for_that_category(t *testing.T) {
+	// Arrange
+	outcomes := NewOutcomeTable(filepath.Join(t.TempDir(), OutcomesFileName))
+	for i := 0; i < 5; i++ {
+		outcomes.Record("llama3.1", "ollama", "coding", OutcomeFailed)
+		outcomes.Record("llama3.2", "ollama", "coding", OutcomeSuccess)
+	}
+	available := []types.ModelInfo{{Name: "llama3.1"}, {Name: "llama3.2"}}
+	table := DefaultRankingTable()
+
+	// Act
+	coding := table.ScoreModels(available, "coding", outcomes, nil)
+	general := table.ScoreModels(available, "general", outcomes, nil)
+
+	// Assert
+	if coding[0].Name != "llama3.2" {
+		t.Errorf("Expected the model whose scripts succeed to win coding tasks, got %+v", coding)
+	}
+	if general[0].Name != "llama3.1" {
+		t.Errorf("Expected other categories to keep the static ranking, got %+v", general)
+	}
+}
+
+func Test_when_outcome_table_is_saved_then_reload_counts(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), OutcomesFileName)
+	outcomes := NewOutcomeTable(path)
+	outcomes.Record("gpt-4o", "openai", "network", OutcomeSuccess)
+	outcomes.Record("gpt-4o", "openai", "network", OutcomeEdited)
+
+	// Act
+	err := outcomes.Save()
+	reloaded, loadErr := LoadOutcomeTable(path)
+
+	// Assert
+	if err != nil || loadErr != nil {
+		t.Fatalf("Expected save and load to succeed, got %v / %v", err, loadErr)
+	}
+	counts, ok := reloaded.Counts("gpt-4o", "network")
+	if !ok || counts.Success != 1 || counts.Edited != 1 || counts.Total() != 2 {
+		t.Errorf("Unexpected reloaded counts: %+v", counts)
+	}
+	if rate := (OutcomeCounts{}).SuccessRate(); rate != 0.5 {
+		t.Errorf("Expected neutral rate without data, got %v", rate)
+	}
+}
+
+func Test_when_model_does_not_fit_in_memory_then_exclude_it_and_prefer_loaded_models(t *testing.T) {
+	// Arrange - an 8GB CI runner with 5GB free
+	hardware := &Hardware{TotalMemory: 8e9, AvailableMemory: 5e9}
+	available := []types.ModelInfo{
+		{Name: "codellama:13b", Size: 7400000000},
+		{Name: "llama3.1:8b", Size: 4900000000},
+		{Name: "llama3.2:3b", Size: 2000000000, Loaded: true},
+	}
+
+	// Act
+	scores := DefaultRankingTable().ScoreModels(available, "coding", nil, hardware)
+
+	// Assert
+	if scores[0].Name != "llama3.2:3b" {
+		t.Errorf("Expected the loaded model that fits to win, got %+v", scores)
+	}
+	if !scores[2].Excluded || scores[2].Name != "codellama:13b" {
+		t.Errorf("Expected the 13b model to be excluded and sorted last, got %+v", scores)
+	}
+	if scores[1].Name != "llama3.1:8b" || !strings.Contains(strings.Join(scores[1].Reasons, ", "), "free") {
+		t.Errorf("Expected the tight fit to be penalized, got %+v", scores[1])
+	}
+}
+
+func Test_when_reading_meminfo_then_use_available_memory(t *testing.T) {
+	// Arrange
+	meminfo := "MemTotal:        8052796 kB\nMemFree:          512000 kB\nMemAvailable:    4026398 kB\n"
+
+	// Act
+	hardware := parseMeminfo(strings.NewReader(meminfo))
+
+	// Assert
+	if hardware == nil || hardware.TotalMemory != 8052796*1024 || hardware.AvailableMemory != 4026398*1024 {
+		t.Errorf("Unexpected hardware: %+v", hardware)
+	}
+	if parseMeminfo(strings.NewReader("garbage")) != nil {
+		t.Error("Expected nil without MemTotal")
+	}
+}
+
+func Test_when_classifying_tasks_then_weigh_evidence_instead_of_first_keyword(t *testing.T) {
+	tests := []struct {
+		description string
+		expected    string
+	}{
+		{"write a script to download files", "network"},
+		{"open the scriptorium catalogue", "general"},
+		{"list processes using the most memory", "sysadmin"},
+		{"rename all photos by date", "filemanagement"},
+		{"copy the logs without deleting anything", "filemanagement"},
+		{"don't install anything, just show the network interfaces", "network"},
+		{"descargar archivos de un sitio web", "network"},
+		{"renommer tous les fichiers du dossier", "filemanagement"},
+		{"redémarrer le service nginx", "sysadmin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			// Act
+			result := ClassifyTask(tt.description)
+
+			// Assert
+			if result.Category != tt.expected {
+				t.Errorf("ClassifyTask(%q) = %s %v, want %s", tt.description, result.Category, result.Scores, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_when_task_spans_categories_then_report_multiple_labels_with_confidence(t *testing.T) {
+	// Act
+	result := DefaultLexicons().Classify("download the JSON from the API and parse it with a python function")
+
+	// Assert
+	labels := result.Labels(0.3)
+	if len(labels) != 2 || labels[0] != "coding" || labels[1] != "network" {
+		t.Errorf("Expected coding and network labels, got %v (%v)", labels, result.Scores)
+	}
+	if result.Confidence <= 0.5 || result.Confidence >= 1 {
+		t.Errorf("Expected a split but leaning confidence, got %v", result.Confidence)
+	}
+}
+
+func Test_when_user_lexicon_adds_keywords_then_classify_with_them(t *testing.T) {
+	// Arrange
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	configDir, _ := config.GetConfigDir()
+	os.MkdirAll(configDir, 0755)
+	os.WriteFile(filepath.Join(configDir, LexiconFileName), []byte(`{
+		"languages": {"en-us": {"categories": {"sysadmin": {"keywords": {"kubectl": 3}}}}}
+	}`), 0644)
+
+	// Act
+	result := ClassifyTask("kubectl rollout the new deployment")
+
+	// Assert
+	if result.Category != "sysadmin" {
+		t.Errorf("Expected user keyword to classify as sysadmin, got %s (%v)", result.Category, result.Scores)
+	}
+}
@@ -0,0 +1,215 @@
+package policy
+
+import (
+	"path"
+	"strings"
+)
+
+// Command is a command in a script, as policy rules with a command, args or paths match it
+type Command struct {
+	Name      string   // Lowercase program or cmdlet name with aliases resolved, e.g. "rm" or "remove-item"
+	Args      []string // Arguments as written, without quotes
+	Redirects []string // Files the command's output is redirected to
+	Line      int
+	Column    int
+}
+
+// Script is what rules are evaluated against
+type Script struct {
+	Type     string // "bash" or "powershell"
+	Source   string
+	Commands []Command // Empty when the script couldn't be parsed; pattern rules still apply
+}
+
+// pending is a finding with the state needed to apply the remaining layers to it
+type pending struct {
+	Finding
+	nextLayer      int  // First layer that may still change the finding
+	explicitAction bool // A rule set the action, so a new severity keeps it
+}
+
+// Apply evaluates the policy against script: it adds findings for rules with a match, then lets
+// each layer change, in order of precedence, the findings of the built-in rules and of the rules
+// from layers below it. Disabled findings are dropped.
+func (p *Policy) Apply(script Script, builtin []Finding) []Finding {
+	var findings []*pending
+	for _, finding := range builtin {
+		if finding.Action == "" {
+			finding.Action = DefaultAction(finding.Severity)
+		}
+		findings = append(findings, &pending{Finding: finding})
+	}
+
+	defined := make(map[string]bool)
+	for i, layer := range p.Layers {
+		for _, rule := range layer.Rules {
+			if rule.Match == nil || defined[rule.ID] {
+				continue
+			}
+			defined[rule.ID] = true
+			findings = append(findings, ruleFindings(rule, layer, i, script)...)
+		}
+	}
+
+	var applied []Finding
+	for _, finding := range findings {
+		if p.override(finding) {
+			applied = append(applied, finding.Finding)
+		}
+	}
+	return applied
+}
+
+// ruleFindings returns a finding for each place the rule from layer matches script
+func ruleFindings(rule Rule, layer Layer, index int, script Script) []*pending {
+	if rule.Disabled {
+		return nil
+	}
+	severity := rule.Severity
+	if severity == "" {
+		severity = SeverityCaution
+	}
+	message := rule.Message
+	if message == "" {
+		message = "Matches policy rule " + rule.ID
+	}
+	action := rule.Action
+	if action == "" {
+		action = DefaultAction(severity)
+	}
+	nextLayer := index + 1
+	if rule.Locked {
+		nextLayer = -1
+	}
+
+	var findings []*pending
+	for _, position := range rule.Match.find(script) {
+		findings = append(findings, &pending{
+			Finding: Finding{
+				Rule: rule.ID, Severity: severity, Message: message, Action: action,
+				Line: position[0], Column: position[1], Remediation: rule.Remediation, Source: layer.Path,
+			},
+			nextLayer:      nextLayer,
+			explicitAction: rule.Action != "",
+		})
+	}
+	return findings
+}
+
+// override applies the layers' rules with the finding's ID, reporting false if one disables it
+func (p *Policy) override(finding *pending) bool {
+	if finding.nextLayer < 0 {
+		return true
+	}
+	for i := finding.nextLayer; i < len(p.Layers); i++ {
+		layer := p.Layers[i]
+		rule, ok := layer.rule(finding.Rule)
+		if !ok || rule.Match != nil {
+			continue
+		}
+		changed := false
+
+		if rule.Disabled && !layer.StrictOnly {
+			return false
+		}
+		if rule.Severity != "" && (!layer.StrictOnly || rule.Severity.Rank() > finding.Severity.Rank()) {
+			finding.Severity = rule.Severity
+			if !finding.explicitAction && rule.Action == "" {
+				finding.Action = DefaultAction(rule.Severity)
+			}
+			changed = true
+		}
+		if rule.Action != "" && (!layer.StrictOnly || rule.Action.Rank() > finding.Action.Rank()) {
+			finding.Action = rule.Action
+			finding.explicitAction = true
+			changed = true
+		}
+		if rule.Message != "" && !layer.StrictOnly {
+			// The built-in message's translations no longer fit the new wording
+			finding.Message = rule.Message
+			finding.MessageKey = ""
+			changed = true
+		}
+		if rule.Remediation != "" && !layer.StrictOnly {
+			finding.Remediation = rule.Remediation
+			changed = true
+		}
+		if changed {
+			finding.Source = layer.Path
+		}
+		if rule.Locked {
+			break
+		}
+	}
+	return true
+}
+
+// find returns the line and column of each place the match fires
+func (m *Match) find(script Script) [][2]int {
+	if m.ScriptType != "" && !strings.EqualFold(m.ScriptType, script.Type) {
+		return nil
+	}
+	lines := strings.Split(script.Source, "\n")
+
+	// Without a command, args or paths the pattern is matched against every line
+	if m.Command == "" && m.args == nil && len(m.Paths) == 0 {
+		var positions [][2]int
+		for i, line := range lines {
+			if location := m.pattern.FindStringIndex(line); location != nil {
+				positions = append(positions, [2]int{i + 1, len([]rune(line[:location[0]])) + 1})
+			}
+		}
+		return positions
+	}
+
+	var positions [][2]int
+	for _, command := range script.Commands {
+		if m.matchesCommand(command, script.Type) &&
+			(m.pattern == nil || (command.Line >= 1 && command.Line <= len(lines) && m.pattern.MatchString(lines[command.Line-1]))) {
+			positions = append(positions, [2]int{command.Line, command.Column})
+		}
+	}
+	return positions
+}
+
+// matchesCommand reports whether the command, args and paths of the match fit command
+func (m *Match) matchesCommand(command Command, scriptType string) bool {
+	if m.Command != "" && !strings.EqualFold(m.Command, command.Name) {
+		return false
+	}
+	if m.args != nil && !m.args.MatchString(strings.Join(command.Args, " ")) {
+		return false
+	}
+	if len(m.Paths) == 0 {
+		return true
+	}
+	for _, candidate := range append(append([]string{}, command.Args...), command.Redirects...) {
+		// Values of options such as of=/dev/sda or --file=/etc/hosts count as paths too
+		values := []string{candidate}
+		if _, value, found := strings.Cut(candidate, "="); found {
+			values = append(values, value)
+		}
+		for _, value := range values {
+			for _, glob := range m.Paths {
+				if matchPath(glob, value, scriptType == "powershell") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchPath reports whether name matches glob. "dir/**" matches dir and everything under it.
+// Windows paths are compared without regard to case or the direction of slashes.
+func matchPath(glob, name string, windows bool) bool {
+	if windows {
+		glob = strings.ReplaceAll(strings.ToLower(glob), `\`, "/")
+		name = strings.ReplaceAll(strings.ToLower(name), `\`, "/")
+	}
+	if dir, ok := strings.CutSuffix(glob, "/**"); ok {
+		return name == dir || strings.HasPrefix(name, dir+"/")
+	}
+	matched, err := path.Match(glob, name)
+	return err == nil && matched
+}
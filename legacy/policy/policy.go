@@ -0,0 +1,287 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"please/config"
+)
+
+// Severity is how dangerous a finding is
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityCaution  Severity = "caution"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// severityRanks orders the severities, least dangerous first
+var severityRanks = map[Severity]int{SeverityInfo: 0, SeverityCaution: 1, SeverityHigh: 2, SeverityCritical: 3}
+
+// Rank orders severities, so a higher rank is more dangerous
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// Label is the prefix shown before findings of this severity
+func (s Severity) Label() string {
+	switch s {
+	case SeverityCritical:
+		return "⛔ CRITICAL"
+	case SeverityHigh:
+		return "🔴 WARNING"
+	case SeverityCaution:
+		return "🟡 CAUTION"
+	default:
+		return "🟢 INFO"
+	}
+}
+
+// Action is what Please asks of the user before running a script with a finding
+type Action string
+
+const (
+	ActionWarn         Action = "warn"                  // Show the finding and run
+	ActionConfirm      Action = "require-confirm"       // Ask for y before running
+	ActionTypedExecute Action = "require-typed-execute" // Ask the user to type EXECUTE
+	ActionBlock        Action = "block"                 // Never run the script
+)
+
+// actionRanks orders the actions, least strict first
+var actionRanks = map[Action]int{ActionWarn: 0, ActionConfirm: 1, ActionTypedExecute: 2, ActionBlock: 3}
+
+// Rank orders actions, so a higher rank is stricter
+func (a Action) Rank() int {
+	return actionRanks[a]
+}
+
+// DefaultAction is the action for a severity when no policy sets one
+func DefaultAction(severity Severity) Action {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return ActionTypedExecute
+	case SeverityCaution:
+		return ActionConfirm
+	default:
+		return ActionWarn
+	}
+}
+
+// Finding is a risk found in a script, with the policy applied. Tools that read findings as
+// JSON should go by Rule, Severity and Action; Message is English text for people.
+type Finding struct {
+	Rule        string   `json:"rule"` // ID of the built-in or policy rule, e.g. "recursive-delete"
+	Severity    Severity `json:"severity"`
+	MessageKey  string   `json:"message_key,omitempty"` // Localization key of a built-in message, e.g. "risks.recursive_delete"; empty once a policy file rewords it
+	Message     string   `json:"message"`
+	Action      Action   `json:"action"`
+	Line        int      `json:"line,omitempty"`        // 1-based; 0 when the finding isn't tied to a position
+	Column      int      `json:"column,omitempty"`      // 1-based, in characters
+	Snippet     string   `json:"snippet,omitempty"`     // The script line at Line
+	Remediation string   `json:"remediation,omitempty"` // How to get the same result more safely
+	Source      string   `json:"source,omitempty"`      // The policy file that last changed the finding, empty when built-in rules alone decided it
+}
+
+// String formats the finding as a validation warning
+func (f Finding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s", f.Severity.Label(), f.Message)
+	}
+	return fmt.Sprintf("%s: %s (line %d, column %d)", f.Severity.Label(), f.Message, f.Line, f.Column)
+}
+
+// Strictest returns the strictest action among findings, ActionWarn when there are none
+func Strictest(findings []Finding) Action {
+	strictest := ActionWarn
+	for _, finding := range findings {
+		if finding.Action.Rank() > strictest.Rank() {
+			strictest = finding.Action
+		}
+	}
+	return strictest
+}
+
+// Match selects what a policy rule fires on; every field that is set must match
+type Match struct {
+	Command    string   `json:"command,omitempty"`     // Program or cmdlet name, e.g. "systemctl" or "Remove-Item"
+	Args       string   `json:"args,omitempty"`        // Regular expression for the command's arguments, joined by spaces
+	Paths      []string `json:"paths,omitempty"`       // Globs for the files a command names or redirects to; "dir/**" matches everything under dir
+	Pattern    string   `json:"pattern,omitempty"`     // Regular expression for a line of the script
+	ScriptType string   `json:"script_type,omitempty"` // "bash" or "powershell"; empty for both
+
+	args    *regexp.Regexp
+	pattern *regexp.Regexp
+}
+
+// Rule is a rule in a policy file. A rule with a match adds a check; a rule without one changes
+// the built-in rule, or the rule from a lower-precedence file, that has the same ID.
+type Rule struct {
+	ID          string   `json:"id"`
+	Match       *Match   `json:"match,omitempty"`
+	Severity    Severity `json:"severity,omitempty"`
+	Message     string   `json:"message,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+	Action      Action   `json:"action,omitempty"`
+	Disabled    bool     `json:"disabled,omitempty"`
+	Locked      bool     `json:"locked,omitempty"` // Files of higher precedence cannot change the rule
+}
+
+// File is the contents of a policy file
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Layer is a policy file from one of the places Please looks for them
+type Layer struct {
+	Name       string // "system", "user" or "project"
+	Path       string
+	Rules      []Rule
+	StrictOnly bool // The layer may add rules and make rules stricter, but not relax or disable them
+}
+
+// rule returns the layer's rule with id
+func (l *Layer) rule(id string) (Rule, bool) {
+	for _, rule := range l.Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Policy is the policy files that were found, lowest precedence first
+type Policy struct {
+	Layers []Layer
+}
+
+// FileName is the name of a policy file in each place Please looks
+const FileName = "policy.json"
+
+// SystemPath returns where administrators put the policy for everyone on the machine
+func SystemPath() string {
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "please", FileName)
+	}
+	return filepath.Join("/etc", "please", FileName)
+}
+
+// UserPath returns the policy file in the user's config directory
+func UserPath() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, FileName), nil
+}
+
+// ProjectPath returns .please/policy.json in dir or the nearest parent that has one, or "" if none does
+func ProjectPath(dir string) string {
+	for {
+		path := filepath.Join(dir, ".please", FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads the system, user and project policy files, in increasing order of precedence.
+// Project files come with the code being worked on, so they may only make rules stricter. Files
+// that don't exist are skipped; the error lists the files that couldn't be read, and the policy
+// holds the rest.
+func Load() (*Policy, error) {
+	layers := []Layer{{Name: "system", Path: SystemPath()}}
+	if userPath, err := UserPath(); err == nil {
+		layers = append(layers, Layer{Name: "user", Path: userPath})
+	}
+	if workingDir, err := os.Getwd(); err == nil {
+		if projectPath := ProjectPath(workingDir); projectPath != "" {
+			layers = append(layers, Layer{Name: "project", Path: projectPath, StrictOnly: true})
+		}
+	}
+	return LoadLayers(layers...)
+}
+
+// LoadLayers reads the rules of each layer from its path, lowest precedence first
+func LoadLayers(layers ...Layer) (*Policy, error) {
+	policy := &Policy{}
+	var errs []error
+	for _, layer := range layers {
+		data, err := os.ReadFile(layer.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err == nil {
+			layer.Rules, err = Parse(data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s policy %s: %v", layer.Name, layer.Path, err))
+			continue
+		}
+		policy.Layers = append(policy.Layers, layer)
+	}
+	return policy, errors.Join(errs...)
+}
+
+// Parse reads and checks the rules of a policy file
+func Parse(data []byte) ([]Rule, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+		rule.Severity = Severity(strings.ToLower(string(rule.Severity)))
+		rule.Action = Action(strings.ToLower(string(rule.Action)))
+		switch {
+		case rule.ID == "":
+			return nil, fmt.Errorf("rule %d has no id", i+1)
+		case rule.Severity != "" && rule.Severity != SeverityInfo && rule.Severity.Rank() == 0:
+			return nil, fmt.Errorf("rule %s: unknown severity %q (use info, caution, high or critical)", rule.ID, rule.Severity)
+		case rule.Action != "" && rule.Action != ActionWarn && rule.Action.Rank() == 0:
+			return nil, fmt.Errorf("rule %s: unknown action %q (use warn, require-confirm, require-typed-execute or block)", rule.ID, rule.Action)
+		}
+		if rule.Match != nil {
+			if err := rule.Match.compile(); err != nil {
+				return nil, fmt.Errorf("rule %s: %v", rule.ID, err)
+			}
+		}
+	}
+	return file.Rules, nil
+}
+
+// compile checks the match and compiles its regular expressions
+func (m *Match) compile() error {
+	if m.Command == "" && m.Args == "" && len(m.Paths) == 0 && m.Pattern == "" {
+		return fmt.Errorf("match needs a command, args, paths or pattern")
+	}
+	var err error
+	if m.Args != "" {
+		if m.args, err = regexp.Compile(m.Args); err != nil {
+			return fmt.Errorf("invalid args expression: %v", err)
+		}
+	}
+	if m.Pattern != "" {
+		if m.pattern, err = regexp.Compile(m.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern expression: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePolicy writes a policy file in a temporary directory and returns its path
+func writePolicy(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+	return path
+}
+
+// builtinFindings are findings as the built-in rules report them for a small script
+func builtinFindings() []Finding {
+	return []Finding{
+		{Rule: "delete-root", Severity: SeverityCritical, Message: "Attempts to delete entire filesystem", Line: 1, Column: 1},
+		{Rule: "service-stop", Severity: SeverityCaution, Message: "Stops system services", Line: 2, Column: 1},
+	}
+}
+
+func Test_when_layers_change_rules_then_later_layers_take_precedence(t *testing.T) {
+	// Arrange
+	system := writePolicy(t, "system.json", `{"rules": [
+		{"id": "sudoers", "match": {"paths": ["/etc/sudoers", "/etc/sudoers.d/**"]}, "severity": "critical", "message": "Never touch sudoers", "action": "block"}
+	]}`)
+	user := writePolicy(t, "user.json", `{"rules": [{"id": "service-stop", "severity": "info"}]}`)
+	loaded, err := LoadLayers(Layer{Name: "system", Path: system}, Layer{Name: "user", Path: user})
+	script := Script{Type: "bash", Commands: []Command{
+		{Name: "tee", Args: []string{"-a", "/etc/sudoers.d/deploy"}, Line: 3, Column: 8},
+	}}
+
+	// Act
+	findings := loaded.Apply(script, builtinFindings())
+
+	// Assert
+	if err != nil || len(findings) != 3 {
+		t.Fatalf("Expected 3 findings and no error, got %+v (%v)", findings, err)
+	}
+	if findings[1].Severity != SeverityInfo || findings[1].Action != ActionWarn || findings[1].Source != user {
+		t.Errorf("Expected the user policy to downgrade service-stop to a warning, got %+v", findings[1])
+	}
+	sudoers := findings[2]
+	if sudoers.Rule != "sudoers" || sudoers.Action != ActionBlock || sudoers.Line != 3 || sudoers.Column != 8 {
+		t.Errorf("Expected the system rule to block the tee command, got %+v", sudoers)
+	}
+}
+
+func Test_when_project_policy_relaxes_rules_then_only_stricter_changes_apply(t *testing.T) {
+	// Arrange
+	project := writePolicy(t, "project.json", `{"rules": [
+		{"id": "delete-root", "disabled": true},
+		{"id": "service-stop", "severity": "info", "action": "block", "message": "Ask ops first"}
+	]}`)
+	loaded, _ := LoadLayers(Layer{Name: "project", Path: project, StrictOnly: true})
+
+	// Act
+	findings := loaded.Apply(Script{Type: "bash"}, builtinFindings())
+
+	// Assert
+	if len(findings) != 2 || findings[0].Rule != "delete-root" {
+		t.Fatalf("Expected the project policy not to disable delete-root, got %+v", findings)
+	}
+	stop := findings[1]
+	if stop.Severity != SeverityCaution || stop.Action != ActionBlock || stop.Message != "Stops system services" {
+		t.Errorf("Expected only the stricter action to apply, got %+v", stop)
+	}
+}
+
+func Test_when_rule_is_locked_then_later_layers_cannot_change_it(t *testing.T) {
+	// Arrange
+	system := writePolicy(t, "system.json", `{"rules": [{"id": "service-stop", "action": "require-typed-execute", "locked": true}]}`)
+	user := writePolicy(t, "user.json", `{"rules": [{"id": "service-stop", "disabled": true}]}`)
+	loaded, _ := LoadLayers(Layer{Name: "system", Path: system}, Layer{Name: "user", Path: user})
+
+	// Act
+	findings := loaded.Apply(Script{Type: "bash"}, builtinFindings())
+
+	// Assert
+	if len(findings) != 2 || findings[1].Action != ActionTypedExecute || findings[1].Source != system {
+		t.Errorf("Expected the locked system rule to win, got %+v", findings)
+	}
+}
+
+func Test_when_pattern_rule_matches_lines_then_report_each_position(t *testing.T) {
+	// Arrange
+	rules, err := Parse([]byte(`{"rules": [{"id": "no-prod", "match": {"pattern": "prod-db\\d", "script_type": "bash"}, "severity": "HIGH"}]}`))
+	loaded := &Policy{Layers: []Layer{{Name: "user", Path: "user.json", Rules: rules}}}
+	script := Script{Type: "bash", Source: "echo start\npsql -h prod-db1\necho prod-db2"}
+
+	// Act
+	findings := loaded.Apply(script, nil)
+	powershell := loaded.Apply(Script{Type: "powershell", Source: script.Source}, nil)
+
+	// Assert
+	if err != nil || len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v (%v)", findings, err)
+	}
+	if findings[0].String() != "🔴 WARNING: Matches policy rule no-prod (line 2, column 9)" || findings[1].Line != 3 {
+		t.Errorf("Expected positions of each match, got %q and line %d", findings[0], findings[1].Line)
+	}
+	if findings[0].Action != ActionTypedExecute || len(powershell) != 0 {
+		t.Errorf("Expected the default action for high and no PowerShell findings, got %s and %+v", findings[0].Action, powershell)
+	}
+}
+
+func Test_when_policy_file_is_invalid_then_return_error_and_keep_valid_layers(t *testing.T) {
+	// Arrange
+	valid := writePolicy(t, "valid.json", `{"rules": [{"id": "service-stop", "severity": "info"}]}`)
+	invalid := writePolicy(t, "invalid.json", `{"rules": [{"id": "x", "severity": "severe", "match": {"command": "rm"}}]}`)
+	missing := filepath.Join(t.TempDir(), "missing.json")
+
+	// Act
+	loaded, err := LoadLayers(Layer{Name: "system", Path: missing}, Layer{Name: "user", Path: valid}, Layer{Name: "project", Path: invalid})
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "unknown severity") || !strings.Contains(err.Error(), "project policy") {
+		t.Errorf("Expected an error naming the invalid file, got %v", err)
+	}
+	if len(loaded.Layers) != 1 || loaded.Layers[0].Name != "user" {
+		t.Errorf("Expected only the valid layer, got %+v", loaded.Layers)
+	}
+}
+
+func Test_when_matching_windows_paths_then_ignore_case_and_slashes(t *testing.T) {
+	// Arrange
+	tests := []struct {
+		glob, name string
+		windows    bool
+		expected   bool
+	}{
+		{"/etc/sudoers", "/etc/sudoers", false, true},
+		{"/etc/sudoers.d/**", "/etc/sudoers.d/deploy", false, true},
+		{"/etc/sudoers.d/**", "/etc/sudoers.dx", false, false},
+		{"/etc/*.conf", "/etc/nginx/nginx.conf", false, false},
+		{`C:\Windows\System32\**`, `c:/windows/system32/drivers/etc/hosts`, true, true},
+		{"/ETC/HOSTS", "/etc/hosts", false, false},
+	}
+
+	for _, tt := range tests {
+		// Act
+		result := matchPath(tt.glob, tt.name, tt.windows)
+
+		// Assert
+		if result != tt.expected {
+			t.Errorf("matchPath(%q, %q) = %v, expected %v", tt.glob, tt.name, result, tt.expected)
+		}
+	}
+}
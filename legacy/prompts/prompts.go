@@ -0,0 +1,345 @@
+package prompts
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"please/config"
+)
+
+// Names of the shipped templates. Generation looks for "generate_<script type>" first, so a
+// user can add generate_python.tmpl without changing Please.
+const (
+	Generate     = "generate"
+	Refine       = "refine"
+	Fix          = "fix"
+	TestAnalysis = "test_analysis"
+	HouseStyle   = "house_style"
+)
+
+// DirName is the directory in the config directory that holds the user's template overrides
+const DirName = "prompts"
+
+// fileExtension is the extension of template files, shipped and overridden
+const fileExtension = ".tmpl"
+
+// shippedTemplates are the templates compiled into Please
+//
+//go:embed templates/*.tmpl
+var shippedTemplates embed.FS
+
+// versionPattern reads the "{{/* version: N */}}" header that starts every shipped template
+var versionPattern = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\d+)\s*\*/`)
+
+// descriptions explain the shipped templates in "please prompts show"
+var descriptions = map[string]string{
+	Generate:                 "Generates a script in a language without its own template",
+	Generate + "_bash":       "Generates a Bash script",
+	Generate + "_powershell": "Generates a PowerShell script",
+	Generate + "_analysis":   "Sends a test failure analysis as written",
+	Refine:                   "Refines a script from the user's feedback",
+	Fix:                      "Repairs a script from its error output",
+	TestAnalysis:             "Asks for a JSON analysis of a failed Go test",
+	HouseStyle:               "Team conventions added to every generate, refine and fix prompt",
+}
+
+// Data holds the variables available to templates; fields a prompt does not use are empty
+type Data struct {
+	Task        string       // What the user asked for
+	ScriptType  string       // "bash", "powershell" or another script type
+	Language    string       // Display name of ScriptType, e.g. "PowerShell"
+	Environment string       // Summary of the target machine, empty when disabled
+	HouseStyle  string       // The rendered house_style template
+	Script      string       // Script being refined or fixed
+	Request     string       // Refinement request
+	Error       string       // Error output of a failed script or test
+	Test        *TestFailure // Failed test, for test_analysis only
+	Structured  bool         // The provider asks for a JSON response with the script and notes about it
+}
+
+// TestFailure describes a failed Go test for the test_analysis template
+type TestFailure struct {
+	Name          string
+	Package       string
+	SourceFile    string
+	Line          int
+	Time          string
+	Output        string
+	SourceContext string
+}
+
+// Template is a prompt template as Please will use it
+type Template struct {
+	Name            string
+	Description     string
+	Source          string // The override when there is one, otherwise the shipped template
+	Version         int    // Version of the shipped template, 0 for a template only the user has
+	Overridden      bool
+	OverrideVersion int    // Shipped version the override was copied from
+	Path            string // Where the override is, or would be, stored
+}
+
+// Outdated reports whether the override was copied from an older shipped template
+func (t *Template) Outdated() bool {
+	return t.Overridden && t.Version > 0 && t.OverrideVersion < t.Version
+}
+
+// Dir returns the directory holding the user's template overrides
+func Dir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, DirName), nil
+}
+
+// Names lists the shipped templates and any the user added, sorted
+func Names() []string {
+	names := make(map[string]bool)
+	entries, _ := shippedTemplates.ReadDir("templates")
+	for _, entry := range entries {
+		names[strings.TrimSuffix(entry.Name(), fileExtension)] = true
+	}
+	if dir, err := Dir(); err == nil {
+		files, _ := filepath.Glob(filepath.Join(dir, "*"+fileExtension))
+		for _, file := range files {
+			names[strings.TrimSuffix(filepath.Base(file), fileExtension)] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// Shipped returns the template compiled into Please under name
+func Shipped(name string) (string, bool) {
+	data, err := shippedTemplates.ReadFile("templates/" + name + fileExtension)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// Load returns the template Please uses for name: the user's override if present, otherwise the shipped one
+func Load(name string) (*Template, error) {
+	t := &Template{Name: name, Description: descriptions[name]}
+
+	shipped, isShipped := Shipped(name)
+	if isShipped {
+		t.Source = shipped
+		t.Version = parseVersion(shipped)
+	}
+
+	if dir, err := Dir(); err == nil {
+		t.Path = filepath.Join(dir, name+fileExtension)
+		data, err := os.ReadFile(t.Path)
+		if err == nil {
+			t.Source = string(data)
+			t.Overridden = true
+			t.OverrideVersion = parseVersion(t.Source)
+		} else if !os.IsNotExist(err) {
+			return t, fmt.Errorf("failed to read prompt template %s: %v", name, err)
+		}
+	}
+
+	if !isShipped && !t.Overridden {
+		return nil, fmt.Errorf("unknown prompt template: %s", name)
+	}
+	return t, nil
+}
+
+// parseVersion reads the version header of a template, 0 when it has none
+func parseVersion(source string) int {
+	match := versionPattern.FindStringSubmatch(source)
+	if match == nil {
+		return 0
+	}
+	version, _ := strconv.Atoi(match[1])
+	return version
+}
+
+// GenerateName returns the generation template for scriptType: "generate_<type>" when one exists,
+// otherwise the generic "generate"
+func GenerateName(scriptType string) string {
+	name := Generate + "_" + scriptType
+	if _, err := Load(name); err == nil {
+		return name
+	}
+	return Generate
+}
+
+// RenderGenerate renders the generation prompt for data.ScriptType
+func RenderGenerate(data Data) (Parts, error) {
+	return RenderParts(GenerateName(data.ScriptType), data)
+}
+
+// Parts is a rendered template split for chat APIs: the template's "system" block holds the fixed
+// rules and the rest of the template is the user turn
+type Parts struct {
+	System string // Empty when the template defines no "system" block
+	User   string
+}
+
+// String joins the parts into a single prompt, for APIs and callers without a system role
+func (p Parts) String() string {
+	if p.System == "" {
+		return p.User
+	}
+	return p.System + "\n\n" + p.User
+}
+
+// Render fills in the named template as a single prompt; see RenderParts
+func Render(name string, data Data) (string, error) {
+	parts, err := RenderParts(name, data)
+	return parts.String(), err
+}
+
+// RenderParts fills in the named template, adding the language name and house style to data. If
+// the user's override fails, the shipped template is rendered instead and the error is returned with it.
+func RenderParts(name string, data Data) (Parts, error) {
+	t, err := Load(name)
+	if err != nil {
+		return Parts{}, err
+	}
+
+	if data.Language == "" {
+		data.Language = Language(data.ScriptType)
+	}
+	if name != HouseStyle && data.HouseStyle == "" {
+		// A broken house style must not stop generation; it is reported by "please prompts show"
+		data.HouseStyle, _ = Render(HouseStyle, data)
+	}
+
+	parts, err := execute(name, t.Source, data)
+	if err == nil || !t.Overridden {
+		return parts, err
+	}
+
+	shipped, ok := Shipped(name)
+	if !ok {
+		return Parts{}, err
+	}
+	parts, shippedErr := execute(name, shipped, data)
+	if shippedErr != nil {
+		return Parts{}, shippedErr
+	}
+	return parts, fmt.Errorf("%v (using the built-in template)", err)
+}
+
+// systemBlock is the name of the template block holding the system prompt
+const systemBlock = "system"
+
+// execute parses and runs one template source
+func execute(name, source string, data Data) (Parts, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return Parts{}, fmt.Errorf("invalid prompt template %s: %v", name, err)
+	}
+
+	var parts Parts
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return Parts{}, fmt.Errorf("failed to render prompt template %s: %v", name, err)
+	}
+	parts.User = strings.TrimSpace(out.String())
+
+	if tmpl.Lookup(systemBlock) != nil {
+		out.Reset()
+		if err := tmpl.ExecuteTemplate(&out, systemBlock, data); err != nil {
+			return Parts{}, fmt.Errorf("failed to render prompt template %s: %v", name, err)
+		}
+		parts.System = strings.TrimSpace(out.String())
+	}
+	return parts, nil
+}
+
+// Validate renders source with sample data, so mistakes show up when the template is saved
+// rather than on the next request
+func Validate(name, source string) error {
+	sample := Data{
+		Task:        "list the ten largest files in the current directory",
+		ScriptType:  "bash",
+		Language:    Language("bash"),
+		Environment: "- OS: linux/amd64",
+		Script:      "#!/bin/bash\necho hello",
+		Request:     "add logging",
+		Error:       "exit status 1",
+		Test:        &TestFailure{Name: "TestExample", Package: "example", SourceFile: "example_test.go", Line: 1},
+	}
+	_, err := execute(name, source, sample)
+	return err
+}
+
+// Edit copies the shipped template to the override directory if needed and returns its path
+func Edit(name string) (string, error) {
+	t, err := Load(name)
+	if err != nil {
+		if !strings.HasPrefix(name, Generate+"_") {
+			return "", err
+		}
+		// A new language: start from the generic generation template
+		t, err = Load(Generate)
+		if err != nil {
+			return "", err
+		}
+		t.Name = name
+		t.Overridden = false
+		if dir, dirErr := Dir(); dirErr == nil {
+			t.Path = filepath.Join(dir, name+fileExtension)
+		}
+	}
+	if t.Path == "" {
+		return "", fmt.Errorf("could not determine the prompt template directory")
+	}
+	if t.Overridden {
+		return t.Path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.Path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create prompt template directory: %v", err)
+	}
+	if err := os.WriteFile(t.Path, []byte(t.Source), 0644); err != nil {
+		return "", fmt.Errorf("failed to write prompt template: %v", err)
+	}
+	return t.Path, nil
+}
+
+// Reset removes the user's override of name, going back to the shipped template
+func Reset(name string) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name+fileExtension)
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("prompt template %s is not overridden", name)
+		}
+		return fmt.Errorf("failed to remove prompt template: %v", err)
+	}
+	return nil
+}
+
+// Language returns the display name of a script type
+func Language(scriptType string) string {
+	switch scriptType {
+	case "bash":
+		return "Bash"
+	case "powershell":
+		return "PowerShell"
+	case "":
+		return "shell"
+	}
+	return strings.ToUpper(scriptType[:1]) + scriptType[1:]
+}
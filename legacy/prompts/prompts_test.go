@@ -0,0 +1,189 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useConfigDir points the config directory at a fresh temporary directory and returns the override directory
+func useConfigDir(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+
+	dir, err := Dir()
+	if err != nil {
+		t.Fatalf("Expected a prompt directory, got: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create prompt directory: %v", err)
+	}
+	return dir
+}
+
+// writeOverride stores an override template for name
+func writeOverride(t *testing.T, dir, name, source string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+fileExtension), []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write override: %v", err)
+	}
+}
+
+func Test_when_validating_shipped_templates_then_all_render(t *testing.T) {
+	// Arrange
+	useConfigDir(t)
+
+	// Act & Assert
+	for _, name := range Names() {
+		source, ok := Shipped(name)
+		if !ok {
+			t.Fatalf("Expected %s to be shipped", name)
+		}
+		if err := Validate(name, source); err != nil {
+			t.Errorf("Expected shipped template %s to be valid, got: %v", name, err)
+		}
+		if parseVersion(source) < 1 {
+			t.Errorf("Expected shipped template %s to have a version header", name)
+		}
+	}
+}
+
+func Test_when_house_style_is_overridden_then_add_it_to_generation_prompts(t *testing.T) {
+	// Arrange
+	dir := useConfigDir(t)
+	writeOverride(t, dir, HouseStyle, `{{if eq .ScriptType "bash"}}- Start the script with set -euo pipefail{{end}}`)
+
+	// Act
+	bash, bashErr := RenderGenerate(Data{Task: "clean tmp", ScriptType: "bash"})
+	powershell, _ := RenderGenerate(Data{Task: "clean tmp", ScriptType: "powershell"})
+
+	// Assert
+	if bashErr != nil {
+		t.Fatalf("Expected no error, got: %v", bashErr)
+	}
+	if !strings.HasSuffix(bash.System, "\n- Start the script with set -euo pipefail") {
+		t.Errorf("Expected the house style at the end of the system prompt's requirements, got:\n%s", bash.System)
+	}
+	if strings.Contains(powershell.String(), "pipefail") {
+		t.Error("Expected the bash-only convention to be left out of PowerShell prompts")
+	}
+}
+
+func Test_when_rendering_generation_template_then_split_rules_from_task(t *testing.T) {
+	// Arrange
+	useConfigDir(t)
+
+	// Act
+	parts, err := RenderGenerate(Data{Task: "clean tmp", ScriptType: "powershell", Environment: "- OS: windows/amd64"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(parts.System, "Requirements:") || !strings.Contains(parts.System, "- OS: windows/amd64") {
+		t.Errorf("Expected rules and environment in the system prompt, got:\n%s", parts.System)
+	}
+	if strings.Contains(parts.User, "Requirements:") || !strings.Contains(parts.User, "clean tmp") {
+		t.Errorf("Expected only the task in the user turn, got:\n%s", parts.User)
+	}
+}
+
+func Test_when_override_has_no_system_block_then_send_everything_as_user_turn(t *testing.T) {
+	// Arrange
+	dir := useConfigDir(t)
+	writeOverride(t, dir, "generate_bash", "{{/* version: 1 */}}Write Bash for: {{.Task}}")
+
+	// Act
+	parts, err := RenderGenerate(Data{Task: "clean tmp", ScriptType: "bash"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if parts.System != "" || parts.User != "Write Bash for: clean tmp" {
+		t.Errorf("Expected a single user turn, got %+v", parts)
+	}
+}
+
+func Test_when_override_is_broken_then_render_shipped_template_and_report_error(t *testing.T) {
+	// Arrange
+	dir := useConfigDir(t)
+	writeOverride(t, dir, Refine, "Refine {{.Scrpt}}")
+
+	// Act
+	prompt, err := Render(Refine, Data{Script: "echo hi", Request: "add logging", ScriptType: "bash"})
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "built-in") {
+		t.Errorf("Expected an error mentioning the built-in fallback, got: %v", err)
+	}
+	if !strings.Contains(prompt, "Refined Bash Script:") || !strings.Contains(prompt, "echo hi") {
+		t.Errorf("Expected the shipped refine prompt, got:\n%s", prompt)
+	}
+}
+
+func Test_when_override_is_from_older_version_then_report_outdated(t *testing.T) {
+	// Arrange
+	dir := useConfigDir(t)
+	writeOverride(t, dir, Fix, "{{/* version: 0 */}}Fix {{.Script}}")
+
+	// Act
+	template, err := Load(Fix)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !template.Overridden || !template.Outdated() {
+		t.Errorf("Expected an outdated override, got overridden=%v version=%d override version=%d",
+			template.Overridden, template.Version, template.OverrideVersion)
+	}
+}
+
+func Test_when_user_adds_template_for_new_script_type_then_use_it_for_generation(t *testing.T) {
+	// Arrange
+	dir := useConfigDir(t)
+
+	// Act
+	before := GenerateName("python")
+	path, err := Edit("generate_python")
+	after := GenerateName("python")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if before != Generate || after != "generate_python" {
+		t.Errorf("Expected generic then generate_python, got %s then %s", before, after)
+	}
+	if path != filepath.Join(dir, "generate_python"+fileExtension) {
+		t.Errorf("Expected the override in the prompt directory, got %s", path)
+	}
+}
+
+func Test_when_resetting_template_then_remove_override(t *testing.T) {
+	// Arrange
+	useConfigDir(t)
+	path, err := Edit(Refine)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Act
+	resetErr := Reset(Refine)
+	secondErr := Reset(Refine)
+
+	// Assert
+	if resetErr != nil {
+		t.Errorf("Expected reset to succeed, got: %v", resetErr)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the override file to be removed")
+	}
+	if secondErr == nil {
+		t.Error("Expected an error when the template is not overridden")
+	}
+}
@@ -2,24 +2,29 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"please/types"
 )
 
+// anthropicBaseURL is the root of the Anthropic REST API
+const anthropicBaseURL = "https://api.anthropic.com"
+
 // AnthropicProvider implements the Provider interface for Anthropic Claude
 type AnthropicProvider struct {
-	config *types.Config
+	config  *types.Config
+	baseURL string
 }
 
 // NewAnthropicProvider creates a new Anthropic provider
 func NewAnthropicProvider(config *types.Config) *AnthropicProvider {
-	return &AnthropicProvider{config: config}
+	return &AnthropicProvider{config: config, baseURL: anthropicBaseURL}
 }
 
 // Name returns the provider name
@@ -33,59 +38,39 @@ func (p *AnthropicProvider) IsConfigured(config *types.Config) bool {
 }
 
 // GenerateScript generates a script using Anthropic's API
-func (p *AnthropicProvider) GenerateScript(request *types.ScriptRequest) (*types.ScriptResponse, error) {
+func (p *AnthropicProvider) GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error) {
 	if !p.IsConfigured(p.config) {
-		return nil, fmt.Errorf("Anthropic API key not configured. Please set ANTHROPIC_API_KEY environment variable or use 'please set anthropic key'")
-	}
-
-	prompt := CreatePrompt(request.TaskDescription, request.ScriptType)
-	
-	// Determine the model to use
-	model := request.Model
-	if model == "" {
-		model = p.getDefaultModel()
-	}
-
-	anthropicRequest := types.AnthropicRequest{
-		Model:     model,
-		MaxTokens: 2000,
-		Messages: []types.Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		Temperature: 0.3,
+		return nil, newNotConfiguredError(p.Name(), "Anthropic API key not configured. Please set ANTHROPIC_API_KEY environment variable or use 'please set anthropic key'")
 	}
 
-	jsonData, err := json.Marshal(anthropicRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
-	}
+	return withTextFallback(p.config, p.Name(), request, func(structured bool) (*types.ScriptResponse, error) {
+		return p.generateScript(ctx, request, structured)
+	})
+}
 
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
+// generateScript makes one messages call, forcing the structured response tool when structured is set
+func (p *AnthropicProvider) generateScript(ctx context.Context, request *types.ScriptRequest, structured bool) (*types.ScriptResponse, error) {
+	req, model, err := p.newMessagesRequest(request, false, structured)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", p.config.AnthropicAPIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := newRetryingClient(p.Name(), p.config).Do(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Anthropic API: %v", err)
+		if IsCancelled(err) {
+			return nil, err
+		}
+		return nil, newConnectionError(p.Name(), model, "failed to connect to Anthropic API", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
+		return nil, cancelledOr(ctx, p.Name(), fmt.Errorf("failed to read response: %v", err))
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Anthropic API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newStatusError(p.Name(), model, resp.StatusCode, body)
 	}
 
 	var anthropicResp types.AnthropicResponse
@@ -99,31 +84,195 @@ func (p *AnthropicProvider) GenerateScript(request *types.ScriptRequest) (*types
 
 	script := ""
 	for _, content := range anthropicResp.Content {
-		if content.Type == "text" {
+		switch content.Type {
+		case "text":
 			script += content.Text
+		case "tool_use":
+			script = string(content.Input)
+		}
+	}
+
+	response := &types.ScriptResponse{
+		Model:           model,
+		Provider:        request.Provider,
+		TaskDescription: request.TaskDescription,
+		ScriptType:      request.ScriptType,
+		Usage:           types.Usage{PromptTokens: anthropicResp.Usage.InputTokens, CompletionTokens: anthropicResp.Usage.OutputTokens},
+	}
+	applyContent(response, script, structured)
+	return response, nil
+}
+
+// GenerateScriptStream generates a script using Anthropic's streaming messages API
+func (p *AnthropicProvider) GenerateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string)) (*types.ScriptResponse, error) {
+	if !p.IsConfigured(p.config) {
+		return nil, newNotConfiguredError(p.Name(), "Anthropic API key not configured. Please set ANTHROPIC_API_KEY environment variable or use 'please set anthropic key'")
+	}
+
+	return withTextFallback(p.config, p.Name(), request, func(structured bool) (*types.ScriptResponse, error) {
+		return p.generateScriptStream(ctx, request, onLine, structured)
+	})
+}
+
+// generateScriptStream makes one streaming messages call, forcing the structured response tool when structured is set
+func (p *AnthropicProvider) generateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string), structured bool) (*types.ScriptResponse, error) {
+	req, model, err := p.newMessagesRequest(request, true, structured)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := newRetryingClient(p.Name(), p.config).Do(ctx, req)
+	if err != nil {
+		if IsCancelled(err) {
+			return nil, err
 		}
+		return nil, newConnectionError(p.Name(), model, "failed to connect to Anthropic API", err)
 	}
+	defer resp.Body.Close()
 
-	script = strings.TrimSpace(script)
-	cleanedScript := cleanScript(script)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newStatusError(p.Name(), model, resp.StatusCode, body)
+	}
+
+	stream := newResponseStream(newLineCleaner(onLine), structured)
+	var usage types.Usage
+	err = readServerSentEvents(resp.Body, func(event, data string) error {
+		var streamEvent types.AnthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &streamEvent); err != nil {
+			return fmt.Errorf("failed to parse stream event: %v", err)
+		}
+
+		switch streamEvent.Type {
+		case "message_start":
+			usage.PromptTokens = streamEvent.Message.Usage.InputTokens
+		case "message_delta":
+			usage.CompletionTokens = streamEvent.Usage.OutputTokens
+		case "content_block_delta":
+			switch streamEvent.Delta.Type {
+			case "text_delta":
+				stream.Write(streamEvent.Delta.Text)
+			case "input_json_delta":
+				stream.Write(streamEvent.Delta.PartialJSON)
+			}
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			return newStreamError(p.Name(), model, streamEvent.Error.Type, streamEvent.Error.Message)
+		}
+		return nil
+	})
+	if err == nil {
+		// message_stop ends every complete response, so a body without it is truncated
+		err = newTruncatedStreamError(p.Name(), model)
+	}
+	if !errors.Is(err, errStreamDone) {
+		return nil, cancelledOr(ctx, p.Name(), err)
+	}
 
-	return &types.ScriptResponse{
-		Script:          cleanedScript,
+	response := &types.ScriptResponse{
 		Model:           model,
 		Provider:        request.Provider,
 		TaskDescription: request.TaskDescription,
 		ScriptType:      request.ScriptType,
-	}, nil
+		Usage:           usage,
+	}
+	stream.Finish(response)
+	return response, nil
+}
+
+// newMessagesRequest builds the messages HTTP request and returns it with the resolved model
+func (p *AnthropicProvider) newMessagesRequest(request *types.ScriptRequest, stream, structured bool) (*http.Request, string, error) {
+	chat := createChatPrompt(request, structured)
+
+	// Determine the model to use
+	model := request.Model
+	if model == "" {
+		model = p.getDefaultModel()
+	}
+
+	anthropicRequest := types.AnthropicRequest{
+		Model:       model,
+		MaxTokens:   2000,
+		System:      chat.System,
+		Messages:    chat.Messages,
+		Temperature: 0.3,
+		Stream:      stream,
+	}
+	if structured {
+		anthropicRequest.Tools = []types.AnthropicTool{anthropicScriptTool()}
+		anthropicRequest.ToolChoice = &types.AnthropicToolChoice{Type: "tool", Name: structuredToolName}
+	}
+
+	jsonData, err := json.Marshal(anthropicRequest)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", p.baseURL+"/v1/messages", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.config.AnthropicAPIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	return req, model, nil
 }
 
-// GetAvailableModels returns the list of available Anthropic models
-func (p *AnthropicProvider) GetAvailableModels() []string {
-	return []string{
-		"claude-3-5-sonnet-20241022",
-		"claude-3-5-haiku-20241022",
-		"claude-3-opus-20240229",
-		"claude-3-sonnet-20240229",
-		"claude-3-haiku-20240307",
+// GetAvailableModels queries Anthropic's /v1/models endpoint for the models the key can use
+func (p *AnthropicProvider) GetAvailableModels() ([]types.ModelInfo, error) {
+	if !p.IsConfigured(p.config) {
+		return nil, newNotConfiguredError(p.Name(), "Anthropic API key not configured. Please set ANTHROPIC_API_KEY environment variable or use 'please set anthropic key'")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	var models []types.ModelInfo
+	afterID := ""
+	for {
+		url := p.baseURL + "/v1/models?limit=1000"
+		if afterID != "" {
+			url += "&after_id=" + afterID
+		}
+
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %v", err)
+		}
+		req.Header.Set("x-api-key", p.config.AnthropicAPIKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, newConnectionError(p.Name(), "", "failed to connect to Anthropic API", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, newStatusError(p.Name(), "", resp.StatusCode, body)
+		}
+
+		var modelsResp types.AnthropicModelsResponse
+		if err := json.Unmarshal(body, &modelsResp); err != nil {
+			return nil, fmt.Errorf("failed to parse models response: %v", err)
+		}
+
+		for _, model := range modelsResp.Data {
+			info := types.ModelInfo{Name: model.ID, ModifiedAt: model.CreatedAt}
+			info.Details.Family = "claude"
+			models = append(models, info)
+		}
+
+		if !modelsResp.HasMore || modelsResp.LastID == "" {
+			return models, nil
+		}
+		afterID = modelsResp.LastID
 	}
 }
 
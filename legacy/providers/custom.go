@@ -0,0 +1,201 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"please/types"
+)
+
+// CustomProvider implements the Provider interface for OpenAI-compatible chat endpoints
+// such as vLLM, LiteLLM or LocalAI configured under custom_providers
+type CustomProvider struct {
+	name           string
+	providerConfig types.ProviderConfig
+	config         *types.Config
+}
+
+// NewCustomProvider creates a new provider for a custom OpenAI-compatible endpoint.
+// config supplies the shared HTTP settings and may be nil to use the defaults.
+func NewCustomProvider(name string, providerConfig types.ProviderConfig, config *types.Config) *CustomProvider {
+	return &CustomProvider{name: name, providerConfig: providerConfig, config: config}
+}
+
+// Name returns the provider name as configured in custom_providers
+func (p *CustomProvider) Name() string {
+	return p.name
+}
+
+// IsConfigured checks if the custom provider has an endpoint URL
+func (p *CustomProvider) IsConfigured(config *types.Config) bool {
+	return strings.TrimSpace(p.providerConfig.URL) != ""
+}
+
+// GenerateScript generates a script using the custom provider's chat completions endpoint
+func (p *CustomProvider) GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error) {
+	if !p.IsConfigured(nil) {
+		return nil, newNotConfiguredError(p.name, fmt.Sprintf("custom provider %s has no URL configured", p.name))
+	}
+
+	return withTextFallback(p.config, p.Name(), request, func(structured bool) (*types.ScriptResponse, error) {
+		return p.generateScript(ctx, request, structured)
+	})
+}
+
+// generateScript makes one chat completions call, asking for a structured response when structured is set
+func (p *CustomProvider) generateScript(ctx context.Context, request *types.ScriptRequest, structured bool) (*types.ScriptResponse, error) {
+	req, model, err := p.newChatRequest(request, false, structured)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := newRetryingClient(p.Name(), p.config).Do(ctx, req)
+	if err != nil {
+		if IsCancelled(err) {
+			return nil, err
+		}
+		return nil, newConnectionError(p.name, model, fmt.Sprintf("failed to connect to %s at %s", p.name, p.providerConfig.URL), err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cancelledOr(ctx, p.Name(), fmt.Errorf("failed to read response: %v", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(p.name, model, resp.StatusCode, body)
+	}
+
+	var chatResp types.OpenAIResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices returned from %s", p.name)
+	}
+
+	response := &types.ScriptResponse{
+		Model:           model,
+		Provider:        p.name,
+		TaskDescription: request.TaskDescription,
+		ScriptType:      request.ScriptType,
+		Usage:           openAIUsage(chatResp.Usage),
+	}
+	applyContent(response, chatResp.Choices[0].Message.Content, structured)
+	return response, nil
+}
+
+// GenerateScriptStream generates a script using the custom provider's streaming chat completions
+func (p *CustomProvider) GenerateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string)) (*types.ScriptResponse, error) {
+	if !p.IsConfigured(nil) {
+		return nil, newNotConfiguredError(p.name, fmt.Sprintf("custom provider %s has no URL configured", p.name))
+	}
+
+	return withTextFallback(p.config, p.Name(), request, func(structured bool) (*types.ScriptResponse, error) {
+		return p.generateScriptStream(ctx, request, onLine, structured)
+	})
+}
+
+// generateScriptStream makes one streaming chat completions call, asking for a structured response when structured is set
+func (p *CustomProvider) generateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string), structured bool) (*types.ScriptResponse, error) {
+	req, model, err := p.newChatRequest(request, true, structured)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := newRetryingClient(p.Name(), p.config).Do(ctx, req)
+	if err != nil {
+		if IsCancelled(err) {
+			return nil, err
+		}
+		return nil, newConnectionError(p.name, model, fmt.Sprintf("failed to connect to %s at %s", p.name, p.providerConfig.URL), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newStatusError(p.name, model, resp.StatusCode, body)
+	}
+
+	stream := newResponseStream(newLineCleaner(onLine), structured)
+	usage, err := readOpenAIStream(resp.Body, stream, p.name, model)
+	if err != nil {
+		return nil, cancelledOr(ctx, p.Name(), err)
+	}
+
+	response := &types.ScriptResponse{
+		Model:           model,
+		Provider:        p.name,
+		TaskDescription: request.TaskDescription,
+		ScriptType:      request.ScriptType,
+		Usage:           usage,
+	}
+	stream.Finish(response)
+	return response, nil
+}
+
+// newChatRequest builds the chat completions HTTP request and returns it with the resolved model
+func (p *CustomProvider) newChatRequest(request *types.ScriptRequest, stream, structured bool) (*http.Request, string, error) {
+	chat := createChatPrompt(request, structured)
+
+	// Determine the model to use
+	model := request.Model
+	if model == "" {
+		model = p.providerConfig.Model
+	}
+
+	chatRequest := types.OpenAIRequest{
+		Model:       model,
+		Messages:    chat.WithSystemMessage(),
+		Temperature: 0.3,
+		MaxTokens:   2000,
+		Stream:      stream,
+	}
+	if structured {
+		chatRequest.ResponseFormat = openAIResponseFormat()
+	}
+
+	jsonData, err := json.Marshal(chatRequest)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", p.ChatCompletionsURL(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.providerConfig.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.providerConfig.APIKey)
+	}
+	// Configured headers win so gateways can override auth or add routing headers
+	for key, value := range p.providerConfig.Headers {
+		req.Header.Set(key, value)
+	}
+
+	return req, model, nil
+}
+
+// ChatCompletionsURL resolves the configured URL to a chat completions endpoint.
+// A full ".../chat/completions" URL is used as-is, a ".../v1" base gets the path appended,
+// and a bare host gets "/v1/chat/completions".
+func (p *CustomProvider) ChatCompletionsURL() string {
+	url := strings.TrimRight(strings.TrimSpace(p.providerConfig.URL), "/")
+
+	switch {
+	case strings.HasSuffix(url, "/chat/completions"):
+		return url
+	case strings.HasSuffix(url, "/v1"):
+		return url + "/chat/completions"
+	default:
+		return url + "/v1/chat/completions"
+	}
+}
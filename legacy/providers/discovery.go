@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"please/config"
+	"please/types"
+)
+
+// ModelLister is implemented by providers that can report the models they currently serve
+type ModelLister interface {
+	GetAvailableModels() ([]types.ModelInfo, error)
+}
+
+// modelCacheTTL is how long a discovered hosted model list is reused before asking the API again
+const modelCacheTTL = 24 * time.Hour
+
+// ModelCatalog caches hosted providers' model lists on disk so selection does not call
+// /v1/models on every run. Local Ollama models are always listed live.
+type ModelCatalog struct {
+	dir    string
+	config *types.Config
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// cachedModels is the on-disk form of one provider's model list
+type cachedModels struct {
+	FetchedAt time.Time         `json:"fetched_at"`
+	Models    []types.ModelInfo `json:"models"`
+}
+
+// NewModelCatalog creates a catalog caching model lists in dir
+func NewModelCatalog(dir string, config *types.Config) *ModelCatalog {
+	return &ModelCatalog{dir: dir, config: config, ttl: modelCacheTTL, now: time.Now}
+}
+
+// OpenModelCatalog creates the catalog in the "models" folder of the config directory
+func OpenModelCatalog(cfg *types.Config) (*ModelCatalog, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewModelCatalog(filepath.Join(configDir, "models"), cfg), nil
+}
+
+// Models returns the provider's models, from the cache when it is fresh. If the provider
+// cannot be reached, a stale cached list is returned in preference to an error.
+func (c *ModelCatalog) Models(providerName string) ([]types.ModelInfo, error) {
+	cached, cacheErr := c.read(providerName)
+	if cacheErr == nil && c.now().Sub(cached.FetchedAt) < c.ttl {
+		return cached.Models, nil
+	}
+
+	models, err := c.Refresh(providerName)
+	if err != nil && cacheErr == nil && !IsCancelled(err) {
+		return cached.Models, nil
+	}
+	return models, err
+}
+
+// Refresh asks the provider for its models and updates the cache
+func (c *ModelCatalog) Refresh(providerName string) ([]types.ModelInfo, error) {
+	provider, err := NewProvider(providerName, c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := provider.(ModelLister)
+	if !ok {
+		return nil, fmt.Errorf("provider %s cannot list its models", providerName)
+	}
+
+	models, err := lister.GetAvailableModels()
+	if err != nil {
+		return nil, err
+	}
+
+	if providerName != "ollama" {
+		c.write(providerName, models)
+	}
+	return models, nil
+}
+
+// read loads the cached list for a provider
+func (c *ModelCatalog) read(providerName string) (*cachedModels, error) {
+	if providerName == "ollama" {
+		return nil, os.ErrNotExist
+	}
+
+	data, err := os.ReadFile(c.CachePath(providerName))
+	if err != nil {
+		return nil, err
+	}
+
+	var cached cachedModels
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+// write stores a provider's list; failures only cost a future API call
+func (c *ModelCatalog) write(providerName string, models []types.ModelInfo) {
+	data, err := json.Marshal(cachedModels{FetchedAt: c.now(), Models: models})
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(c.dir, 0755) == nil {
+		os.WriteFile(c.CachePath(providerName), data, 0644)
+	}
+}
+
+// CachePath returns the cache file for a provider
+func (c *ModelCatalog) CachePath(providerName string) string {
+	return filepath.Join(c.dir, providerName+".json")
+}
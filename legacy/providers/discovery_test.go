@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"please/types"
+)
+
+func Test_when_model_list_is_cached_then_reuse_it_without_calling_api(t *testing.T) {
+	// Arrange
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"data":[{"id":"gpt-4o","created":1715367049}]}`))
+	}))
+	defer server.Close()
+
+	catalog := NewModelCatalog(t.TempDir(), &types.Config{OpenAIAPIKey: "test-key"})
+	provider := NewOpenAIProvider(catalog.config)
+	provider.baseURL = server.URL
+	models, _ := provider.GetAvailableModels()
+	catalog.write("openai", models)
+
+	// Act - the built-in provider would call the real API, so any refresh would fail the test
+	cached, err := catalog.Models("openai")
+
+	// Assert
+	if err != nil || len(cached) != 1 || cached[0].Name != "gpt-4o" {
+		t.Errorf("Expected cached gpt-4o, got %v (%v)", cached, err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected only the initial API request, got %d", requests)
+	}
+}
+
+func Test_when_cached_list_is_stale_and_refresh_fails_then_return_stale_list(t *testing.T) {
+	// Arrange
+	catalog := NewModelCatalog(t.TempDir(), &types.Config{}) // no API key, so refresh fails
+	start := time.Now()
+	catalog.now = func() time.Time { return start }
+	catalog.write("anthropic", []types.ModelInfo{{Name: "claude-3-5-haiku-20241022"}})
+
+	// Act
+	catalog.now = func() time.Time { return start.Add(2 * modelCacheTTL) }
+	models, err := catalog.Models("anthropic")
+
+	// Assert
+	if err != nil || len(models) != 1 {
+		t.Errorf("Expected stale cached list, got %v (%v)", models, err)
+	}
+}
+
+func Test_when_provider_cannot_list_models_then_return_error(t *testing.T) {
+	// Arrange
+	catalog := NewModelCatalog(t.TempDir(), &types.Config{})
+
+	// Act
+	_, err := catalog.Models("openai")
+
+	// Assert
+	if ErrorKindOf(err) != ErrorNotConfigured {
+		t.Errorf("Expected not configured error without API key, got: %v", err)
+	}
+}
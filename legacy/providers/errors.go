@@ -0,0 +1,259 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CancelledError is returned when a provider call is interrupted by Ctrl+C or its context deadline
+type CancelledError struct {
+	Provider string
+	Err      error
+}
+
+// Error describes which provider call was cancelled
+func (e *CancelledError) Error() string {
+	return fmt.Sprintf("%s request cancelled: %v", e.Provider, e.Err)
+}
+
+// Unwrap exposes the underlying context error
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
+// IsCancelled reports whether err comes from a cancelled provider call
+func IsCancelled(err error) bool {
+	var cancelled *CancelledError
+	return errors.As(err, &cancelled)
+}
+
+// cancelledOr returns a CancelledError if ctx is done, otherwise err unchanged
+func cancelledOr(ctx context.Context, provider string, err error) error {
+	if ctx.Err() != nil && !IsCancelled(err) {
+		return &CancelledError{Provider: provider, Err: ctx.Err()}
+	}
+	return err
+}
+
+// ErrorKind classifies provider failures so callers can react without parsing messages
+type ErrorKind string
+
+const (
+	ErrorUnknown         ErrorKind = "unknown"
+	ErrorNotConfigured   ErrorKind = "not_configured"
+	ErrorAuthentication  ErrorKind = "authentication"
+	ErrorModelNotFound   ErrorKind = "model_not_found"
+	ErrorQuotaExceeded   ErrorKind = "quota_exceeded"
+	ErrorRateLimited     ErrorKind = "rate_limited"
+	ErrorContextTooLong  ErrorKind = "context_too_long"
+	ErrorInvalidRequest  ErrorKind = "invalid_request"
+	ErrorServer          ErrorKind = "server"
+	ErrorNetwork         ErrorKind = "network"
+	ErrorInvalidResponse ErrorKind = "invalid_response"
+)
+
+// ProviderError is the structured error returned by providers for configuration,
+// connection and API failures
+type ProviderError struct {
+	Provider   string    // Registry name of the provider, e.g. "openai"
+	Kind       ErrorKind // Classified failure kind
+	StatusCode int       // HTTP status, or 0 when no response was received
+	Code       string    // Provider error code or type, e.g. "insufficient_quota"
+	Message    string    // Provider error message, or the raw body when it could not be parsed
+	Model      string    // Model the request was made with, if known
+	Err        error     // Underlying error, e.g. from the network
+}
+
+// Error keeps the "<Provider> API returned status N: message" form used before errors were typed
+func (e *ProviderError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s API returned status %d: %s", providerDisplayName(e.Provider), e.StatusCode, e.Message)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap exposes the underlying error
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Remediation suggests what the user can do about the error, or "" if there is nothing specific
+func (e *ProviderError) Remediation() string {
+	name := providerDisplayName(e.Provider)
+
+	switch e.Kind {
+	case ErrorNotConfigured, ErrorAuthentication:
+		switch e.Provider {
+		case "openai":
+			return "Check your OpenAI API key: set OPENAI_API_KEY or run 'please set openai key'"
+		case "anthropic":
+			return "Check your Anthropic API key: set ANTHROPIC_API_KEY or run 'please set anthropic key'"
+		}
+		return fmt.Sprintf("Check the url and api_key configured for provider %s", e.Provider)
+	case ErrorModelNotFound:
+		if e.Provider == "ollama" && e.Model != "" {
+			return fmt.Sprintf("Run `ollama pull %s` to download the model", e.Model)
+		}
+		return fmt.Sprintf("Check that model %q is available from %s", e.Model, name)
+	case ErrorQuotaExceeded:
+		return fmt.Sprintf("Your %s quota is exhausted; check your plan and billing details", name)
+	case ErrorRateLimited:
+		return fmt.Sprintf("%s is rate limiting requests; wait a moment and try again", name)
+	case ErrorContextTooLong:
+		return "Shorten the task description or choose a model with a larger context window"
+	case ErrorServer:
+		return fmt.Sprintf("%s is having problems; try again shortly or switch provider", name)
+	case ErrorNetwork:
+		if e.Provider == "ollama" {
+			return "Make sure Ollama is running (`ollama serve`)"
+		}
+		return fmt.Sprintf("Check your network connection to %s", name)
+	}
+	return ""
+}
+
+// AsProviderError returns the ProviderError wrapped in err, if any
+func AsProviderError(err error) (*ProviderError, bool) {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr, true
+	}
+	return nil, false
+}
+
+// ErrorKindOf returns the kind of a provider error, or ErrorUnknown for other errors
+func ErrorKindOf(err error) ErrorKind {
+	if providerErr, ok := AsProviderError(err); ok {
+		return providerErr.Kind
+	}
+	return ErrorUnknown
+}
+
+// newNotConfiguredError reports a provider that is missing its API key or URL
+func newNotConfiguredError(provider, message string) *ProviderError {
+	return &ProviderError{Provider: provider, Kind: ErrorNotConfigured, Message: message}
+}
+
+// newConnectionError reports a request that never received a response
+func newConnectionError(provider, model, message string, err error) *ProviderError {
+	return &ProviderError{Provider: provider, Kind: ErrorNetwork, Message: message, Model: model, Err: err}
+}
+
+// newStatusError builds an error from a non-200 response, parsing the provider's error body
+func newStatusError(provider, model string, statusCode int, body []byte) *ProviderError {
+	code, message := parseErrorBody(body)
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+
+	return &ProviderError{
+		Provider:   provider,
+		Kind:       classifyError(statusCode, code, message),
+		StatusCode: statusCode,
+		Code:       code,
+		Message:    message,
+		Model:      model,
+	}
+}
+
+// newStreamError reports an error event received part way through a streamed response
+func newStreamError(provider, model, code, message string) *ProviderError {
+	return &ProviderError{
+		Provider: provider,
+		Kind:     classifyError(0, code, message),
+		Code:     code,
+		Message:  fmt.Sprintf("%s stream error (%s): %s", providerDisplayName(provider), code, message),
+		Model:    model,
+	}
+}
+
+// newTruncatedStreamError reports a streamed response that ended before the provider signalled completion
+func newTruncatedStreamError(provider, model string) *ProviderError {
+	return &ProviderError{
+		Provider: provider,
+		Kind:     ErrorInvalidResponse,
+		Message:  fmt.Sprintf("%s stream ended before the response was complete", providerDisplayName(provider)),
+		Model:    model,
+	}
+}
+
+// parseErrorBody extracts the error code and message from the JSON error shapes used by
+// OpenAI-compatible APIs ({"error":{"code","type","message"}}), Anthropic
+// ({"type":"error","error":{"type","message"}}) and Ollama ({"error":"message"})
+func parseErrorBody(body []byte) (code, message string) {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || len(envelope.Error) == 0 {
+		return "", ""
+	}
+
+	var text string
+	if err := json.Unmarshal(envelope.Error, &text); err == nil {
+		return "", text
+	}
+
+	var detail struct {
+		Code    interface{} `json:"code"`
+		Type    string      `json:"type"`
+		Message string      `json:"message"`
+	}
+	if err := json.Unmarshal(envelope.Error, &detail); err != nil {
+		return "", ""
+	}
+
+	code = detail.Type
+	if s, ok := detail.Code.(string); ok && s != "" {
+		code = s
+	}
+	return code, detail.Message
+}
+
+// classifyError maps an HTTP status and provider error code/message onto an ErrorKind
+func classifyError(statusCode int, code, message string) ErrorKind {
+	text := strings.ToLower(code + " " + message)
+
+	switch {
+	case strings.Contains(text, "context_length") || strings.Contains(text, "context length") ||
+		strings.Contains(text, "context window") || strings.Contains(text, "prompt is too long") ||
+		statusCode == http.StatusRequestEntityTooLarge:
+		return ErrorContextTooLong
+	case strings.Contains(text, "insufficient_quota") || strings.Contains(text, "billing") ||
+		strings.Contains(text, "credit balance"):
+		return ErrorQuotaExceeded
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden ||
+		strings.Contains(text, "invalid_api_key") || strings.Contains(text, "authentication_error") ||
+		strings.Contains(text, "permission_error"):
+		return ErrorAuthentication
+	case statusCode == http.StatusNotFound || strings.Contains(text, "model_not_found") ||
+		(strings.Contains(text, "model") && strings.Contains(text, "not found")):
+		return ErrorModelNotFound
+	case statusCode == http.StatusTooManyRequests || strings.Contains(text, "rate_limit"):
+		return ErrorRateLimited
+	case statusCode >= 500 || strings.Contains(text, "overloaded") || strings.Contains(text, "api_error"):
+		return ErrorServer
+	case statusCode >= 400:
+		return ErrorInvalidRequest
+	}
+	return ErrorUnknown
+}
+
+// providerDisplayName returns the user-facing name for a provider
+func providerDisplayName(provider string) string {
+	switch provider {
+	case "ollama":
+		return "Ollama"
+	case "openai":
+		return "OpenAI"
+	case "anthropic":
+		return "Anthropic"
+	}
+	return provider
+}
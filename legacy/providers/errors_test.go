@@ -0,0 +1,94 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"please/types"
+)
+
+func Test_when_provider_error_body_is_parsed_then_classify_kind(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		kind    ErrorKind
+		code    string
+		message string
+	}{
+		{"openai bad key", 401, `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`, ErrorAuthentication, "invalid_api_key", "Incorrect API key provided"},
+		{"openai quota", 429, `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":null}}`, ErrorQuotaExceeded, "insufficient_quota", "You exceeded your current quota"},
+		{"openai context", 400, `{"error":{"message":"This model's maximum context length is 4097 tokens","code":"context_length_exceeded"}}`, ErrorContextTooLong, "context_length_exceeded", "This model's maximum context length is 4097 tokens"},
+		{"anthropic overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrorServer, "overloaded_error", "Overloaded"},
+		{"anthropic rate limit", 429, `{"type":"error","error":{"type":"rate_limit_error","message":"Too many requests"}}`, ErrorRateLimited, "rate_limit_error", "Too many requests"},
+		{"ollama missing model", 404, `{"error":"model 'llama3.2' not found, try pulling it first"}`, ErrorModelNotFound, "", "model 'llama3.2' not found, try pulling it first"},
+		{"plain text body", 502, "bad gateway", ErrorServer, "", "bad gateway"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newStatusError("openai", "gpt-4", tt.status, []byte(tt.body))
+
+			if err.Kind != tt.kind || err.Code != tt.code || err.Message != tt.message {
+				t.Errorf("got kind=%s code=%q message=%q, want kind=%s code=%q message=%q",
+					err.Kind, err.Code, err.Message, tt.kind, tt.code, tt.message)
+			}
+		})
+	}
+}
+
+func Test_when_ollama_model_is_missing_then_suggest_ollama_pull(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model 'llama3.2' not found, try pulling it first"}`))
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(&types.Config{OllamaURL: server.URL})
+
+	// Act
+	_, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{
+		TaskDescription: "list files",
+		ScriptType:      "bash",
+		Provider:        "ollama",
+		Model:           "llama3.2",
+	})
+
+	// Assert
+	providerErr, ok := AsProviderError(err)
+	if !ok {
+		t.Fatalf("Expected ProviderError, got %T: %v", err, err)
+	}
+	if providerErr.Kind != ErrorModelNotFound || providerErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 model not found, got %s (%d)", providerErr.Kind, providerErr.StatusCode)
+	}
+	if !strings.Contains(providerErr.Remediation(), "ollama pull llama3.2") {
+		t.Errorf("Expected ollama pull remediation, got %q", providerErr.Remediation())
+	}
+	if !strings.Contains(err.Error(), "Ollama API returned status 404") {
+		t.Errorf("Expected status in error message, got %q", err.Error())
+	}
+}
+
+func Test_when_api_key_is_missing_then_return_not_configured_kind(t *testing.T) {
+	// Arrange
+	provider := NewOpenAIProvider(&types.Config{})
+
+	// Act
+	_, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{ScriptType: "bash"})
+
+	// Assert
+	if ErrorKindOf(err) != ErrorNotConfigured {
+		t.Errorf("Expected not configured error, got %s: %v", ErrorKindOf(err), err)
+	}
+}
+
+func Test_when_error_is_not_from_provider_then_kind_is_unknown(t *testing.T) {
+	if kind := ErrorKindOf(context.Canceled); kind != ErrorUnknown {
+		t.Errorf("Expected unknown kind, got %s", kind)
+	}
+}
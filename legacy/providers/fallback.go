@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"please/types"
+)
+
+// FallbackChain returns primary followed by the configured fallback providers, without duplicates
+func FallbackChain(primary string, config *types.Config) []string {
+	chain := []string{primary}
+	if config == nil {
+		return chain
+	}
+
+	seen := map[string]bool{primary: true}
+	for _, name := range config.FallbackProviders {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		chain = append(chain, name)
+	}
+	return chain
+}
+
+// ShouldFallback reports whether err means the provider cannot serve requests right now,
+// so the next provider in the chain should be tried. Cancellation and problems with the
+// request itself, such as an over-long prompt, are returned to the user instead.
+func ShouldFallback(err error) bool {
+	if err == nil || IsCancelled(err) {
+		return false
+	}
+
+	switch ErrorKindOf(err) {
+	case ErrorNotConfigured, ErrorAuthentication, ErrorModelNotFound, ErrorQuotaExceeded,
+		ErrorRateLimited, ErrorServer, ErrorNetwork:
+		return true
+	}
+	return false
+}
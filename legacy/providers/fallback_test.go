@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"please/types"
+)
+
+func Test_when_building_fallback_chain_then_primary_comes_first_without_duplicates(t *testing.T) {
+	// Arrange
+	config := &types.Config{FallbackProviders: []string{"anthropic", "ollama", "", "openai", "anthropic"}}
+
+	// Act
+	chain := FallbackChain("ollama", config)
+
+	// Assert
+	expected := []string{"ollama", "anthropic", "openai"}
+	if strings.Join(chain, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, chain)
+	}
+}
+
+func Test_when_classifying_errors_for_fallback_then_only_unusable_providers_fall_back(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"not configured", newNotConfiguredError("openai", "no key"), true},
+		{"network", newConnectionError("ollama", "llama3.2", "failed to connect", errors.New("refused")), true},
+		{"server", newStatusError("anthropic", "", 529, []byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)), true},
+		{"context too long", newStatusError("openai", "", 400, []byte(`{"error":{"code":"context_length_exceeded","message":"too long"}}`)), false},
+		{"cancelled", &CancelledError{Provider: "openai", Err: context.Canceled}, false},
+		{"unsupported provider", errors.New("unsupported provider: nope"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldFallback(tt.err); got != tt.expected {
+				t.Errorf("ShouldFallback(%v) = %v, want %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -35,81 +36,180 @@ func (p *OllamaProvider) IsConfigured(config *types.Config) bool {
 }
 
 // GenerateScript generates a script using Ollama
-func (p *OllamaProvider) GenerateScript(request *types.ScriptRequest) (*types.ScriptResponse, error) {
-	baseURL := p.config.OllamaURL
-	if baseURL == "" {
-		baseURL = "http://localhost:11434"
-	}
+func (p *OllamaProvider) GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error) {
+	return withTextFallback(p.config, p.Name(), request, func(structured bool) (*types.ScriptResponse, error) {
+		return p.generateScript(ctx, request, structured)
+	})
+}
 
-	prompt := CreatePrompt(request.TaskDescription, request.ScriptType)
+// generateScript makes one /api/chat call, constraining the output to the response schema when structured is set
+func (p *OllamaProvider) generateScript(ctx context.Context, request *types.ScriptRequest, structured bool) (*types.ScriptResponse, error) {
+	baseURL := p.getBaseURL()
 
-	ollamaRequest := types.OllamaRequest{
-		Model:  request.Model,
-		Prompt: prompt,
-		Stream: false,
-		Options: map[string]interface{}{
-			"temperature": 0.3,
-			"top_p":       0.9,
-		},
+	jsonData, err := p.newChatBody(request, false, structured)
+	if err != nil {
+		return nil, err
 	}
 
-	jsonData, err := json.Marshal(ollamaRequest)
+	req, err := http.NewRequest("POST", baseURL+"/api/chat", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Post(baseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := newRetryingClient(p.Name(), p.config).Do(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Ollama at %s: %v\nMake sure Ollama is running", baseURL, err)
+		if IsCancelled(err) {
+			return nil, err
+		}
+		return nil, newConnectionError(p.Name(), request.Model, "failed to connect to Ollama at "+baseURL, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Ollama API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newStatusError(p.Name(), request.Model, resp.StatusCode, body)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
+		return nil, cancelledOr(ctx, p.Name(), fmt.Errorf("failed to read response: %v", err))
 	}
 
-	var ollamaResp types.OllamaResponse
+	var ollamaResp types.OllamaChatResponse
 	if err := json.Unmarshal(body, &ollamaResp); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %v", err)
 	}
+	if ollamaResp.Error != "" {
+		return nil, newStreamError(p.Name(), request.Model, "", ollamaResp.Error)
+	}
 
-	script := strings.TrimSpace(ollamaResp.Response)
-	cleanedScript := cleanScript(script)
+	response := &types.ScriptResponse{
+		Model:           request.Model,
+		Provider:        request.Provider,
+		TaskDescription: request.TaskDescription,
+		ScriptType:      request.ScriptType,
+		Usage:           types.Usage{PromptTokens: ollamaResp.PromptEvalCount, CompletionTokens: ollamaResp.EvalCount},
+	}
+	applyContent(response, ollamaResp.Message.Content, structured)
+	return response, nil
+}
+
+// GenerateScriptStream generates a script using Ollama's NDJSON streaming output
+func (p *OllamaProvider) GenerateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string)) (*types.ScriptResponse, error) {
+	return withTextFallback(p.config, p.Name(), request, func(structured bool) (*types.ScriptResponse, error) {
+		return p.generateScriptStream(ctx, request, onLine, structured)
+	})
+}
+
+// generateScriptStream makes one streaming /api/chat call, constraining the output to the response schema when structured is set
+func (p *OllamaProvider) generateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string), structured bool) (*types.ScriptResponse, error) {
+	baseURL := p.getBaseURL()
+
+	jsonData, err := p.newChatBody(request, true, structured)
+	if err != nil {
+		return nil, err
+	}
 
-	return &types.ScriptResponse{
-		Script:          cleanedScript,
+	req, err := http.NewRequest("POST", baseURL+"/api/chat", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := newRetryingClient(p.Name(), p.config).Do(ctx, req)
+	if err != nil {
+		if IsCancelled(err) {
+			return nil, err
+		}
+		return nil, newConnectionError(p.Name(), request.Model, "failed to connect to Ollama at "+baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newStatusError(p.Name(), request.Model, resp.StatusCode, body)
+	}
+
+	stream := newResponseStream(newLineCleaner(onLine), structured)
+	var usage types.Usage
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk types.OllamaChatResponse
+		if err := decoder.Decode(&chunk); err == io.EOF {
+			// The final chunk has Done set, so running out of body first means the script is truncated
+			return nil, cancelledOr(ctx, p.Name(), newTruncatedStreamError(p.Name(), request.Model))
+		} else if err != nil {
+			return nil, cancelledOr(ctx, p.Name(), fmt.Errorf("failed to parse stream chunk: %v", err))
+		}
+		if chunk.Error != "" {
+			return nil, newStreamError(p.Name(), request.Model, "", chunk.Error)
+		}
+
+		stream.Write(chunk.Message.Content)
+		if chunk.Done {
+			usage = types.Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			break
+		}
+	}
+
+	response := &types.ScriptResponse{
 		Model:           request.Model,
 		Provider:        request.Provider,
 		TaskDescription: request.TaskDescription,
 		ScriptType:      request.ScriptType,
-	}, nil
+		Usage:           usage,
+	}
+	stream.Finish(response)
+	return response, nil
+}
+
+// newChatBody builds the JSON body for an /api/chat request, which takes the system prompt as a
+// message so the model sees the rules apart from the task
+func (p *OllamaProvider) newChatBody(request *types.ScriptRequest, stream, structured bool) ([]byte, error) {
+	ollamaRequest := types.OllamaChatRequest{
+		Model:    request.Model,
+		Messages: createChatPrompt(request, structured).WithSystemMessage(),
+		Stream:   stream,
+		Options: map[string]interface{}{
+			"temperature": 0.3,
+			"top_p":       0.9,
+		},
+	}
+	if structured {
+		ollamaRequest.Format = scriptSchema
+	}
+
+	jsonData, err := json.Marshal(ollamaRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+	return jsonData, nil
+}
+
+// getBaseURL returns the configured Ollama URL or the local default
+func (p *OllamaProvider) getBaseURL() string {
+	if p.config.OllamaURL == "" {
+		return "http://localhost:11434"
+	}
+	return p.config.OllamaURL
 }
 
 // GetAvailableModels queries Ollama for all available models
 func (p *OllamaProvider) GetAvailableModels() ([]types.ModelInfo, error) {
-	baseURL := p.config.OllamaURL
-	if baseURL == "" {
-		baseURL = "http://localhost:11434"
-	}
+	baseURL := p.getBaseURL()
 
 	client := &http.Client{Timeout: 10 * time.Second}
 
 	resp, err := client.Get(baseURL + "/api/tags")
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Ollama: %v", err)
+		return nil, newConnectionError(p.Name(), "", "failed to connect to Ollama", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Ollama API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newStatusError(p.Name(), "", resp.StatusCode, body)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -122,27 +222,85 @@ func (p *OllamaProvider) GetAvailableModels() ([]types.ModelInfo, error) {
 		return nil, fmt.Errorf("failed to parse models response: %v", err)
 	}
 
+	p.describeModels(client, modelsResp.Models)
 	return modelsResp.Models, nil
 }
 
+// describeModels marks models Ollama currently has loaded and fills in details from /api/show.
+// Both endpoints are best effort: older Ollama versions may lack them and selection copes without.
+func (p *OllamaProvider) describeModels(client *http.Client, models []types.ModelInfo) {
+	loaded := make(map[string]bool)
+	if resp, err := client.Get(p.getBaseURL() + "/api/ps"); err == nil {
+		var running types.OllamaRunningModelsResponse
+		if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&running) == nil {
+			for _, model := range running.Models {
+				loaded[model.Name] = true
+			}
+		}
+		resp.Body.Close()
+	}
+
+	for i := range models {
+		models[i].Loaded = loaded[models[i].Name]
+
+		show, err := p.showModel(client, models[i].Name)
+		if err != nil {
+			continue
+		}
+		if models[i].Details.ParameterSize == "" {
+			models[i].Details.ParameterSize = show.Details.ParameterSize
+		}
+		if models[i].Details.QuantizationLevel == "" {
+			models[i].Details.QuantizationLevel = show.Details.QuantizationLevel
+		}
+		models[i].ContextLength = contextLength(show.ModelInfo)
+	}
+}
+
+// showModel asks Ollama for one model's details
+func (p *OllamaProvider) showModel(client *http.Client, name string) (*types.OllamaShowResponse, error) {
+	body, err := json.Marshal(map[string]string{"model": name})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Post(p.getBaseURL()+"/api/show", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("show returned status %d", resp.StatusCode)
+	}
+
+	var show types.OllamaShowResponse
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return nil, fmt.Errorf("failed to parse show response: %v", err)
+	}
+	return &show, nil
+}
+
+// contextLength finds the "<architecture>.context_length" entry in /api/show's model_info
+func contextLength(modelInfo map[string]interface{}) int {
+	for key, value := range modelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if n, ok := value.(float64); ok {
+			return int(n)
+		}
+	}
+	return 0
+}
+
 // cleanScript removes markdown formatting and other unwanted content from the generated script
 func cleanScript(script string) string {
 	lines := strings.Split(script, "\n")
 	cleanedLines := []string{}
 
 	for _, line := range lines {
-		// Skip markdown code block markers
-		if strings.HasPrefix(strings.TrimSpace(line), "```") {
-			continue
-		}
-
-		// Skip empty explanatory lines that might have been added
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" ||
-			strings.HasPrefix(trimmed, "Here's a PowerShell script") ||
-			strings.HasPrefix(trimmed, "Here's a Bash script") ||
-			strings.HasPrefix(trimmed, "This script will") ||
-			strings.HasPrefix(trimmed, "The following script") {
+		if isScriptNoiseLine(line) {
 			continue
 		}
 
@@ -151,3 +309,20 @@ func cleanScript(script string) string {
 
 	return strings.Join(cleanedLines, "\n")
 }
+
+// isScriptNoiseLine reports whether a line is markdown or explanatory text that cleanScript removes
+func isScriptNoiseLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+
+	// Skip markdown code block markers
+	if strings.HasPrefix(trimmed, "```") {
+		return true
+	}
+
+	// Skip empty explanatory lines that might have been added
+	return trimmed == "" ||
+		strings.HasPrefix(trimmed, "Here's a PowerShell script") ||
+		strings.HasPrefix(trimmed, "Here's a Bash script") ||
+		strings.HasPrefix(trimmed, "This script will") ||
+		strings.HasPrefix(trimmed, "The following script")
+}
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,14 +13,18 @@ import (
 	"please/types"
 )
 
+// openAIBaseURL is the root of the OpenAI REST API
+const openAIBaseURL = "https://api.openai.com"
+
 // OpenAIProvider implements the Provider interface for OpenAI
 type OpenAIProvider struct {
-	config *types.Config
+	config  *types.Config
+	baseURL string
 }
 
 // NewOpenAIProvider creates a new OpenAI provider
 func NewOpenAIProvider(config *types.Config) *OpenAIProvider {
-	return &OpenAIProvider{config: config}
+	return &OpenAIProvider{config: config, baseURL: openAIBaseURL}
 }
 
 // Name returns the provider name
@@ -33,13 +38,114 @@ func (p *OpenAIProvider) IsConfigured(config *types.Config) bool {
 }
 
 // GenerateScript generates a script using OpenAI's API
-func (p *OpenAIProvider) GenerateScript(request *types.ScriptRequest) (*types.ScriptResponse, error) {
+func (p *OpenAIProvider) GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error) {
 	if !p.IsConfigured(p.config) {
-		return nil, fmt.Errorf("OpenAI API key not configured. Please set OPENAI_API_KEY environment variable or use 'please set openai key'")
+		return nil, newNotConfiguredError(p.Name(), "OpenAI API key not configured. Please set OPENAI_API_KEY environment variable or use 'please set openai key'")
+	}
+
+	return withTextFallback(p.config, p.Name(), request, func(structured bool) (*types.ScriptResponse, error) {
+		return p.generateScript(ctx, request, structured)
+	})
+}
+
+// generateScript makes one chat completions call, asking for a structured response when structured is set
+func (p *OpenAIProvider) generateScript(ctx context.Context, request *types.ScriptRequest, structured bool) (*types.ScriptResponse, error) {
+	req, model, err := p.newChatRequest(request, false, structured)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := newRetryingClient(p.Name(), p.config).Do(ctx, req)
+	if err != nil {
+		if IsCancelled(err) {
+			return nil, err
+		}
+		return nil, newConnectionError(p.Name(), model, "failed to connect to OpenAI API", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cancelledOr(ctx, p.Name(), fmt.Errorf("failed to read response: %v", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(p.Name(), model, resp.StatusCode, body)
+	}
+
+	var openaiResp types.OpenAIResponse
+	if err := json.Unmarshal(body, &openaiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	if len(openaiResp.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices returned from OpenAI")
+	}
+
+	response := &types.ScriptResponse{
+		Model:           model,
+		Provider:        request.Provider,
+		TaskDescription: request.TaskDescription,
+		ScriptType:      request.ScriptType,
+		Usage:           openAIUsage(openaiResp.Usage),
+	}
+	applyContent(response, openaiResp.Choices[0].Message.Content, structured)
+	return response, nil
+}
+
+// GenerateScriptStream generates a script using OpenAI's streaming chat completions
+func (p *OpenAIProvider) GenerateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string)) (*types.ScriptResponse, error) {
+	if !p.IsConfigured(p.config) {
+		return nil, newNotConfiguredError(p.Name(), "OpenAI API key not configured. Please set OPENAI_API_KEY environment variable or use 'please set openai key'")
+	}
+
+	return withTextFallback(p.config, p.Name(), request, func(structured bool) (*types.ScriptResponse, error) {
+		return p.generateScriptStream(ctx, request, onLine, structured)
+	})
+}
+
+// generateScriptStream makes one streaming chat completions call, asking for a structured response when structured is set
+func (p *OpenAIProvider) generateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string), structured bool) (*types.ScriptResponse, error) {
+	req, model, err := p.newChatRequest(request, true, structured)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := newRetryingClient(p.Name(), p.config).Do(ctx, req)
+	if err != nil {
+		if IsCancelled(err) {
+			return nil, err
+		}
+		return nil, newConnectionError(p.Name(), model, "failed to connect to OpenAI API", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, newStatusError(p.Name(), model, resp.StatusCode, body)
+	}
+
+	stream := newResponseStream(newLineCleaner(onLine), structured)
+	usage, err := readOpenAIStream(resp.Body, stream, p.Name(), model)
+	if err != nil {
+		return nil, cancelledOr(ctx, p.Name(), err)
+	}
+
+	response := &types.ScriptResponse{
+		Model:           model,
+		Provider:        request.Provider,
+		TaskDescription: request.TaskDescription,
+		ScriptType:      request.ScriptType,
+		Usage:           usage,
 	}
+	stream.Finish(response)
+	return response, nil
+}
+
+// newChatRequest builds the chat completions HTTP request and returns it with the resolved model
+func (p *OpenAIProvider) newChatRequest(request *types.ScriptRequest, stream, structured bool) (*http.Request, string, error) {
+	chat := createChatPrompt(request, structured)
 
-	prompt := CreatePrompt(request.TaskDescription, request.ScriptType)
-	
 	// Determine the model to use
 	model := request.Model
 	if model == "" {
@@ -47,34 +153,57 @@ func (p *OpenAIProvider) GenerateScript(request *types.ScriptRequest) (*types.Sc
 	}
 
 	openaiRequest := types.OpenAIRequest{
-		Model: model,
-		Messages: []types.Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		Temperature: 0.3,
-		MaxTokens:   2000,
+		Model:    model,
+		Messages: chat.WithSystemMessage(),
+		Stream:   stream,
+	}
+	if isOpenAIReasoningModel(model) {
+		// Reasoning models only take the default temperature, and their hidden reasoning
+		// counts against the completion budget
+		openaiRequest.MaxCompletionTokens = 16000
+	} else {
+		openaiRequest.Temperature = 0.3
+		openaiRequest.MaxTokens = 2000
+	}
+	if stream {
+		openaiRequest.StreamOptions = &types.StreamOptions{IncludeUsage: true}
+	}
+	if structured {
+		openaiRequest.ResponseFormat = openAIResponseFormat()
 	}
 
 	jsonData, err := json.Marshal(openaiRequest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+		return nil, "", fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", p.baseURL+"/v1/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, "", fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+p.config.OpenAIAPIKey)
 
-	client := &http.Client{Timeout: 120 * time.Second}
+	return req, model, nil
+}
+
+// GetAvailableModels queries OpenAI's /v1/models endpoint for the chat models the key can use
+func (p *OpenAIProvider) GetAvailableModels() ([]types.ModelInfo, error) {
+	if !p.IsConfigured(p.config) {
+		return nil, newNotConfiguredError(p.Name(), "OpenAI API key not configured. Please set OPENAI_API_KEY environment variable or use 'please set openai key'")
+	}
+
+	req, err := http.NewRequest("GET", p.baseURL+"/v1/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.OpenAIAPIKey)
+
+	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to OpenAI API: %v", err)
+		return nil, newConnectionError(p.Name(), "", "failed to connect to OpenAI API", err)
 	}
 	defer resp.Body.Close()
 
@@ -84,43 +213,54 @@ func (p *OpenAIProvider) GenerateScript(request *types.ScriptRequest) (*types.Sc
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAI API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newStatusError(p.Name(), "", resp.StatusCode, body)
 	}
 
-	var openaiResp types.OpenAIResponse
-	if err := json.Unmarshal(body, &openaiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+	var modelsResp types.OpenAIModelsResponse
+	if err := json.Unmarshal(body, &modelsResp); err != nil {
+		return nil, fmt.Errorf("failed to parse models response: %v", err)
 	}
 
-	if len(openaiResp.Choices) == 0 {
-		return nil, fmt.Errorf("no response choices returned from OpenAI")
+	var models []types.ModelInfo
+	for _, model := range modelsResp.Data {
+		if !isOpenAIChatModel(model.ID) {
+			continue
+		}
+		info := types.ModelInfo{Name: model.ID, ModifiedAt: time.Unix(model.Created, 0)}
+		info.Details.Family = model.OwnedBy
+		models = append(models, info)
 	}
+	return models, nil
+}
 
-	script := strings.TrimSpace(openaiResp.Choices[0].Message.Content)
-	cleanedScript := cleanScript(script)
-
-	return &types.ScriptResponse{
-		Script:          cleanedScript,
-		Model:           model,
-		Provider:        request.Provider,
-		TaskDescription: request.TaskDescription,
-		ScriptType:      request.ScriptType,
-	}, nil
+// isOpenAIChatModel filters /v1/models down to models usable with chat completions,
+// skipping embeddings, audio, image, moderation and realtime models
+func isOpenAIChatModel(id string) bool {
+	if !strings.HasPrefix(id, "gpt-") && !strings.HasPrefix(id, "chatgpt-") && !strings.HasPrefix(id, "o1") &&
+		!strings.HasPrefix(id, "o3") && !strings.HasPrefix(id, "o4") {
+		return false
+	}
+	for _, excluded := range []string{"audio", "realtime", "transcribe", "tts", "image", "search", "instruct"} {
+		if strings.Contains(id, excluded) {
+			return false
+		}
+	}
+	return true
 }
 
-// GetAvailableModels returns the list of available OpenAI models
-func (p *OpenAIProvider) GetAvailableModels() []string {
-	return []string{
-		"gpt-4",
-		"gpt-4-turbo",
-		"gpt-4-turbo-preview", 
-		"gpt-3.5-turbo",
-		"gpt-3.5-turbo-16k",
+// isOpenAIReasoningModel reports whether id is an o-series or GPT-5 reasoning model, which reject
+// temperature and max_tokens with a 400
+func isOpenAIReasoningModel(id string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
 	}
+	return false
 }
 
 // getDefaultModel returns the default model for OpenAI
 func (p *OpenAIProvider) getDefaultModel() string {
-	// GPT-3.5-turbo is more cost-effective for script generation
-	return "gpt-3.5-turbo"
+	// GPT-4o-mini is the most cost-effective current model for script generation
+	return "gpt-4o-mini"
 }
@@ -1,14 +1,19 @@
 package providers
 
 import (
-	"fmt"
+	"context"
+	"strings"
+	"time"
+
+	"please/prompts"
 	"please/types"
 )
 
 // Provider defines the interface for AI providers
 type Provider interface {
-	// GenerateScript generates a script using the provider's AI service
-	GenerateScript(request *types.ScriptRequest) (*types.ScriptResponse, error)
+	// GenerateScript generates a script using the provider's AI service.
+	// Cancelling ctx aborts the call and returns a *CancelledError.
+	GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error)
 
 	// Name returns the name of the provider
 	Name() string
@@ -17,78 +22,107 @@ type Provider interface {
 	IsConfigured(config *types.Config) bool
 }
 
-// CreatePrompt creates the appropriate prompt based on script type and task
+// CreatePrompt renders the generation prompt template for scriptType as a single prompt
 func CreatePrompt(taskDescription, scriptType string) string {
-	if scriptType == "bash" {
-		return `You are a Bash scripting expert. Generate a complete, working Bash script to accomplish the following task:
-
-` + taskDescription + `
-
-Requirements:
-- ***CRITICAL*** ANY POTENTIALLY DANGEROUS OR UNKNOWN COMMANDS SHOULD BE HIGHLIGHTED WITH COMMENTS EXPLAINING WHY THEY ARE DANGEROUS TO ENSURE THAT THEY ARE CHECKED BY THE USER BEFORE RUNNING
-- Write clean, well-commented Bash code
-- Include error handling where appropriate
-- Use Bash best practices
-- Include proper shebang (#!/bin/bash)
-- Do NOT include markdown code blocks, backticks, or formatting
-- Do NOT include explanations or descriptions
-- Return ONLY the raw Bash script code
-- The script should be ready to run as-is
-- Start directly with the shebang and Bash commands
-
-Bash Script:`
-	} else {
-		return `You are a PowerShell expert. Generate a complete, working PowerShell script to accomplish the following task:
-
-` + taskDescription + `
-
-Requirements:
-- ***CRITICAL*** ANY POTENTIALLY DANGEROUS OR UNKNOWN COMMANDS SHOULD BE HIGHLIGHTED WITH COMMENTS EXPLAINING WHY THEY ARE DANGEROUS TO ENSURE THAT THEY ARE CHECKED BY THE USER BEFORE RUNNING
-- Write clean, well-commented PowerShell code
-- Include error handling where appropriate
-- Use PowerShell best practices
-- Do NOT include markdown code blocks, backticks, or formatting
-- Do NOT include explanations or descriptions
-- Return ONLY the raw PowerShell script code
-- The script should be ready to run as-is
-- Start directly with PowerShell commands, no preamble
-
-PowerShell Script:`
+	parts, _ := prompts.RenderGenerate(prompts.Data{Task: taskDescription, ScriptType: scriptType})
+	return parts.String()
+}
+
+// ChatPrompt is a request's prompt arranged the way chat APIs take it
+type ChatPrompt struct {
+	System   string          // Fixed rules for every script, sent as the system prompt
+	Messages []types.Message // The request's history followed by the task as the final user turn
+}
+
+// CreateChatPrompt renders the generation template for request, putting the rules and the
+// environment context in the system prompt and the task in the user turn
+func CreateChatPrompt(request *types.ScriptRequest) ChatPrompt {
+	return createChatPrompt(request, false)
+}
+
+// createChatPrompt is CreateChatPrompt, asking for the structured response fields when structured is set
+func createChatPrompt(request *types.ScriptRequest, structured bool) ChatPrompt {
+	parts, _ := prompts.RenderGenerate(prompts.Data{
+		Task:        request.TaskDescription,
+		ScriptType:  request.ScriptType,
+		Environment: request.Environment,
+		Structured:  structured,
+	})
+
+	messages := make([]types.Message, 0, len(request.History)+1)
+	messages = append(messages, request.History...)
+	messages = append(messages, types.Message{Role: "user", Content: parts.User})
+	return ChatPrompt{System: parts.System, Messages: messages}
+}
+
+// WithSystemMessage returns the messages preceded by the system prompt, for APIs that take it as a role
+func (c ChatPrompt) WithSystemMessage() []types.Message {
+	if c.System == "" {
+		return c.Messages
 	}
+	return append([]types.Message{{Role: "system", Content: c.System}}, c.Messages...)
+}
+
+// CreateRequestPrompt flattens the chat prompt for request into one text, so that everything the
+// provider sees, history included, is part of the response cache key
+func CreateRequestPrompt(request *types.ScriptRequest) string {
+	chat := CreateChatPrompt(request)
+
+	sections := []string{}
+	if chat.System != "" {
+		sections = append(sections, chat.System)
+	}
+	for i, message := range chat.Messages {
+		if i == len(chat.Messages)-1 {
+			sections = append(sections, message.Content)
+		} else {
+			sections = append(sections, message.Role+": "+message.Content)
+		}
+	}
+	return strings.Join(sections, "\n\n")
 }
 
 // GenerateFixedScript generates a fixed script using the provider's AI service, given the original script and error message
 func GenerateFixedScript(originalScript, errorMessage, scriptType, model, provider string, config *types.Config) (string, error) {
-	// Debug: Print provider and config info
-	fmt.Printf("[DEBUG] GenerateFixedScript called with provider: %s, model: %s\n", provider, model)
-	fmt.Printf("[DEBUG] Config: Provider=%s, OpenAIKey='%s', OllamaURL='%s'\n", config.Provider, config.OpenAIAPIKey, config.OllamaURL)
+	return GenerateFixedScriptContext(context.Background(), originalScript, errorMessage, scriptType, model, provider, config)
+}
+
+// CreateFixPrompt composes a prompt for the LLM to fix the script based on the error
+func CreateFixPrompt(originalScript, errorMessage string) string {
+	prompt, _ := prompts.Render(prompts.Fix, prompts.Data{Script: originalScript, Error: errorMessage})
+	return prompt
+}
 
-	// Compose a prompt for the LLM to fix the script based on the error
-	prompt := "The following script failed with this error:\n\nScript:\n" + originalScript + "\n\nError:\n" + errorMessage + "\n\nPlease suggest a corrected version of the script. Return ONLY the fixed script, no explanations or markdown formatting."
+// GenerateFixedScriptContext is GenerateFixedScript with a context for cancellation
+func GenerateFixedScriptContext(ctx context.Context, originalScript, errorMessage, scriptType, model, provider string, config *types.Config) (string, error) {
+	resp, err := GenerateFixedResponseContext(ctx, originalScript, errorMessage, scriptType, model, provider, config)
+	if err != nil {
+		return "", err
+	}
+	return resp.Script, nil
+}
 
+// GenerateFixedResponseContext is GenerateFixedScriptContext returning the whole response, so that
+// the caller can account for its token usage. Usage.Latency is set to the time taken by the call.
+func GenerateFixedResponseContext(ctx context.Context, originalScript, errorMessage, scriptType, model, provider string, config *types.Config) (*types.ScriptResponse, error) {
 	request := &types.ScriptRequest{
-		TaskDescription: prompt,
+		TaskDescription: CreateFixPrompt(originalScript, errorMessage),
 		ScriptType:      scriptType,
 		Provider:        provider,
 		Model:           model,
 	}
 
-	// Select the correct provider based on the provider parameter, not hard-coded OpenAI
-	var providerInstance Provider
-	switch provider {
-	case "openai":
-		providerInstance = NewOpenAIProvider(config)
-	case "anthropic":
-		providerInstance = NewAnthropicProvider(config)
-	case "ollama":
-		providerInstance = NewOllamaProvider(config)
-	default:
-		return "", fmt.Errorf("unsupported provider: %s", provider)
+	// Redaction matters here: the error output may contain credentials or hostnames from the failed run
+	providerInstance, err := NewConfiguredProvider(provider, config)
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := providerInstance.GenerateScript(request)
+	start := time.Now()
+	resp, err := providerInstance.GenerateScript(ctx, request)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return resp.Script, nil
+	resp.Usage.Latency = time.Since(start)
+	return resp, nil
 }
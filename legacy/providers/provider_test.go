@@ -1,6 +1,10 @@
 package providers
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -54,20 +58,33 @@ func Test_when_creating_powershell_prompt_then_include_powershell_specific_eleme
 	}
 }
 
-func Test_when_creating_prompt_with_unknown_script_type_then_default_to_powershell(t *testing.T) {
+func Test_when_creating_prompt_with_other_script_type_then_use_generic_template_for_that_language(t *testing.T) {
 	// Arrange
 	taskDescription := "do something"
-	scriptType := "unknown"
+	scriptType := "python"
 
 	// Act
 	result := CreatePrompt(taskDescription, scriptType)
 
 	// Assert
-	if !strings.Contains(result, "PowerShell expert") {
-		t.Error("Expected unknown script type to default to PowerShell")
+	if strings.Contains(result, "PowerShell") {
+		t.Error("Expected other script types not to be treated as PowerShell")
 	}
-	if !strings.Contains(result, "PowerShell Script:") {
-		t.Error("Expected unknown script type to end with 'PowerShell Script:'")
+	if !strings.Contains(result, "Python expert") || !strings.HasSuffix(result, "Python Script:") {
+		t.Errorf("Expected a Python prompt, got:\n%s", result)
+	}
+}
+
+func Test_when_creating_analysis_prompt_then_send_task_unchanged(t *testing.T) {
+	// Arrange
+	analysis := "Analyze this test failure and reply with JSON"
+
+	// Act
+	result := CreatePrompt(analysis, "analysis")
+
+	// Assert
+	if result != analysis {
+		t.Errorf("Expected the analysis request as written, got:\n%s", result)
 	}
 }
 
@@ -156,7 +173,7 @@ type MockProvider struct {
 	IsConfiguredFunc   func(*types.Config) bool
 }
 
-func (m *MockProvider) GenerateScript(request *types.ScriptRequest) (*types.ScriptResponse, error) {
+func (m *MockProvider) GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error) {
 	if m.GenerateScriptFunc != nil {
 		return m.GenerateScriptFunc(request)
 	}
@@ -204,6 +221,19 @@ func Test_when_generating_fixed_script_with_unsupported_provider_then_return_err
 	}
 }
 
+func Test_when_generating_fixed_script_with_unconfigured_provider_then_return_not_configured(t *testing.T) {
+	// Arrange
+	config := &types.Config{Provider: "anthropic"}
+
+	// Act
+	_, err := GenerateFixedScript("echo hi", "command not found", "bash", "claude-sonnet-4", "anthropic", config)
+
+	// Assert
+	if ErrorKindOf(err) != ErrorNotConfigured {
+		t.Errorf("Expected not configured error, got: %v", err)
+	}
+}
+
 func Test_when_generating_fixed_script_then_include_original_script_and_error_in_prompt(t *testing.T) {
 	// Arrange
 	originalScript := "rm -rf /"
@@ -526,7 +556,7 @@ func Test_when_openai_provider_not_configured_then_return_error(t *testing.T) {
 	}
 
 	// Act
-	result, err := provider.GenerateScript(request)
+	result, err := provider.GenerateScript(context.Background(), request)
 
 	// Assert
 	if err == nil {
@@ -551,7 +581,7 @@ func Test_when_anthropic_provider_not_configured_then_return_error(t *testing.T)
 	}
 
 	// Act
-	result, err := provider.GenerateScript(request)
+	result, err := provider.GenerateScript(context.Background(), request)
 
 	// Assert
 	if err == nil {
@@ -565,56 +595,275 @@ func Test_when_anthropic_provider_not_configured_then_return_error(t *testing.T)
 	}
 }
 
-func Test_when_getting_openai_available_models_then_return_model_list(t *testing.T) {
+func Test_when_request_has_environment_then_include_it_before_requirements(t *testing.T) {
 	// Arrange
-	provider := NewOpenAIProvider(&types.Config{})
+	request := &types.ScriptRequest{
+		TaskDescription: "install jq",
+		ScriptType:      "bash",
+		Environment:     "- OS: Fedora Linux 40\n- Package manager: dnf",
+	}
 
 	// Act
-	models := provider.GetAvailableModels()
+	prompt := CreateRequestPrompt(request)
+	withoutEnvironment := CreateRequestPrompt(&types.ScriptRequest{TaskDescription: "install jq", ScriptType: "bash"})
 
 	// Assert
-	if len(models) == 0 {
-		t.Error("Expected non-empty list of available models")
-	}
-
-	// Check for expected models
-	expectedModels := []string{"gpt-4", "gpt-3.5-turbo"}
-	for _, expected := range expectedModels {
-		found := false
-		for _, model := range models {
-			if model == expected {
-				found = true
-				break
-			}
+	environment := strings.Index(prompt, "Package manager: dnf")
+	if environment < 0 || environment > strings.Index(prompt, "Requirements:") {
+		t.Errorf("Expected environment before the requirements, got:\n%s", prompt)
+	}
+	if withoutEnvironment != CreatePrompt("install jq", "bash") {
+		t.Error("Expected the plain prompt when there is no environment context")
+	}
+}
+
+func Test_when_sending_to_openai_then_put_rules_in_system_message_and_history_before_task(t *testing.T) {
+	// Arrange
+	var got types.OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ls -la"}}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&types.Config{OpenAIAPIKey: "test-key"})
+	provider.baseURL = server.URL
+	history := []types.Message{{Role: "user", Content: "list files"}, {Role: "assistant", Content: "ls"}}
+
+	// Act
+	_, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{
+		TaskDescription: "include hidden files",
+		ScriptType:      "bash",
+		History:         history,
+	})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	roles := []string{}
+	for _, message := range got.Messages {
+		roles = append(roles, message.Role)
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,user" {
+		t.Fatalf("Expected system, history and task messages, got roles %v", roles)
+	}
+	if !strings.Contains(got.Messages[0].Content, "Requirements:") || strings.Contains(got.Messages[3].Content, "Requirements:") {
+		t.Error("Expected the rules in the system message only")
+	}
+	if !strings.Contains(got.Messages[3].Content, "include hidden files") {
+		t.Errorf("Expected the task in the last user message, got %q", got.Messages[3].Content)
+	}
+}
+
+func Test_when_sending_to_openai_reasoning_model_then_omit_temperature_and_max_tokens(t *testing.T) {
+	// Arrange
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ls -la"}}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&types.Config{OpenAIAPIKey: "test-key"})
+	provider.baseURL = server.URL
+
+	// Act
+	for _, model := range []string{"o4-mini", "gpt-4.1"} {
+		if _, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "list", ScriptType: "bash", Model: model}); err != nil {
+			t.Fatalf("Expected no error for %s, got: %v", model, err)
+		}
+	}
+
+	// Assert
+	reasoning, chat := bodies[0], bodies[1]
+	if _, ok := reasoning["temperature"]; ok {
+		t.Error("Expected no temperature for a reasoning model")
+	}
+	if _, ok := reasoning["max_tokens"]; ok || reasoning["max_completion_tokens"] == nil {
+		t.Errorf("Expected max_completion_tokens instead of max_tokens, got %v", reasoning)
+	}
+	if chat["temperature"] == nil || chat["max_tokens"] == nil {
+		t.Errorf("Expected temperature and max_tokens for a chat model, got %v", chat)
+	}
+}
+
+func Test_when_sending_to_anthropic_then_use_top_level_system_field(t *testing.T) {
+	// Arrange
+	var got types.AnthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"content":[{"type":"text","text":"Get-ChildItem"}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&types.Config{AnthropicAPIKey: "test-key"})
+	provider.baseURL = server.URL
+
+	// Act
+	_, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "list files", ScriptType: "powershell"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(got.System, "PowerShell expert") {
+		t.Errorf("Expected the rules in the system field, got %q", got.System)
+	}
+	if len(got.Messages) != 1 || got.Messages[0].Role != "user" || strings.Contains(got.Messages[0].Content, "Requirements:") {
+		t.Errorf("Expected a single user message with the task, got %+v", got.Messages)
+	}
+}
+
+func Test_when_generating_with_ollama_then_use_chat_endpoint(t *testing.T) {
+	// Arrange
+	var got types.OllamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
 		}
-		if !found {
-			t.Errorf("Expected model '%s' not found in available models", expected)
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"message":{"role":"assistant","content":"#!/bin/bash\ndf -h"},"done":true,"prompt_eval_count":40,"eval_count":6}`))
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(&types.Config{OllamaURL: server.URL})
+
+	// Act
+	response, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "disk usage", ScriptType: "bash", Model: "llama3.2"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[1].Role != "user" {
+		t.Errorf("Expected system and user messages, got %+v", got.Messages)
+	}
+	if response.Script != "#!/bin/bash\ndf -h" || response.Usage.CompletionTokens != 6 {
+		t.Errorf("Unexpected response %+v", response)
+	}
+}
+
+func Test_when_getting_openai_available_models_then_return_model_list(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Unexpected request %s with auth %q", r.URL.Path, r.Header.Get("Authorization"))
 		}
+		w.Write([]byte(`{"data":[
+			{"id":"gpt-4o","created":1715367049,"owned_by":"system"},
+			{"id":"gpt-3.5-turbo","created":1677610602,"owned_by":"openai"},
+			{"id":"text-embedding-3-small","created":1705948997,"owned_by":"system"},
+			{"id":"gpt-4o-realtime-preview","created":1727659998,"owned_by":"system"}
+		]}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&types.Config{OpenAIAPIKey: "test-key"})
+	provider.baseURL = server.URL
+
+	// Act
+	models, err := provider.GetAvailableModels()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Only chat models should be returned
+	var names []string
+	for _, model := range models {
+		names = append(names, model.Name)
+	}
+	if strings.Join(names, ",") != "gpt-4o,gpt-3.5-turbo" {
+		t.Errorf("Expected chat models only, got %v", names)
+	}
+	if models[0].ModifiedAt.Unix() != 1715367049 {
+		t.Errorf("Expected created time to be kept, got %v", models[0].ModifiedAt)
 	}
 }
 
-func Test_when_getting_anthropic_available_models_then_return_model_list(t *testing.T) {
+func Test_when_getting_openai_models_without_key_then_return_not_configured(t *testing.T) {
 	// Arrange
-	provider := NewAnthropicProvider(&types.Config{})
+	provider := NewOpenAIProvider(&types.Config{})
 
 	// Act
-	models := provider.GetAvailableModels()
+	_, err := provider.GetAvailableModels()
 
 	// Assert
-	if len(models) == 0 {
-		t.Error("Expected non-empty list of available models")
+	if ErrorKindOf(err) != ErrorNotConfigured {
+		t.Errorf("Expected not configured error, got: %v", err)
 	}
+}
 
-	// Check for Claude models
-	found := false
+func Test_when_getting_anthropic_available_models_then_return_model_list(t *testing.T) {
+	// Arrange
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") == "" {
+			t.Error("Expected Anthropic auth headers")
+		}
+		if r.URL.Query().Get("after_id") == "" {
+			w.Write([]byte(`{"data":[{"id":"claude-sonnet-4-20250514","created_at":"2025-05-22T00:00:00Z"}],"has_more":true,"last_id":"claude-sonnet-4-20250514"}`))
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"claude-3-5-haiku-20241022","created_at":"2024-10-22T00:00:00Z"}],"has_more":false}`))
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&types.Config{AnthropicAPIKey: "test-key"})
+	provider.baseURL = server.URL
+
+	// Act
+	models, err := provider.GetAvailableModels()
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if requests != 2 || len(models) != 2 {
+		t.Fatalf("Expected both pages to be read, got %d models in %d requests", len(models), requests)
+	}
 	for _, model := range models {
-		if strings.Contains(model, "claude") {
-			found = true
-			break
+		if !strings.Contains(model.Name, "claude") {
+			t.Errorf("Expected Claude models, got %s", model.Name)
+		}
+	}
+}
+
+func Test_when_getting_ollama_models_then_include_loaded_state_and_show_details(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"llama3.1:8b","size":4920753328},{"name":"qwen3-coder:30b","size":18556688736}]}`))
+		case "/api/ps":
+			w.Write([]byte(`{"models":[{"name":"llama3.1:8b","size":6654289920,"size_vram":6654289920}]}`))
+		case "/api/show":
+			w.Write([]byte(`{"details":{"parameter_size":"8.0B","quantization_level":"Q4_K_M"},"model_info":{"llama.context_length":131072}}`))
+		default:
+			http.NotFound(w, r)
 		}
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(&types.Config{OllamaURL: server.URL})
+
+	// Act
+	models, err := provider.GetAvailableModels()
+
+	// Assert
+	if err != nil || len(models) != 2 {
+		t.Fatalf("Expected two models, got %d (%v)", len(models), err)
+	}
+	if !models[0].Loaded || models[1].Loaded {
+		t.Errorf("Expected only llama3.1:8b to be loaded, got %+v", models)
 	}
-	if !found {
-		t.Error("Expected at least one Claude model in Anthropic available models")
+	if models[0].ContextLength != 131072 || models[0].Details.QuantizationLevel != "Q4_K_M" {
+		t.Errorf("Expected details from /api/show, got %+v", models[0])
 	}
 }
 
@@ -651,7 +900,7 @@ func Test_when_testing_provider_interface_implementation_then_satisfy_interface(
 				ScriptType:      "bash",
 				Provider:        name,
 			}
-			_, err := provider.GenerateScript(request)
+			_, err := provider.GenerateScript(context.Background(), request)
 			// We expect an error due to invalid config/network, but method should exist
 			if err == nil {
 				t.Logf("Unexpected success from %s provider", name)
@@ -0,0 +1,284 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"please/types"
+)
+
+// Kinds of sensitive data the redactor recognises, as used in types.RedactionConfig.Exclude
+const (
+	RedactPrivateKey = "private_key"
+	RedactPassword   = "password"
+	RedactAPIKey     = "api_key"
+	RedactToken      = "token"
+	RedactEmail      = "email"
+	RedactIP         = "ip"
+)
+
+// placeholderPrefix starts every placeholder; placeholders look like identifiers so models keep them intact
+const placeholderPrefix = "REDACTED_"
+
+// placeholderNote tells the model to copy placeholders through unchanged
+const placeholderNote = "\n\nValues written as " + placeholderPrefix + "<KIND>_<N> are placeholders for private data; use them exactly as written."
+
+// secretDetector finds one kind of sensitive value. When group is non-zero only that submatch is
+// replaced, so "password=hunter2" keeps its key and only the value is hidden.
+type secretDetector struct {
+	kind    string
+	pattern *regexp.Regexp
+	group   int
+	accept  func(value string) bool
+}
+
+// secretDetectors run in order, most specific first, so a key inside a URL is not also taken for a password
+var secretDetectors = []secretDetector{
+	{kind: RedactPrivateKey, pattern: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)},
+	{kind: RedactPassword, pattern: regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^\s:/@]+:([^\s@/]+)@`), group: 1},
+	{kind: RedactAPIKey, pattern: regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{20,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_-]{35}|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|glpat-[A-Za-z0-9_-]{20,}|xox[abprs]-[A-Za-z0-9-]{10,})`)},
+	{kind: RedactToken, pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{kind: RedactToken, pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{16,}=*)`), group: 1},
+	{kind: RedactPassword, pattern: regexp.MustCompile(`(?i)\b[A-Za-z0-9_-]*(?:password|passwd|pwd|secret|token|api[_-]?key)[A-Za-z0-9_-]*["']?\s*[:=]\s*["']?([^\s"',;]{4,})`), group: 1},
+	{kind: RedactEmail, pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{kind: RedactIP, pattern: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), accept: isPrivateAddress},
+	{kind: RedactIP, pattern: regexp.MustCompile(`(?i)\b[0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}\b`), accept: isPrivateAddress},
+}
+
+// isPrivateAddress accepts addresses that identify a machine; loopback and unspecified
+// addresses such as 127.0.0.1 or 0.0.0.0 reveal nothing and are left for the model to see
+func isPrivateAddress(value string) bool {
+	ip := net.ParseIP(value)
+	return ip != nil && !ip.IsLoopback() && !ip.IsUnspecified()
+}
+
+// Redactor replaces sensitive values with placeholders and restores them afterwards. The same
+// value always maps to the same placeholder, so a script can use it consistently.
+type Redactor struct {
+	exclude      map[string]bool
+	placeholders map[string]string // value -> placeholder
+	values       map[string]string // placeholder -> value
+	counts       map[string]int
+}
+
+// NewRedactor creates a redactor that leaves the excluded kinds untouched
+func NewRedactor(exclude []string) *Redactor {
+	r := &Redactor{
+		exclude:      make(map[string]bool, len(exclude)),
+		placeholders: make(map[string]string),
+		values:       make(map[string]string),
+		counts:       make(map[string]int),
+	}
+	for _, kind := range exclude {
+		r.exclude[kind] = true
+	}
+	return r
+}
+
+// Redact returns text with every detected secret replaced by a placeholder
+func (r *Redactor) Redact(text string) string {
+	for _, detector := range secretDetectors {
+		if r.exclude[detector.kind] {
+			continue
+		}
+		text = r.apply(detector, text)
+	}
+	return text
+}
+
+// apply replaces the matches of one detector
+func (r *Redactor) apply(detector secretDetector, text string) string {
+	matches := detector.pattern.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+
+	var result strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[2*detector.group], match[2*detector.group+1]
+		value := text[start:end]
+		if strings.HasPrefix(value, placeholderPrefix) || (detector.accept != nil && !detector.accept(value)) {
+			continue
+		}
+		result.WriteString(text[last:start])
+		result.WriteString(r.placeholder(detector.kind, value))
+		last = end
+	}
+	result.WriteString(text[last:])
+	return result.String()
+}
+
+// placeholder returns the placeholder for value, allocating one on first sight
+func (r *Redactor) placeholder(kind, value string) string {
+	if placeholder, ok := r.placeholders[value]; ok {
+		return placeholder
+	}
+	r.counts[kind]++
+	placeholder := fmt.Sprintf("%s%s_%d", placeholderPrefix, strings.ToUpper(kind), r.counts[kind])
+	r.placeholders[value] = placeholder
+	r.values[placeholder] = value
+	return placeholder
+}
+
+// Redacted reports how many distinct values have been replaced
+func (r *Redactor) Redacted() int {
+	return len(r.values)
+}
+
+// Restore puts the original values back in place of their placeholders
+func (r *Redactor) Restore(text string) string {
+	if len(r.values) == 0 {
+		return text
+	}
+
+	// Longest placeholders first, so REDACTED_IP_1 does not eat the start of REDACTED_IP_12
+	placeholders := make([]string, 0, len(r.values))
+	for placeholder := range r.values {
+		placeholders = append(placeholders, placeholder)
+	}
+	sort.Slice(placeholders, func(i, j int) bool {
+		if len(placeholders[i]) != len(placeholders[j]) {
+			return len(placeholders[i]) > len(placeholders[j])
+		}
+		return placeholders[i] < placeholders[j]
+	})
+
+	pairs := make([]string, 0, 2*len(placeholders))
+	for _, placeholder := range placeholders {
+		pairs = append(pairs, placeholder, r.values[placeholder])
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// ShouldRedact applies the redaction policy: config.Redaction.Providers decides when it names the
+// provider, otherwise everything sent off the machine is redacted and local providers are not
+func ShouldRedact(name string, config *types.Config) bool {
+	if config == nil {
+		return !isLocalProvider(name, config)
+	}
+	if config.Redaction.Disabled {
+		return false
+	}
+	if enabled, ok := config.Redaction.Providers[name]; ok {
+		return enabled
+	}
+	return !isLocalProvider(name, config)
+}
+
+// isLocalProvider reports whether prompts for the provider stay on this machine
+func isLocalProvider(name string, config *types.Config) bool {
+	switch name {
+	case "mock", "replay":
+		return true
+	case "ollama":
+		if config == nil || config.OllamaURL == "" {
+			return true
+		}
+		return isLoopbackURL(config.OllamaURL)
+	}
+
+	if config != nil {
+		if providerConfig, exists := config.CustomProviders[name]; exists {
+			return isLoopbackURL(providerConfig.URL)
+		}
+	}
+	return false
+}
+
+// isLoopbackURL reports whether rawURL points at this machine
+func isLoopbackURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// WithRedaction wraps provider so that requests are redacted according to the policy; providers
+// that need no redaction are returned unchanged
+func WithRedaction(provider Provider, config *types.Config) Provider {
+	if !ShouldRedact(provider.Name(), config) {
+		return provider
+	}
+	var exclude []string
+	if config != nil {
+		exclude = config.Redaction.Exclude
+	}
+	return &redactingProvider{Provider: provider, exclude: exclude}
+}
+
+// redactingProvider masks secrets in each request and restores them in the generated script
+type redactingProvider struct {
+	Provider
+	exclude []string
+}
+
+// GenerateScript redacts the request, calls the wrapped provider and restores the response
+func (p *redactingProvider) GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error) {
+	redactor, redacted := p.redact(request)
+	response, err := p.Provider.GenerateScript(ctx, redacted)
+	if err != nil {
+		return nil, err
+	}
+	return restoreResponse(redactor, request, response), nil
+}
+
+// GenerateScriptStream streams through the wrapped provider, restoring each line before it is shown
+func (p *redactingProvider) GenerateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string)) (*types.ScriptResponse, error) {
+	redactor, redacted := p.redact(request)
+
+	var restoreLine func(line string)
+	if onLine != nil {
+		restoreLine = func(line string) {
+			onLine(redactor.Restore(line))
+		}
+	}
+
+	response, err := GenerateScriptStreaming(ctx, p.Provider, redacted, restoreLine)
+	if err != nil {
+		return nil, err
+	}
+	return restoreResponse(redactor, request, response), nil
+}
+
+// redact returns a fresh redactor and a copy of request with its free text masked
+func (p *redactingProvider) redact(request *types.ScriptRequest) (*Redactor, *types.ScriptRequest) {
+	redactor := NewRedactor(p.exclude)
+	redacted := *request
+	redacted.TaskDescription = redactor.Redact(request.TaskDescription)
+	redacted.Environment = redactor.Redact(request.Environment)
+	redacted.History = make([]types.Message, len(request.History))
+	for i, message := range request.History {
+		redacted.History[i] = types.Message{Role: message.Role, Content: redactor.Redact(message.Content)}
+	}
+	if redactor.Redacted() > 0 {
+		redacted.TaskDescription += placeholderNote
+	}
+	return redactor, &redacted
+}
+
+// restoreResponse puts the original values back into the script, the notes about it and the user's own task text
+func restoreResponse(redactor *Redactor, request *types.ScriptRequest, response *types.ScriptResponse) *types.ScriptResponse {
+	response.Script = redactor.Restore(response.Script)
+	response.Summary = redactor.Restore(response.Summary)
+	for i := range response.Explanation {
+		response.Explanation[i] = redactor.Restore(response.Explanation[i])
+	}
+	for i := range response.Risks {
+		response.Risks[i].Operation = redactor.Restore(response.Risks[i].Operation)
+		response.Risks[i].Reason = redactor.Restore(response.Risks[i].Reason)
+	}
+	response.TaskDescription = request.TaskDescription
+	return response
+}
@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+
+	"please/types"
+)
+
+// NewProvider creates the provider registered under the given name.
+// Built-in providers are matched first, then entries from config.CustomProviders,
+// which are served by a generic OpenAI-compatible chat provider.
+func NewProvider(name string, config *types.Config) (Provider, error) {
+	switch name {
+	case "ollama":
+		return NewOllamaProvider(config), nil
+	case "openai":
+		return NewOpenAIProvider(config), nil
+	case "anthropic":
+		return NewAnthropicProvider(config), nil
+	case "mock", "replay":
+		// Offline fixtures for tests and demos; deliberately not listed in BuiltinProviderNames
+		return NewReplayProvider(config), nil
+	}
+
+	if config != nil {
+		if providerConfig, exists := config.CustomProviders[name]; exists {
+			return NewCustomProvider(name, providerConfig, config), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported provider: %s", name)
+}
+
+// NewConfiguredProvider creates the named provider and verifies that it is properly configured.
+// Requests to providers that leave the machine are redacted according to config.Redaction.
+func NewConfiguredProvider(name string, config *types.Config) (Provider, error) {
+	provider, err := NewProvider(name, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if !provider.IsConfigured(config) {
+		return nil, newNotConfiguredError(name, fmt.Sprintf("provider %s is not properly configured", name))
+	}
+
+	return WithRedaction(provider, config), nil
+}
+
+// BuiltinProviderNames returns the names of the providers compiled into Please
+func BuiltinProviderNames() []string {
+	return []string{"ollama", "openai", "anthropic"}
+}
+
+// AvailableProviderNames returns the built-in provider names followed by any custom providers in sorted order
+func AvailableProviderNames(config *types.Config) []string {
+	names := BuiltinProviderNames()
+	if config == nil {
+		return names
+	}
+
+	custom := make([]string, 0, len(config.CustomProviders))
+	for name := range config.CustomProviders {
+		if !IsBuiltinProvider(name) {
+			custom = append(custom, name)
+		}
+	}
+	sort.Strings(custom)
+
+	return append(names, custom...)
+}
+
+// IsBuiltinProvider reports whether name refers to a compiled-in provider
+func IsBuiltinProvider(name string) bool {
+	for _, builtin := range BuiltinProviderNames() {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
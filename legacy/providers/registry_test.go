@@ -0,0 +1,203 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"please/types"
+)
+
+func Test_when_creating_builtin_provider_from_registry_then_return_matching_provider(t *testing.T) {
+	// Arrange
+	config := &types.Config{}
+
+	for _, name := range BuiltinProviderNames() {
+		// Act
+		provider, err := NewProvider(name, config)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("Expected no error for built-in provider %s, got: %v", name, err)
+		}
+		if provider.Name() != name {
+			t.Errorf("Expected provider name %s, got %s", name, provider.Name())
+		}
+	}
+}
+
+func Test_when_creating_unknown_provider_from_registry_then_return_unsupported_error(t *testing.T) {
+	// Arrange
+	config := &types.Config{CustomProviders: map[string]types.ProviderConfig{}}
+
+	// Act
+	_, err := NewProvider("does-not-exist", config)
+
+	// Assert
+	if err == nil || err.Error() != "unsupported provider: does-not-exist" {
+		t.Errorf("Expected unsupported provider error, got: %v", err)
+	}
+}
+
+func Test_when_creating_custom_provider_from_registry_then_use_custom_provider(t *testing.T) {
+	// Arrange
+	config := &types.Config{
+		CustomProviders: map[string]types.ProviderConfig{
+			"gateway": {URL: "http://localhost:4000", Model: "llama-3-70b"},
+		},
+	}
+
+	// Act
+	provider, err := NewProvider("gateway", config)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected custom provider to be created, got: %v", err)
+	}
+	if _, ok := provider.(*CustomProvider); !ok {
+		t.Errorf("Expected *CustomProvider, got %T", provider)
+	}
+	if provider.Name() != "gateway" {
+		t.Errorf("Expected provider name 'gateway', got %s", provider.Name())
+	}
+}
+
+func Test_when_custom_provider_has_no_url_then_configured_provider_returns_error(t *testing.T) {
+	// Arrange
+	config := &types.Config{
+		CustomProviders: map[string]types.ProviderConfig{
+			"gateway": {Model: "llama-3-70b"},
+		},
+	}
+
+	// Act
+	_, err := NewConfiguredProvider("gateway", config)
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "not properly configured") {
+		t.Errorf("Expected not configured error, got: %v", err)
+	}
+}
+
+func Test_when_listing_available_providers_then_append_sorted_custom_providers(t *testing.T) {
+	// Arrange
+	config := &types.Config{
+		CustomProviders: map[string]types.ProviderConfig{
+			"vllm":    {URL: "http://vllm:8000/v1"},
+			"litellm": {URL: "http://litellm:4000"},
+		},
+	}
+
+	// Act
+	names := AvailableProviderNames(config)
+
+	// Assert
+	expected := []string{"ollama", "openai", "anthropic", "litellm", "vllm"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func Test_when_resolving_custom_provider_url_then_target_chat_completions(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"http://localhost:8080", "http://localhost:8080/v1/chat/completions"},
+		{"http://localhost:8080/", "http://localhost:8080/v1/chat/completions"},
+		{"http://gateway/v1", "http://gateway/v1/chat/completions"},
+		{"http://gateway/openai/v1/chat/completions", "http://gateway/openai/v1/chat/completions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			provider := NewCustomProvider("custom", types.ProviderConfig{URL: tt.url}, nil)
+
+			if got := provider.ChatCompletionsURL(); got != tt.expected {
+				t.Errorf("ChatCompletionsURL() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_when_custom_provider_generates_script_then_honor_url_headers_and_key(t *testing.T) {
+	// Arrange
+	var gotAuth, gotTeam, gotPath string
+	var gotRequest types.OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotTeam = r.Header.Get("X-Team")
+		json.NewDecoder(r.Body).Decode(&gotRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + "```bash\\n#!/bin/bash\\necho hi\\n```" + `"}}]}`))
+	}))
+	defer server.Close()
+
+	config := &types.Config{
+		CustomProviders: map[string]types.ProviderConfig{
+			"gateway": {
+				URL:     server.URL + "/v1",
+				APIKey:  "secret-key",
+				Headers: map[string]string{"X-Team": "platform"},
+				Model:   "qwen2.5-coder",
+			},
+		},
+	}
+	provider, err := NewConfiguredProvider("gateway", config)
+	if err != nil {
+		t.Fatalf("Expected provider to be configured, got: %v", err)
+	}
+
+	// Act
+	response, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{
+		TaskDescription: "say hi",
+		ScriptType:      "bash",
+		Provider:        "gateway",
+	})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if gotPath != "/v1/chat/completions" {
+		t.Errorf("Expected request to /v1/chat/completions, got %s", gotPath)
+	}
+	if gotAuth != "Bearer secret-key" {
+		t.Errorf("Expected bearer API key, got %q", gotAuth)
+	}
+	if gotTeam != "platform" {
+		t.Errorf("Expected configured header to be sent, got %q", gotTeam)
+	}
+	if gotRequest.Model != "qwen2.5-coder" {
+		t.Errorf("Expected configured model to be used, got %s", gotRequest.Model)
+	}
+	if response.Script != "#!/bin/bash\necho hi" {
+		t.Errorf("Expected cleaned script, got %q", response.Script)
+	}
+	if response.Provider != "gateway" || response.Model != "qwen2.5-coder" {
+		t.Errorf("Expected gateway/qwen2.5-coder, got %s/%s", response.Provider, response.Model)
+	}
+}
+
+func Test_when_custom_provider_returns_error_status_then_include_provider_name(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream unavailable"))
+	}))
+	defer server.Close()
+
+	provider := NewCustomProvider("gateway", types.ProviderConfig{URL: server.URL}, &types.Config{HTTP: types.HTTPConfig{MaxRetries: -1}})
+
+	// Act
+	_, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "x", ScriptType: "bash"})
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "gateway API returned status 502") {
+		t.Errorf("Expected gateway status error, got: %v", err)
+	}
+}
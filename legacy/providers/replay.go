@@ -0,0 +1,231 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"please/types"
+)
+
+// ReplayProvider is the offline "mock" provider. It answers from JSON fixtures and, when a
+// record provider is configured, fills in missing fixtures from that real provider.
+type ReplayProvider struct {
+	config *types.Config
+}
+
+// Fixture is a recorded script response stored as one JSON file in the fixtures directory.
+// A fixture matches a request by PromptHash, or by TaskDescription (case and whitespace
+// insensitive) with an optional ScriptType.
+type Fixture struct {
+	TaskDescription string `json:"task_description"`
+	ScriptType      string `json:"script_type,omitempty"`
+	PromptHash      string `json:"prompt_hash,omitempty"`
+	Script          string `json:"script"`
+	Model           string `json:"model,omitempty"`
+	RecordedFrom    string `json:"recorded_from,omitempty"`
+}
+
+// NewReplayProvider creates a new replay provider
+func NewReplayProvider(config *types.Config) *ReplayProvider {
+	return &ReplayProvider{config: config}
+}
+
+// Name returns the provider name
+func (p *ReplayProvider) Name() string {
+	return "mock"
+}
+
+// IsConfigured always succeeds; missing fixtures are reported when generating
+func (p *ReplayProvider) IsConfigured(config *types.Config) bool {
+	return true
+}
+
+// GenerateScript returns the fixture for the request, recording it first if needed
+func (p *ReplayProvider) GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error) {
+	dir := p.FixturesDir()
+
+	fixture, err := FindFixture(dir, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if fixture == nil {
+		fixture, err = p.record(ctx, dir, request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	model := fixture.Model
+	if model == "" {
+		model = request.Model
+	}
+
+	return &types.ScriptResponse{
+		Script:          fixture.Script,
+		Model:           model,
+		Provider:        p.Name(),
+		TaskDescription: request.TaskDescription,
+		ScriptType:      request.ScriptType,
+	}, nil
+}
+
+// record asks the configured record provider for a script and saves it as a new fixture
+func (p *ReplayProvider) record(ctx context.Context, dir string, request *types.ScriptRequest) (*Fixture, error) {
+	recordProvider := p.recordProvider()
+	if recordProvider == "" {
+		return nil, newNotConfiguredError(p.Name(), fmt.Sprintf("no fixture in %s for task %q; set PLEASE_RECORD_PROVIDER to record one", dir, request.TaskDescription))
+	}
+	if recordProvider == p.Name() {
+		return nil, newNotConfiguredError(p.Name(), "PLEASE_RECORD_PROVIDER must name a real provider, not mock")
+	}
+
+	provider, err := NewConfiguredProvider(recordProvider, p.config)
+	if err != nil {
+		return nil, err
+	}
+
+	recordRequest := *request
+	recordRequest.Provider = recordProvider
+	response, err := provider.GenerateScript(ctx, &recordRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := &Fixture{
+		TaskDescription: request.TaskDescription,
+		ScriptType:      request.ScriptType,
+		PromptHash:      PromptHash(request),
+		Script:          response.Script,
+		Model:           response.Model,
+		RecordedFrom:    response.Provider,
+	}
+	if err := SaveFixture(dir, fixture); err != nil {
+		return nil, err
+	}
+	return fixture, nil
+}
+
+// FixturesDir returns PLEASE_FIXTURES_DIR, the configured fixtures_dir, or the default
+// "fixtures" folder next to the config file
+func (p *ReplayProvider) FixturesDir() string {
+	if dir := os.Getenv("PLEASE_FIXTURES_DIR"); dir != "" {
+		return dir
+	}
+	if p.config != nil && p.config.FixturesDir != "" {
+		return p.config.FixturesDir
+	}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(configDir, "please", "fixtures")
+	}
+	return "fixtures"
+}
+
+// recordProvider returns the provider used to record missing fixtures, or "" for replay only
+func (p *ReplayProvider) recordProvider() string {
+	if provider := os.Getenv("PLEASE_RECORD_PROVIDER"); provider != "" {
+		return provider
+	}
+	if p.config != nil {
+		return p.config.RecordProvider
+	}
+	return ""
+}
+
+// PromptHash identifies a request by the exact prompt that would be sent to a provider
+func PromptHash(request *types.ScriptRequest) string {
+	// Environment context is left out so fixtures replay on any machine
+	sum := sha256.Sum256([]byte(CreatePrompt(request.TaskDescription, request.ScriptType)))
+	return hex.EncodeToString(sum[:])
+}
+
+// FindFixture returns the fixture in dir matching request, or nil if there is none.
+// Files are read in name order; a prompt hash or exact script type match wins over a
+// fixture that leaves the script type open.
+func FindFixture(dir string, request *types.ScriptRequest) (*Fixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fixtures: %v", err)
+	}
+	sort.Strings(paths)
+
+	hash := PromptHash(request)
+	task := normalizeTask(request.TaskDescription)
+
+	var candidate *Fixture
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture %s: %v", path, err)
+		}
+
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("failed to parse fixture %s: %v", path, err)
+		}
+
+		if fixture.PromptHash == hash {
+			return &fixture, nil
+		}
+		if normalizeTask(fixture.TaskDescription) != task {
+			continue
+		}
+		if fixture.ScriptType == request.ScriptType {
+			return &fixture, nil
+		}
+		if fixture.ScriptType == "" && candidate == nil {
+			candidate = &fixture
+		}
+	}
+
+	return candidate, nil
+}
+
+// SaveFixture writes fixture into dir under a readable name derived from its task
+func SaveFixture(dir string, fixture *Fixture) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create fixtures directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fixture: %v", err)
+	}
+
+	name := fixtureSlug(fixture.TaskDescription)
+	if fixture.PromptHash != "" {
+		name += "-" + fixture.PromptHash[:8]
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write fixture: %v", err)
+	}
+	return nil
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// fixtureSlug turns a task description into a short file-name-safe slug
+func fixtureSlug(taskDescription string) string {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(taskDescription), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	if slug == "" {
+		slug = "fixture"
+	}
+	return slug
+}
+
+// normalizeTask compares task descriptions case-insensitively with collapsed whitespace
+func normalizeTask(taskDescription string) string {
+	return strings.Join(strings.Fields(strings.ToLower(taskDescription)), " ")
+}
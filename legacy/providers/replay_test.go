@@ -0,0 +1,95 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"please/types"
+)
+
+func Test_when_fixture_matches_task_then_replay_script(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	SaveFixture(dir, &Fixture{TaskDescription: "List Files", Script: "ls", Model: "recorded-model"})
+	provider := NewReplayProvider(&types.Config{FixturesDir: dir})
+
+	// Act
+	response, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{
+		TaskDescription: "  list   files ",
+		ScriptType:      "bash",
+	})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if response.Script != "ls" || response.Model != "recorded-model" || response.Provider != "mock" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
+
+func Test_when_fixture_script_type_matches_exactly_then_prefer_it(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	SaveFixture(dir, &Fixture{TaskDescription: "show date", Script: "date"})
+	SaveFixture(dir, &Fixture{TaskDescription: "show date", ScriptType: "powershell", Script: "Get-Date"})
+
+	// Act
+	fixture, err := FindFixture(dir, &types.ScriptRequest{TaskDescription: "show date", ScriptType: "powershell"})
+
+	// Assert
+	if err != nil || fixture == nil || fixture.Script != "Get-Date" {
+		t.Errorf("Expected powershell fixture, got %+v (%v)", fixture, err)
+	}
+}
+
+func Test_when_fixture_is_missing_and_not_recording_then_return_not_configured(t *testing.T) {
+	// Arrange
+	t.Setenv("PLEASE_RECORD_PROVIDER", "")
+	provider := NewReplayProvider(&types.Config{FixturesDir: t.TempDir()})
+
+	// Act
+	_, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "unknown"})
+
+	// Assert
+	if ErrorKindOf(err) != ErrorNotConfigured {
+		t.Errorf("Expected not configured error, got: %v", err)
+	}
+}
+
+func Test_when_recording_then_save_fixture_and_replay_offline(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"df -h"}}]}`))
+	}))
+
+	dir := t.TempDir()
+	config := &types.Config{
+		FixturesDir:    dir,
+		RecordProvider: "gateway",
+		CustomProviders: map[string]types.ProviderConfig{
+			"gateway": {URL: server.URL, Model: "live-model"},
+		},
+	}
+	request := &types.ScriptRequest{TaskDescription: "disk usage", ScriptType: "bash", Provider: "mock"}
+
+	// Act
+	recorded, err := NewReplayProvider(config).GenerateScript(context.Background(), request)
+	server.Close()
+	config.RecordProvider = ""
+	replayed, replayErr := NewReplayProvider(config).GenerateScript(context.Background(), request)
+
+	// Assert
+	if err != nil || replayErr != nil {
+		t.Fatalf("Expected record and replay to succeed, got: %v / %v", err, replayErr)
+	}
+	if recorded.Script != "df -h" || replayed.Script != "df -h" || replayed.Model != "live-model" {
+		t.Errorf("Expected recorded script to replay, got %+v then %+v", recorded, replayed)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "disk-usage-*.json")); len(files) != 1 {
+		t.Errorf("Expected one fixture file, got %v", files)
+	}
+}
@@ -0,0 +1,184 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"please/types"
+)
+
+// StreamingProvider is implemented by providers that can deliver the script while it is being generated
+type StreamingProvider interface {
+	Provider
+
+	// GenerateScriptStream generates a script, calling onLine with each cleaned line as soon as it is complete
+	GenerateScriptStream(ctx context.Context, request *types.ScriptRequest, onLine func(line string)) (*types.ScriptResponse, error)
+}
+
+// GenerateScriptStreaming streams the script through onLine when the provider supports it,
+// otherwise it falls back to GenerateScript and replays the finished script line by line
+// The response's Usage.Latency is set to the time taken by the provider call.
+func GenerateScriptStreaming(ctx context.Context, provider Provider, request *types.ScriptRequest, onLine func(line string)) (*types.ScriptResponse, error) {
+	start := time.Now()
+
+	if streamer, ok := provider.(StreamingProvider); ok {
+		response, err := streamer.GenerateScriptStream(ctx, request, onLine)
+		if err != nil {
+			return nil, err
+		}
+		response.Usage.Latency = time.Since(start)
+		return response, nil
+	}
+
+	response, err := provider.GenerateScript(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	response.Usage.Latency = time.Since(start)
+
+	if onLine != nil && response.Script != "" {
+		for _, line := range strings.Split(response.Script, "\n") {
+			onLine(line)
+		}
+	}
+
+	return response, nil
+}
+
+// lineCleaner applies the cleanScript rules incrementally to streamed text,
+// emitting each line once it is complete and not markdown or preamble noise
+type lineCleaner struct {
+	onLine  func(line string)
+	pending string
+	full    strings.Builder
+	emitted int
+}
+
+// newLineCleaner creates a lineCleaner that reports cleaned lines to onLine (which may be nil)
+func newLineCleaner(onLine func(line string)) *lineCleaner {
+	return &lineCleaner{onLine: onLine}
+}
+
+// Write consumes a streamed text fragment
+func (c *lineCleaner) Write(fragment string) {
+	c.full.WriteString(fragment)
+
+	lines := strings.Split(c.pending+fragment, "\n")
+	for _, line := range lines[:len(lines)-1] {
+		c.emit(strings.TrimRight(line, "\r"))
+	}
+	c.pending = lines[len(lines)-1]
+}
+
+// Flush emits any trailing partial line and returns the complete cleaned script
+func (c *lineCleaner) Flush() string {
+	if c.pending != "" {
+		c.emit(strings.TrimRightFunc(c.pending, unicode.IsSpace))
+		c.pending = ""
+	}
+	return cleanScript(strings.TrimSpace(c.full.String()))
+}
+
+// emit reports a complete line unless cleanScript would drop it
+func (c *lineCleaner) emit(line string) {
+	if isScriptNoiseLine(line) {
+		return
+	}
+	// cleanScript trims the whole response, so the first kept line loses its indentation
+	if c.emitted == 0 {
+		line = strings.TrimLeftFunc(line, unicode.IsSpace)
+	}
+	c.emitted++
+	if c.onLine != nil {
+		c.onLine(line)
+	}
+}
+
+// readServerSentEvents parses a text/event-stream body, calling handle with each event name and data payload
+func readServerSentEvents(body io.Reader, handle func(event, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			event = ""
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if err := handle(event, strings.TrimSpace(strings.TrimPrefix(line, "data:"))); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %v", err)
+	}
+	return nil
+}
+
+// errStreamDone stops stream parsing once the provider signals completion
+var errStreamDone = errors.New("stream done")
+
+// readOpenAIStream feeds OpenAI-compatible chat.completion.chunk deltas into the writer and
+// returns the token usage if the server sent a usage chunk. An error event, or a body that ends
+// before [DONE] or a finish_reason, is returned as a *ProviderError so that a truncated script is
+// never mistaken for a complete one.
+func readOpenAIStream(body io.Reader, writer fragmentWriter, provider, model string) (types.Usage, error) {
+	var usage types.Usage
+	finished := false
+	err := readServerSentEvents(body, func(event, data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+
+		var chunk types.OpenAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse stream chunk: %v", err)
+		}
+		if len(chunk.Error) > 0 && string(chunk.Error) != "null" {
+			code, message := parseErrorBody([]byte(data))
+			if message == "" {
+				message = string(chunk.Error)
+			}
+			return newStreamError(provider, model, code, message)
+		}
+
+		for _, choice := range chunk.Choices {
+			writer.Write(choice.Delta.Content)
+			if choice.FinishReason != "" {
+				finished = true
+			}
+		}
+		if chunk.Usage != nil {
+			usage = openAIUsage(chunk.Usage)
+		}
+		return nil
+	})
+
+	if errors.Is(err, errStreamDone) {
+		return usage, nil
+	}
+	if err == nil && !finished {
+		return usage, newTruncatedStreamError(provider, model)
+	}
+	return usage, err
+}
+
+// openAIUsage converts an OpenAI-compatible usage block, which may be absent
+func openAIUsage(usage *types.OpenAIUsage) types.Usage {
+	if usage == nil {
+		return types.Usage{}
+	}
+	return types.Usage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens}
+}
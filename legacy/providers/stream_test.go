@@ -0,0 +1,352 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"please/types"
+)
+
+func Test_when_cleaning_streamed_fragments_then_emit_clean_lines_as_they_complete(t *testing.T) {
+	// Arrange
+	var lines []string
+	cleaner := newLineCleaner(func(line string) { lines = append(lines, line) })
+	raw := "Here's a Bash script:\n```bash\n#!/bin/bash\necho \"hi\"\n\n```"
+
+	// Act - feed the text in small uneven fragments
+	for i := 0; i < len(raw); i += 3 {
+		end := i + 3
+		if end > len(raw) {
+			end = len(raw)
+		}
+		cleaner.Write(raw[i:end])
+	}
+	script := cleaner.Flush()
+
+	// Assert
+	expected := []string{"#!/bin/bash", `echo "hi"`}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected streamed lines %v, got %v", expected, lines)
+	}
+	if script != cleanScript(strings.TrimSpace(raw)) {
+		t.Errorf("Expected flushed script to match cleanScript output, got %q", script)
+	}
+}
+
+func Test_when_cleaning_stream_without_trailing_newline_then_flush_last_line(t *testing.T) {
+	// Arrange
+	var lines []string
+	cleaner := newLineCleaner(func(line string) { lines = append(lines, line) })
+
+	// Act
+	cleaner.Write("  Get-Process\nWrite-Host done  ")
+	cleaner.Flush()
+
+	// Assert
+	if len(lines) != 2 || lines[0] != "Get-Process" || lines[1] != "Write-Host done" {
+		t.Errorf("Expected trimmed first and last lines, got %q", lines)
+	}
+}
+
+func Test_when_provider_does_not_stream_then_replay_script_lines(t *testing.T) {
+	// Arrange
+	provider := &MockProvider{
+		GenerateScriptFunc: func(request *types.ScriptRequest) (*types.ScriptResponse, error) {
+			return &types.ScriptResponse{Script: "line one\nline two"}, nil
+		},
+	}
+	var lines []string
+
+	// Act
+	response, err := GenerateScriptStreaming(context.Background(), provider, &types.ScriptRequest{}, func(line string) { lines = append(lines, line) })
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(lines) != 2 || lines[1] != "line two" {
+		t.Errorf("Expected replayed lines, got %v", lines)
+	}
+	if response.Script != "line one\nline two" {
+		t.Errorf("Expected original script, got %q", response.Script)
+	}
+}
+
+func Test_when_streaming_from_ollama_then_parse_ndjson_chunks(t *testing.T) {
+	// Arrange
+	var gotRequest types.OllamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&gotRequest)
+		w.Write([]byte(`{"message":{"role":"assistant","content":"#!/bin/bash\necho "},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":"hello\nls"},"done":false}` + "\n"))
+		w.Write([]byte(`{"message":{"role":"assistant","content":""},"done":true}` + "\n"))
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(&types.Config{OllamaURL: server.URL})
+	var lines []string
+
+	// Act
+	response, err := provider.GenerateScriptStream(context.Background(), &types.ScriptRequest{
+		TaskDescription: "greet",
+		ScriptType:      "bash",
+		Provider:        "ollama",
+		Model:           "llama3.2",
+	}, func(line string) { lines = append(lines, line) })
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !gotRequest.Stream {
+		t.Error("Expected Ollama request to enable streaming")
+	}
+	if strings.Join(lines, "|") != "#!/bin/bash|echo hello|ls" {
+		t.Errorf("Unexpected streamed lines: %v", lines)
+	}
+	if response.Script != "#!/bin/bash\necho hello\nls" {
+		t.Errorf("Unexpected final script: %q", response.Script)
+	}
+}
+
+func Test_when_streaming_from_openai_then_parse_sse_deltas(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"```bash\\n#!/bin/bash\\n\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"df -h\\n```\"}}]}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&types.Config{OpenAIAPIKey: "test-key"})
+	provider.baseURL = server.URL
+	var lines []string
+
+	// Act
+	response, err := provider.GenerateScriptStream(context.Background(), &types.ScriptRequest{
+		TaskDescription: "disk usage",
+		ScriptType:      "bash",
+		Provider:        "openai",
+	}, func(line string) { lines = append(lines, line) })
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.Join(lines, "|") != "#!/bin/bash|df -h" {
+		t.Errorf("Unexpected streamed lines: %v", lines)
+	}
+	if response.Model != "gpt-4o-mini" {
+		t.Errorf("Expected default model, got %s", response.Model)
+	}
+}
+
+func Test_when_streaming_from_anthropic_then_parse_content_block_deltas(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\"}\n\n"))
+		w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Get-Date\\nWrite-\"}}\n\n"))
+		w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Host ok\"}}\n\n"))
+		w.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&types.Config{AnthropicAPIKey: "test-key"})
+	provider.baseURL = server.URL
+	var lines []string
+
+	// Act
+	response, err := provider.GenerateScriptStream(context.Background(), &types.ScriptRequest{
+		TaskDescription: "date",
+		ScriptType:      "powershell",
+		Provider:        "anthropic",
+	}, func(line string) { lines = append(lines, line) })
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.Join(lines, "|") != "Get-Date|Write-Host ok" {
+		t.Errorf("Unexpected streamed lines: %v", lines)
+	}
+	if response.Script != "Get-Date\nWrite-Host ok" {
+		t.Errorf("Unexpected final script: %q", response.Script)
+	}
+}
+
+func Test_when_anthropic_stream_reports_error_then_return_error(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"))
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&types.Config{AnthropicAPIKey: "test-key"})
+	provider.baseURL = server.URL
+
+	// Act
+	_, err := provider.GenerateScriptStream(context.Background(), &types.ScriptRequest{ScriptType: "bash"}, nil)
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Errorf("Expected overloaded error, got: %v", err)
+	}
+}
+
+func Test_when_ollama_stream_reports_error_then_return_provider_error(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":{"role":"assistant","content":"#!/bin/bash\n"},"done":false}` + "\n"))
+		w.Write([]byte(`{"error":"model runner has unexpectedly stopped"}` + "\n"))
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(&types.Config{OllamaURL: server.URL})
+
+	// Act
+	_, err := provider.GenerateScriptStream(context.Background(), &types.ScriptRequest{ScriptType: "bash", Model: "llama3.2"}, nil)
+
+	// Assert
+	if _, ok := AsProviderError(err); !ok || !strings.Contains(err.Error(), "unexpectedly stopped") {
+		t.Errorf("Expected provider error with Ollama's message, got: %v", err)
+	}
+}
+
+func Test_when_openai_stream_reports_error_event_then_return_provider_error(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"#!/bin/bash\\n\"}}]}\n\n"))
+		w.Write([]byte("data: {\"error\":{\"type\":\"rate_limit_error\",\"message\":\"Upstream rate limited\"}}\n\n"))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&types.Config{OpenAIAPIKey: "test-key"})
+	provider.baseURL = server.URL
+
+	// Act
+	_, err := provider.GenerateScriptStream(context.Background(), &types.ScriptRequest{ScriptType: "bash"}, nil)
+
+	// Assert
+	if ErrorKindOf(err) != ErrorRateLimited {
+		t.Errorf("Expected rate limited error, got: %v", err)
+	}
+}
+
+func Test_when_streams_end_before_completion_then_return_truncated_error(t *testing.T) {
+	// Arrange
+	ollamaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":{"role":"assistant","content":"rm -rf ./bu"},"done":false}` + "\n"))
+	}))
+	defer ollamaServer.Close()
+
+	openaiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"rm -rf ./bu\"}}]}\n\n"))
+	}))
+	defer openaiServer.Close()
+
+	anthropicServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"rm -rf ./bu\"}}\n\n"))
+	}))
+	defer anthropicServer.Close()
+
+	openai := NewOpenAIProvider(&types.Config{OpenAIAPIKey: "test-key"})
+	openai.baseURL = openaiServer.URL
+	anthropic := NewAnthropicProvider(&types.Config{AnthropicAPIKey: "test-key"})
+	anthropic.baseURL = anthropicServer.URL
+	streamers := []StreamingProvider{
+		NewOllamaProvider(&types.Config{OllamaURL: ollamaServer.URL}),
+		openai,
+		anthropic,
+	}
+
+	for _, provider := range streamers {
+		t.Run(provider.Name(), func(t *testing.T) {
+			// Act
+			response, err := provider.GenerateScriptStream(context.Background(), &types.ScriptRequest{ScriptType: "bash"}, nil)
+
+			// Assert
+			if ErrorKindOf(err) != ErrorInvalidResponse {
+				t.Errorf("Expected invalid response error, got: %v", err)
+			}
+			if response != nil {
+				t.Errorf("Expected no partial response, got %q", response.Script)
+			}
+		})
+	}
+}
+
+func Test_when_streams_report_usage_then_capture_token_counts(t *testing.T) {
+	// Arrange
+	anthropicServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":42,\"output_tokens\":1}}}\n\n"))
+		w.Write([]byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"ls\"}}\n\n"))
+		w.Write([]byte("event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":7}}\n\n"))
+		w.Write([]byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer anthropicServer.Close()
+
+	openaiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request types.OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.StreamOptions == nil || !request.StreamOptions.IncludeUsage {
+			t.Error("Expected OpenAI stream to request usage")
+		}
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"ls\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[],\"usage\":{\"prompt_tokens\":30,\"completion_tokens\":4}}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer openaiServer.Close()
+
+	ollamaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"response":"ls","done":false}` + "\n"))
+		w.Write([]byte(`{"response":"","done":true,"prompt_eval_count":25,"eval_count":3}` + "\n"))
+	}))
+	defer ollamaServer.Close()
+
+	anthropic := NewAnthropicProvider(&types.Config{AnthropicAPIKey: "test-key"})
+	anthropic.baseURL = anthropicServer.URL
+	openai := NewOpenAIProvider(&types.Config{OpenAIAPIKey: "test-key"})
+	openai.baseURL = openaiServer.URL
+	ollama := NewOllamaProvider(&types.Config{OllamaURL: ollamaServer.URL})
+	request := &types.ScriptRequest{TaskDescription: "list", ScriptType: "bash"}
+
+	tests := []struct {
+		provider   StreamingProvider
+		prompt     int
+		completion int
+	}{
+		{anthropic, 42, 7},
+		{openai, 30, 4},
+		{ollama, 25, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider.Name(), func(t *testing.T) {
+			// Act
+			response, err := GenerateScriptStreaming(context.Background(), tt.provider, request, nil)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if response.Usage.PromptTokens != tt.prompt || response.Usage.CompletionTokens != tt.completion {
+				t.Errorf("Expected %d/%d tokens, got %+v", tt.prompt, tt.completion, response.Usage)
+			}
+			if response.Usage.Latency <= 0 {
+				t.Error("Expected latency to be measured")
+			}
+		})
+	}
+}
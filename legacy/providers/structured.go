@@ -0,0 +1,358 @@
+package providers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"please/config"
+	"please/types"
+)
+
+// structuredToolName names the Anthropic tool the model is made to call with its answer
+const structuredToolName = "submit_script"
+
+// scriptSchema is the JSON schema of a structured script response. "script" comes first so that
+// it streams before the notes about it.
+var scriptSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"script": map[string]interface{}{
+			"type":        "string",
+			"description": "The complete script as raw code, without markdown fences",
+		},
+		"summary": map[string]interface{}{
+			"type":        "string",
+			"description": "One sentence describing what the script does",
+		},
+		"explanation": map[string]interface{}{
+			"type":        "array",
+			"description": "What the script does, one step per item, in order",
+			"items":       map[string]interface{}{"type": "string"},
+		},
+		"required_tools": map[string]interface{}{
+			"type":        "array",
+			"description": "Commands the script needs that may not be installed",
+			"items":       map[string]interface{}{"type": "string"},
+		},
+		"risks": map[string]interface{}{
+			"type":        "array",
+			"description": "Operations that delete, overwrite, need elevated rights or change the system",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"operation": map[string]interface{}{"type": "string"},
+					"reason":    map[string]interface{}{"type": "string"},
+				},
+				"required":             []string{"operation", "reason"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required":             []string{"script", "summary", "explanation", "required_tools", "risks"},
+	"additionalProperties": false,
+}
+
+// openAIResponseFormat asks OpenAI-compatible APIs for JSON that follows scriptSchema
+func openAIResponseFormat() *types.OpenAIResponseFormat {
+	return &types.OpenAIResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &types.OpenAIJSONSchema{Name: "script", Strict: true, Schema: scriptSchema},
+	}
+}
+
+// anthropicScriptTool is the tool Anthropic models are made to call, its input being the structured response
+func anthropicScriptTool() types.AnthropicTool {
+	return types.AnthropicTool{
+		Name:        structuredToolName,
+		Description: "Submit the generated script together with its explanation, required tools and risky operations",
+		InputSchema: scriptSchema,
+	}
+}
+
+// structuredScript is a structured response as the model returns it
+type structuredScript struct {
+	Script        string             `json:"script"`
+	Summary       string             `json:"summary"`
+	Explanation   []string           `json:"explanation"`
+	RequiredTools []string           `json:"required_tools"`
+	Risks         []types.ScriptRisk `json:"risks"`
+}
+
+// structuredResponses reports whether providers should ask for a structured response to request.
+// The schema describes a script, so other requests, like test failure analysis, get plain text.
+func structuredResponses(config *types.Config, request *types.ScriptRequest) bool {
+	if request.ScriptType != "bash" && request.ScriptType != "powershell" {
+		return false
+	}
+	return config == nil || config.ResponseFormat != types.ResponseFormatText
+}
+
+// withTextFallback calls generate asking for a structured response when request gets one, and
+// again for plain text if the API rejects the structured request, as APIs without JSON schema or
+// tool support do. Models that rejected it once are remembered and asked for plain text straight
+// away.
+func withTextFallback(config *types.Config, provider string, request *types.ScriptRequest, generate func(structured bool) (*types.ScriptResponse, error)) (*types.ScriptResponse, error) {
+	if !structuredResponses(config, request) || isUnstructuredModel(provider, request.Model) {
+		return generate(false)
+	}
+	response, err := generate(true)
+	if isStructuredRejection(err) {
+		rememberUnstructuredModel(provider, request.Model)
+		return generate(false)
+	}
+	return response, err
+}
+
+// isStructuredRejection reports whether err is the API refusing the response format or tool that
+// asks for a structured response, as opposed to any other invalid request
+func isStructuredRejection(err error) bool {
+	providerErr, ok := AsProviderError(err)
+	if !ok || providerErr.Kind != ErrorInvalidRequest {
+		return false
+	}
+
+	text := strings.ToLower(providerErr.Code + " " + providerErr.Message)
+	for _, marker := range []string{"response_format", "json_schema", "json_object", "structured", "schema", "tool"} {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// unstructuredModelsFileName holds, in the config directory, the models whose API rejected a
+// structured request
+const unstructuredModelsFileName = "unstructured_models.json"
+
+// unstructuredModelsPath returns the file remembering models without structured output, or "" if
+// there is no config directory
+func unstructuredModelsPath() string {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, unstructuredModelsFileName)
+}
+
+// loadUnstructuredModels reads the remembered "provider/model" keys; a missing file is an empty set
+func loadUnstructuredModels(path string) map[string]bool {
+	models := map[string]bool{}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &models)
+	}
+	return models
+}
+
+// isUnstructuredModel reports whether model has rejected a structured request before
+func isUnstructuredModel(provider, model string) bool {
+	path := unstructuredModelsPath()
+	return path != "" && loadUnstructuredModels(path)[provider+"/"+model]
+}
+
+// rememberUnstructuredModel records that model rejects structured requests; failures only cost a
+// rejected request next time
+func rememberUnstructuredModel(provider, model string) {
+	path := unstructuredModelsPath()
+	if path == "" {
+		return
+	}
+
+	models := loadUnstructuredModels(path)
+	models[provider+"/"+model] = true
+	data, err := json.MarshalIndent(models, "", "  ")
+	if err != nil {
+		return
+	}
+	if os.MkdirAll(filepath.Dir(path), 0755) == nil {
+		os.WriteFile(path, data, 0644)
+	}
+}
+
+// parseStructuredScript decodes a structured response, tolerating markdown fences around the JSON.
+// It fails for anything without a script, so models that answered in plain text take the text path.
+func parseStructuredScript(content string) (*structuredScript, bool) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, false
+	}
+
+	var parsed structuredScript
+	if err := json.Unmarshal([]byte(content[start:end+1]), &parsed); err != nil {
+		return nil, false
+	}
+	if strings.TrimSpace(parsed.Script) == "" {
+		return nil, false
+	}
+	return &parsed, true
+}
+
+// applyContent fills in response from the model's complete answer, using the structured fields
+// when the answer is a structured response and cleaning it as plain text otherwise
+func applyContent(response *types.ScriptResponse, content string, structured bool) {
+	if structured {
+		if parsed, ok := parseStructuredScript(content); ok {
+			applyStructured(response, parsed)
+			return
+		}
+	}
+	response.Script = cleanScript(strings.TrimSpace(content))
+}
+
+// applyStructured copies a parsed structured response into response
+func applyStructured(response *types.ScriptResponse, parsed *structuredScript) {
+	response.Script = cleanScript(strings.TrimSpace(parsed.Script))
+	response.Summary = strings.TrimSpace(parsed.Summary)
+	response.Explanation = parsed.Explanation
+	response.RequiredTools = parsed.RequiredTools
+	response.Risks = parsed.Risks
+	response.Structured = true
+}
+
+// fragmentWriter consumes streamed text as it arrives
+type fragmentWriter interface {
+	Write(fragment string)
+}
+
+// scriptFieldPattern finds where the "script" string value starts in a streamed JSON response
+var scriptFieldPattern = regexp.MustCompile(`"script"\s*:\s*"`)
+
+// responseStream turns streamed fragments into script lines. For a structured response it decodes
+// the "script" field as it arrives, so the script streams line by line just like plain text.
+type responseStream struct {
+	cleaner    *lineCleaner
+	structured bool
+	raw        strings.Builder
+	pos        int  // Next byte of raw to decode once inside the script field
+	inScript   bool // The opening quote of the script field has been read
+	done       bool // The closing quote of the script field has been read
+	wrote      bool // Some of the script field was passed to the cleaner
+}
+
+// newResponseStream creates a stream that reports cleaned lines through cleaner
+func newResponseStream(cleaner *lineCleaner, structured bool) *responseStream {
+	return &responseStream{cleaner: cleaner, structured: structured}
+}
+
+// Write consumes a streamed fragment
+func (s *responseStream) Write(fragment string) {
+	if !s.structured {
+		s.cleaner.Write(fragment)
+		return
+	}
+
+	s.raw.WriteString(fragment)
+	if s.done {
+		return
+	}
+
+	raw := s.raw.String()
+	if !s.inScript && isPlainText(raw) {
+		// The model ignored the requested format, so stream its answer as text
+		s.structured = false
+		s.cleaner.Write(raw)
+		return
+	}
+	if !s.inScript {
+		match := scriptFieldPattern.FindStringIndex(raw)
+		if match == nil {
+			return
+		}
+		s.inScript = true
+		s.pos = match[1]
+	}
+
+	decoded, consumed, closed := decodeJSONStringPrefix(raw[s.pos:])
+	s.pos += consumed
+	if decoded != "" {
+		s.cleaner.Write(decoded)
+		s.wrote = true
+	}
+	s.done = closed
+}
+
+// Finish completes response from everything streamed. A structured answer that cannot be parsed
+// is treated as plain text, and streamed now if none of it was shown yet.
+func (s *responseStream) Finish(response *types.ScriptResponse) {
+	if !s.structured {
+		response.Script = s.cleaner.Flush()
+		return
+	}
+
+	raw := s.raw.String()
+	parsed, ok := parseStructuredScript(raw)
+	if !s.wrote {
+		if ok {
+			s.cleaner.Write(parsed.Script)
+		} else {
+			s.cleaner.Write(raw)
+		}
+	}
+	flushed := s.cleaner.Flush()
+
+	if ok {
+		applyStructured(response, parsed)
+		return
+	}
+	response.Script = flushed
+}
+
+// isPlainText reports whether a response that has started to arrive is clearly not JSON. A
+// markdown fence could hold either, so it is left for Finish to decide.
+func isPlainText(raw string) bool {
+	trimmed := strings.TrimLeftFunc(raw, unicode.IsSpace)
+	return trimmed != "" && trimmed[0] != '{' && trimmed[0] != '`'
+}
+
+// decodeJSONStringPrefix decodes as much of a JSON string body as is complete. It returns the
+// decoded text, the number of bytes consumed and whether the closing quote was reached; an escape
+// sequence cut off at the end of s is left for the next call.
+func decodeJSONStringPrefix(s string) (string, int, bool) {
+	var out strings.Builder
+	i := 0
+	for i < len(s) {
+		switch c := s[i]; c {
+		case '"':
+			return out.String(), i + 1, true
+		case '\\':
+			length := escapeLength(s[i:])
+			if length == 0 {
+				return out.String(), i, false
+			}
+			var decoded string
+			if err := json.Unmarshal([]byte(`"`+s[i:i+length]+`"`), &decoded); err == nil {
+				out.WriteString(decoded)
+			}
+			i += length
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String(), i, false
+}
+
+// escapeLength returns the length of the escape sequence starting s, or 0 if it is incomplete.
+// A UTF-16 high surrogate is kept together with the low surrogate that follows it.
+func escapeLength(s string) int {
+	if len(s) < 2 {
+		return 0
+	}
+	if s[1] != 'u' {
+		return 2
+	}
+	if len(s) < 6 {
+		return 0
+	}
+	if hex := strings.ToLower(s[2:6]); hex >= "d800" && hex <= "dbff" {
+		if len(s) < 12 {
+			return 0
+		}
+		return 12
+	}
+	return 6
+}
@@ -0,0 +1,306 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"please/types"
+)
+
+// structuredBody is a structured response as a provider would return it
+const structuredBody = `{"script":"#!/bin/bash\nrm -rf \"$HOME/tmp\"\necho \"done \u2713 \ud83d\ude80\"","summary":"Empties the tmp folder","explanation":["Delete the folder","Report completion"],"required_tools":[],"risks":[{"operation":"rm -rf","reason":"deletes files permanently"}]}`
+
+func Test_when_structured_response_streams_in_small_fragments_then_decode_script_lines(t *testing.T) {
+	// Arrange
+	var lines []string
+	stream := newResponseStream(newLineCleaner(func(line string) { lines = append(lines, line) }), true)
+
+	// Act
+	for i := 0; i < len(structuredBody); i += 3 {
+		end := i + 3
+		if end > len(structuredBody) {
+			end = len(structuredBody)
+		}
+		stream.Write(structuredBody[i:end])
+	}
+	response := &types.ScriptResponse{}
+	stream.Finish(response)
+
+	// Assert
+	expected := []string{"#!/bin/bash", `rm -rf "$HOME/tmp"`, "echo \"done ✓ 🚀\""}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected streamed lines %q, got %q", expected, lines)
+	}
+	if response.Script != strings.Join(expected, "\n") {
+		t.Errorf("Expected the decoded script, got %q", response.Script)
+	}
+	if !response.Structured || response.Summary != "Empties the tmp folder" || len(response.Explanation) != 2 {
+		t.Errorf("Expected the structured fields, got %+v", response)
+	}
+	if len(response.Risks) != 1 || response.Risks[0].Operation != "rm -rf" {
+		t.Errorf("Expected the declared risk, got %+v", response.Risks)
+	}
+}
+
+func Test_when_model_ignores_structured_format_then_stream_text(t *testing.T) {
+	// Arrange
+	var lines []string
+	stream := newResponseStream(newLineCleaner(func(line string) { lines = append(lines, line) }), true)
+
+	// Act
+	stream.Write("#!/bin/bash\n")
+	streamedEarly := len(lines)
+	stream.Write("ls -la\n")
+	response := &types.ScriptResponse{}
+	stream.Finish(response)
+
+	// Assert
+	if streamedEarly != 1 {
+		t.Errorf("Expected text lines to stream as they arrive, got %d before the end", streamedEarly)
+	}
+	if response.Structured || response.Script != "#!/bin/bash\nls -la" {
+		t.Errorf("Expected a plain-text response, got %+v", response)
+	}
+}
+
+func Test_when_structured_response_is_fenced_then_parse_it(t *testing.T) {
+	// Arrange
+	content := "```json\n" + structuredBody + "\n```"
+	response := &types.ScriptResponse{}
+
+	// Act
+	applyContent(response, content, true)
+
+	// Assert
+	if !response.Structured || !strings.HasPrefix(response.Script, "#!/bin/bash\nrm -rf") {
+		t.Errorf("Expected the fenced JSON to be parsed, got %+v", response)
+	}
+}
+
+func Test_when_sending_to_openai_then_ask_for_json_schema(t *testing.T) {
+	// Arrange
+	var got types.OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		content, _ := json.Marshal(structuredBody)
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%s}}]}`, content)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&types.Config{OpenAIAPIKey: "test-key"})
+	provider.baseURL = server.URL
+
+	// Act
+	response, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "empty tmp", ScriptType: "bash"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.ResponseFormat == nil || got.ResponseFormat.Type != "json_schema" || !got.ResponseFormat.JSONSchema.Strict {
+		t.Errorf("Expected a strict json_schema response format, got %+v", got.ResponseFormat)
+	}
+	if !strings.Contains(got.Messages[0].Content, `"risks"`) {
+		t.Error("Expected the system prompt to describe the JSON fields")
+	}
+	if !response.Structured || response.Summary != "Empties the tmp folder" {
+		t.Errorf("Expected a structured response, got %+v", response)
+	}
+}
+
+func Test_when_api_rejects_structured_request_then_retry_as_text(t *testing.T) {
+	// Arrange
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	var formats []bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var got types.OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&got)
+		formats = append(formats, got.ResponseFormat != nil)
+		if got.ResponseFormat != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"response_format is not supported by this model","type":"invalid_request_error"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"#!/bin/bash\nls"}}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewCustomProvider("gateway", types.ProviderConfig{URL: server.URL}, nil)
+
+	// Act
+	response, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "list files", ScriptType: "bash"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(formats) != 2 || !formats[0] || formats[1] {
+		t.Errorf("Expected a structured request then a text request, got %v", formats)
+	}
+	if response.Structured || response.Script != "#!/bin/bash\nls" {
+		t.Errorf("Expected the text response, got %+v", response)
+	}
+}
+
+func Test_when_model_rejected_structured_request_before_then_send_text_straight_away(t *testing.T) {
+	// Arrange
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var got types.OpenAIRequest
+		json.NewDecoder(r.Body).Decode(&got)
+		if got.ResponseFormat != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"Invalid parameter: 'response_format' of type 'json_schema' is not supported with this model.","type":"invalid_request_error"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ls"}}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewCustomProvider("gateway", types.ProviderConfig{URL: server.URL}, nil)
+	request := &types.ScriptRequest{TaskDescription: "list files", ScriptType: "bash", Model: "old-model"}
+	provider.GenerateScript(context.Background(), request)
+
+	// Act
+	requests = 0
+	_, err := provider.GenerateScript(context.Background(), request)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected a single text request for a remembered model, got %d requests", requests)
+	}
+}
+
+func Test_when_api_rejects_request_for_another_reason_then_do_not_retry(t *testing.T) {
+	// Arrange
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"Invalid value for 'messages[1].role'","type":"invalid_request_error"}}`))
+	}))
+	defer server.Close()
+
+	provider := NewCustomProvider("gateway", types.ProviderConfig{URL: server.URL}, nil)
+
+	// Act
+	_, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "list files", ScriptType: "bash"})
+
+	// Assert
+	if ErrorKindOf(err) != ErrorInvalidRequest {
+		t.Errorf("Expected invalid request error, got: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected no retry for an unrelated 400, got %d requests", requests)
+	}
+}
+
+func Test_when_response_format_is_text_then_send_no_schema(t *testing.T) {
+	// Arrange
+	var got types.OllamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"message":{"role":"assistant","content":"df -h"},"done":true}`))
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider(&types.Config{OllamaURL: server.URL, ResponseFormat: types.ResponseFormatText})
+
+	// Act
+	_, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "disk usage", ScriptType: "bash", Model: "llama3.2"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.Format != nil || strings.Contains(got.Messages[0].Content, `"risks"`) {
+		t.Errorf("Expected a plain-text request, got format %v", got.Format)
+	}
+}
+
+func Test_when_request_is_not_for_a_script_then_send_no_schema(t *testing.T) {
+	// Arrange
+	var got types.OpenAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ROOT_CAUSE: off by one"}}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(&types.Config{OpenAIAPIKey: "test-key"})
+	provider.baseURL = server.URL
+
+	// Act
+	response, err := provider.GenerateScript(context.Background(), &types.ScriptRequest{TaskDescription: "analyze this test failure", ScriptType: "analysis"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.ResponseFormat != nil || strings.Contains(got.Messages[0].Content, `"risks"`) {
+		t.Errorf("Expected a plain-text request, got format %+v", got.ResponseFormat)
+	}
+	if response.Structured || !strings.Contains(response.Script, "ROOT_CAUSE: off by one") {
+		t.Errorf("Expected the analysis text, got %+v", response)
+	}
+}
+
+func Test_when_anthropic_streams_tool_input_then_stream_script_lines(t *testing.T) {
+	// Arrange
+	var got types.AnthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"content_block\":{\"type\":\"tool_use\",\"name\":\"submit_script\"}}\n\n")
+		for i := 0; i < len(structuredBody); i += 10 {
+			end := i + 10
+			if end > len(structuredBody) {
+				end = len(structuredBody)
+			}
+			fragment, _ := json.Marshal(structuredBody[i:end])
+			fmt.Fprintf(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":%s}}\n\n", fragment)
+		}
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider(&types.Config{AnthropicAPIKey: "test-key"})
+	provider.baseURL = server.URL
+
+	// Act
+	var lines []string
+	response, err := provider.GenerateScriptStream(context.Background(), &types.ScriptRequest{TaskDescription: "empty tmp", ScriptType: "bash"}, func(line string) {
+		lines = append(lines, line)
+	})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.ToolChoice == nil || got.ToolChoice.Name != structuredToolName || len(got.Tools) != 1 {
+		t.Errorf("Expected a forced submit_script tool call, got tools %+v and choice %+v", got.Tools, got.ToolChoice)
+	}
+	if len(lines) != 3 || lines[1] != `rm -rf "$HOME/tmp"` {
+		t.Errorf("Expected the script to stream line by line, got %q", lines)
+	}
+	if len(response.Risks) != 1 || response.Risks[0].Reason != "deletes files permanently" {
+		t.Errorf("Expected the declared risk, got %+v", response.Risks)
+	}
+}
@@ -0,0 +1,174 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"please/types"
+)
+
+// Defaults for provider HTTP calls, overridable through types.HTTPConfig
+const (
+	defaultRequestTimeout = 120 * time.Second
+	defaultConnectTimeout = 10 * time.Second
+	defaultMaxRetries     = 3
+)
+
+// Backoff bounds for retries; variables so tests can shorten them
+var (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// transports holds one pooled transport per connect timeout so every provider shares connections
+var transports sync.Map
+
+// sharedTransport returns the pooled transport for the given connect timeout
+func sharedTransport(connectTimeout time.Duration) *http.Transport {
+	if transport, ok := transports.Load(connectTimeout); ok {
+		return transport.(*http.Transport)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+
+	actual, _ := transports.LoadOrStore(connectTimeout, transport)
+	return actual.(*http.Transport)
+}
+
+// retryingClient sends provider requests with configured timeouts, context cancellation
+// and jittered exponential backoff on rate limits, server errors and timeouts
+type retryingClient struct {
+	provider   string
+	client     *http.Client
+	maxRetries int
+}
+
+// newRetryingClient creates a client for the named provider using the HTTP settings in config
+func newRetryingClient(provider string, config *types.Config) *retryingClient {
+	timeout := defaultRequestTimeout
+	connectTimeout := defaultConnectTimeout
+	maxRetries := defaultMaxRetries
+
+	if config != nil {
+		if config.HTTP.TimeoutSeconds > 0 {
+			timeout = time.Duration(config.HTTP.TimeoutSeconds) * time.Second
+		}
+		if config.HTTP.ConnectTimeoutSeconds > 0 {
+			connectTimeout = time.Duration(config.HTTP.ConnectTimeoutSeconds) * time.Second
+		}
+		if config.HTTP.MaxRetries > 0 {
+			maxRetries = config.HTTP.MaxRetries
+		} else if config.HTTP.MaxRetries < 0 {
+			maxRetries = 0
+		}
+	}
+
+	return &retryingClient{
+		provider:   provider,
+		client:     &http.Client{Timeout: timeout, Transport: sharedTransport(connectTimeout)},
+		maxRetries: maxRetries,
+	}
+}
+
+// Do sends req bound to ctx. Retryable responses are retried until maxRetries is reached,
+// after which the last response is returned for the caller to report. A cancelled ctx
+// always yields a *CancelledError.
+func (c *retryingClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+
+		resp, err := c.client.Do(attemptReq)
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, &CancelledError{Provider: c.provider, Err: ctx.Err()}
+		}
+
+		if attempt >= c.maxRetries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		delay := backoffDelay(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = retryAfter
+			}
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, &CancelledError{Provider: c.provider, Err: ctx.Err()}
+		case <-time.After(delay):
+		}
+	}
+}
+
+// shouldRetry reports whether a response or error is transient
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+		529: // Anthropic "overloaded"
+		return true
+	}
+	return false
+}
+
+// backoffDelay returns an exponential delay for the attempt with up to 50% random jitter
+func backoffDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter understands both the delay-seconds and HTTP-date forms of Retry-After
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if when, err := http.ParseTime(value); err == nil {
+		delay = time.Until(when)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay, true
+}
@@ -0,0 +1,118 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"please/types"
+)
+
+func Test_when_server_returns_transient_errors_then_retry_until_success(t *testing.T) {
+	// Arrange
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = 500 * time.Millisecond }()
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("Expected request body to be replayed, got %q", body)
+		}
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := newRetryingClient("test", &types.Config{})
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader("payload"))
+
+	// Act
+	resp, err := client.Do(context.Background(), req)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || attempts != 3 {
+		t.Errorf("Expected success on third attempt, got status %d after %d attempts", resp.StatusCode, attempts)
+	}
+}
+
+func Test_when_retries_are_disabled_then_return_first_response(t *testing.T) {
+	// Arrange
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := newRetryingClient("test", &types.Config{HTTP: types.HTTPConfig{MaxRetries: -1}})
+	req, _ := http.NewRequest("GET", server.URL, nil)
+
+	// Act
+	resp, err := client.Do(context.Background(), req)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || attempts != 1 {
+		t.Errorf("Expected a single 429 attempt, got status %d after %d attempts", resp.StatusCode, attempts)
+	}
+}
+
+func Test_when_context_is_cancelled_then_return_cancelled_error(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client := newRetryingClient("test", &types.Config{})
+	req, _ := http.NewRequest("GET", server.URL, nil)
+
+	// Act
+	_, err := client.Do(ctx, req)
+
+	// Assert
+	if !IsCancelled(err) {
+		t.Errorf("Expected CancelledError, got: %v", err)
+	}
+}
+
+func Test_when_parsing_retry_after_then_accept_seconds_and_cap_delay(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"2", 2 * time.Second, true},
+		{"3600", retryMaxDelay, true},
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			delay, ok := parseRetryAfter(tt.value)
+
+			if ok != tt.ok || delay != tt.expected {
+				t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, delay, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
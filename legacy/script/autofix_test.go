@@ -1,6 +1,7 @@
 package script
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -118,7 +119,7 @@ type MockProvider struct {
 	GenerateScriptFunc func(*types.ScriptRequest) (*types.ScriptResponse, error)
 }
 
-func (m *MockProvider) GenerateScript(req *types.ScriptRequest) (*types.ScriptResponse, error) {
+func (m *MockProvider) GenerateScript(ctx context.Context, req *types.ScriptRequest) (*types.ScriptResponse, error) {
 	if m.GenerateScriptFunc != nil {
 		return m.GenerateScriptFunc(req)
 	}
@@ -152,7 +153,7 @@ func generateFixedScriptWithProvider(
 		Model:           model,
 	}
 
-	response, err := provider.GenerateScript(request)
+	response, err := provider.GenerateScript(context.Background(), request)
 	if err != nil {
 		return "", err
 	}
@@ -162,7 +163,7 @@ func generateFixedScriptWithProvider(
 
 // Provider interface for testing - this should match the real one
 type Provider interface {
-	GenerateScript(request *types.ScriptRequest) (*types.ScriptResponse, error)
+	GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error)
 	Name() string
 	IsConfigured(config *types.Config) bool
 }
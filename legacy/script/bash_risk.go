@@ -0,0 +1,567 @@
+package script
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"please/policy"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// maxNestedScripts limits how deep "bash -c '...'" scripts are analysed
+const maxNestedScripts = 3
+
+// analyzeBash parses source as Bash and evaluates the risk rules against its syntax tree, so
+// commands are recognised by name and flags however they are written, and text in strings,
+// comments and heredocs is never mistaken for a command
+func analyzeBash(source string) (*scriptAnalysis, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(source), "")
+	if err != nil {
+		return nil, err
+	}
+	analyzer := &bashAnalyzer{}
+	analyzer.analyze(file)
+	return &analyzer.scriptAnalysis, nil
+}
+
+// bashAnalyzer collects the findings and commands of one script
+type bashAnalyzer struct {
+	scriptAnalysis
+	at    *syntax.Pos // Position to report instead of the node's, for scripts nested in "bash -c"
+	depth int
+}
+
+// analyze walks node and checks every command, pipeline and redirection in it
+func (a *bashAnalyzer) analyze(node syntax.Node) {
+	syntax.Walk(node, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.Stmt:
+			for _, redirect := range n.Redirs {
+				a.checkRedirect(redirect)
+			}
+			a.recordCommand(n)
+		case *syntax.BinaryCmd:
+			if n.Op == syntax.Pipe || n.Op == syntax.PipeAll {
+				a.checkPipe(n)
+			}
+		case *syntax.CallExpr:
+			if command, ok := resolveCommand(n); ok {
+				a.checkCommand(command)
+			}
+		}
+		return true
+	})
+}
+
+// position returns where to report something at pos
+func (a *bashAnalyzer) position(pos syntax.Pos) syntax.Pos {
+	if a.at != nil {
+		return *a.at
+	}
+	return pos
+}
+
+// report records a finding of a built-in rule at pos
+func (a *bashAnalyzer) report(pos syntax.Pos, rule string, severity policy.Severity, message string) {
+	pos = a.position(pos)
+	a.scriptAnalysis.report(pos.Line(), pos.Col(), rule, severity, message)
+}
+
+// recordCommand keeps the simple command a statement runs, with its redirections, for policy rules
+func (a *bashAnalyzer) recordCommand(stmt *syntax.Stmt) {
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	if !ok {
+		return
+	}
+	command, ok := resolveCommand(call)
+	if !ok {
+		return
+	}
+	pos := a.position(command.pos)
+	recorded := policy.Command{Name: command.name, Line: int(pos.Line()), Column: int(pos.Col())}
+	for _, arg := range command.args {
+		recorded.Args = append(recorded.Args, arg.text)
+	}
+	for _, redirect := range stmt.Redirs {
+		if redirect.Word != nil {
+			recorded.Redirects = append(recorded.Redirects, newBashWord(redirect.Word).text)
+		}
+	}
+	a.commands = append(a.commands, recorded)
+}
+
+// bashWord is a command word as far as it can be known before the script runs
+type bashWord struct {
+	text    string   // The word as written, with expansions shown as $NAME
+	ifEmpty string   // The word if every unguarded variable in it is empty or unset
+	dynamic bool     // Contains expansions, so its value is only known at run time
+	empties []string // The unguarded variables, e.g. "$DIR"
+	word    *syntax.Word
+}
+
+// unknownValue stands in for expansions whose value cannot be empty or is not known
+const unknownValue = "\x00"
+
+// newBashWord works out what is known about w
+func newBashWord(w *syntax.Word) bashWord {
+	word := bashWord{word: w}
+	var text, ifEmpty strings.Builder
+
+	var visit func(parts []syntax.WordPart)
+	visit = func(parts []syntax.WordPart) {
+		for _, part := range parts {
+			switch p := part.(type) {
+			case *syntax.Lit:
+				text.WriteString(p.Value)
+				ifEmpty.WriteString(p.Value)
+			case *syntax.SglQuoted:
+				text.WriteString(p.Value)
+				ifEmpty.WriteString(p.Value)
+			case *syntax.DblQuoted:
+				visit(p.Parts)
+			case *syntax.ParamExp:
+				word.dynamic = true
+				name := "$"
+				if p.Param != nil {
+					name += p.Param.Value
+				}
+				text.WriteString(name)
+				switch {
+				case p.Exp != nil && (p.Exp.Op == syntax.ErrorUnset || p.Exp.Op == syntax.ErrorUnsetOrNull):
+					// ${DIR:?} stops the script instead of expanding to nothing
+					ifEmpty.WriteString(unknownValue)
+				case p.Exp != nil && p.Exp.Word != nil && isDefaultOperator(p.Exp.Op):
+					ifEmpty.WriteString(newBashWord(p.Exp.Word).ifEmpty)
+				default:
+					word.empties = append(word.empties, name)
+				}
+			default:
+				word.dynamic = true
+				text.WriteString("$(...)")
+				ifEmpty.WriteString(unknownValue)
+			}
+		}
+	}
+	visit(w.Parts)
+
+	word.text = text.String()
+	word.ifEmpty = ifEmpty.String()
+	return word
+}
+
+// isDefaultOperator reports whether op substitutes its word for an empty or unset variable
+func isDefaultOperator(op syntax.ParExpOperator) bool {
+	switch op {
+	case syntax.DefaultUnset, syntax.DefaultUnsetOrNull, syntax.AssignUnset, syntax.AssignUnsetOrNull:
+		return true
+	}
+	return false
+}
+
+// bashCommand is a simple command with wrappers such as sudo, env and xargs taken off
+type bashCommand struct {
+	name      string // Base name of the program, e.g. "rm" for /bin/rm
+	args      []bashWord
+	elevated  bool // Run through sudo or doas
+	rootShell bool // "sudo -i" or "sudo -s", which open a root shell
+	pos       syntax.Pos
+}
+
+// wrapperValueFlags are the flags of command wrappers that take a separate value
+var wrapperValueFlags = map[string]map[string]bool{
+	"sudo":  {"-u": true, "-g": true, "-C": true, "-D": true, "-h": true, "-p": true, "-r": true, "-t": true, "-U": true},
+	"doas":  {"-u": true, "-C": true},
+	"env":   {"-u": true, "-C": true, "-S": true},
+	"nice":  {"-n": true},
+	"xargs": {"-I": true, "-n": true, "-P": true, "-L": true, "-d": true, "-E": true, "-s": true, "-a": true},
+}
+
+// resolveCommand finds the program a call runs, looking through wrappers. A program only known at
+// run time, like $SUDO, may be a wrapper too, so it is skipped and the words after it are checked.
+func resolveCommand(call *syntax.CallExpr) (bashCommand, bool) {
+	if len(call.Args) == 0 {
+		return bashCommand{}, false
+	}
+	words := make([]bashWord, len(call.Args))
+	for i, arg := range call.Args {
+		words[i] = newBashWord(arg)
+	}
+
+	command := bashCommand{pos: call.Args[0].Pos()}
+	i := 0
+	for i < len(words) {
+		if words[i].dynamic {
+			i++
+			continue
+		}
+		name := path.Base(words[i].text)
+		switch name {
+		case "sudo", "doas", "env", "nice", "nohup", "time", "command", "exec", "xargs", "stdbuf", "ionice", "timeout":
+			if name == "sudo" || name == "doas" {
+				command.elevated = true
+			}
+			i++
+			for i < len(words) && (strings.HasPrefix(words[i].text, "-") || (name == "env" && strings.Contains(words[i].text, "="))) {
+				if (name == "sudo" || name == "doas") && (words[i].text == "-i" || words[i].text == "-s") {
+					command.rootShell = true
+				}
+				if wrapperValueFlags[name][words[i].text] {
+					i++
+				}
+				i++
+			}
+			if name == "timeout" && i < len(words) {
+				i++ // The duration
+			}
+			continue
+		}
+
+		command.name = strings.ToLower(name)
+		command.args = words[i+1:]
+		command.pos = call.Args[i].Pos()
+		return command, true
+	}
+
+	// A bare "sudo -i" runs nothing but the root shell
+	return command, command.rootShell
+}
+
+// parseFlags splits args into flags and operands. Combined short flags are split, so "-rf",
+// "-r -f" and "--recursive --force" all set "r" and "f" when long maps the long names.
+func parseFlags(args []bashWord, long map[string]string) (map[string]bool, []bashWord) {
+	flags := make(map[string]bool)
+	var operands []bashWord
+	for i, arg := range args {
+		switch {
+		case arg.text == "--":
+			return flags, append(operands, args[i+1:]...)
+		case strings.HasPrefix(arg.text, "--"):
+			name := strings.SplitN(strings.TrimPrefix(arg.text, "--"), "=", 2)[0]
+			if short, ok := long[name]; ok {
+				flags[short] = true
+			} else {
+				flags["--"+name] = true
+			}
+		case strings.HasPrefix(arg.text, "-") && len(arg.text) > 1 && !arg.dynamic:
+			for _, flag := range arg.text[1:] {
+				flags[string(flag)] = true
+			}
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	return flags, operands
+}
+
+// isRootPath reports whether p names the root directory or everything in it
+func isRootPath(p string) bool {
+	if p == "" || strings.Contains(p, unknownValue) {
+		return false
+	}
+	if strings.HasSuffix(p, "/*") {
+		p = strings.TrimSuffix(p, "*")
+	}
+	return strings.HasPrefix(p, "/") && path.Clean(p) == "/"
+}
+
+// diskDevicePattern matches block devices that hold whole disks or partitions
+var diskDevicePattern = regexp.MustCompile(`^/dev/(sd[a-z]|hd[a-z]|vd[a-z]|xvd[a-z]|nvme\d|mmcblk\d|r?disk\d|md\d|dm-\d|mapper/)`)
+
+// windowsDrivePattern matches a drive root such as "c:", "C:\" or "c:\*"
+var windowsDrivePattern = regexp.MustCompile(`^([a-zA-Z]:)(\\\\?\*?)?$`)
+
+// shells are programs that run the script they are given
+var shells = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true}
+
+// downloaders fetch content from the network
+var downloaders = map[string]bool{"curl": true, "wget": true, "fetch": true}
+
+// checkCommand applies the rules for the command's program
+func (a *bashAnalyzer) checkCommand(command bashCommand) {
+	if command.rootShell {
+		a.report(command.pos, "root-shell", riskHigh, "Escalates to root privileges")
+	}
+
+	switch {
+	case command.name == "rm":
+		a.checkRm(command)
+	case command.name == "find":
+		a.checkFind(command)
+	case command.name == "dd":
+		a.checkDd(command)
+	case command.name == "mkfs" || strings.HasPrefix(command.name, "mkfs."):
+		a.report(command.pos, "make-filesystem", riskCritical, "Attempts to create new filesystem (destroys data)")
+	case command.name == "shred" || command.name == "wipefs":
+		for _, arg := range command.args {
+			if diskDevicePattern.MatchString(arg.text) {
+				a.report(command.pos, "overwrite-disk", riskCritical, "Erases disk device "+arg.text)
+			}
+		}
+	case command.name == "shutdown" || command.name == "poweroff":
+		a.report(command.pos, "shutdown", riskHigh, "Will shutdown the system")
+	case command.name == "reboot":
+		a.report(command.pos, "shutdown", riskHigh, "Will restart the system")
+	case command.name == "halt":
+		a.report(command.pos, "shutdown", riskHigh, "Will halt the system")
+	case command.name == "init" || command.name == "telinit":
+		a.checkRunlevel(command)
+	case command.name == "systemctl":
+		a.checkSystemctl(command)
+	case command.name == "service":
+		if len(command.args) > 1 && command.args[1].text == "stop" {
+			a.report(command.pos, "service-stop", riskCaution, "Stops system services")
+		}
+	case command.name == "su":
+		_, operands := parseFlags(command.args, nil)
+		if len(operands) == 0 || operands[0].text == "root" || operands[0].text == "-" {
+			a.report(command.pos, "root-shell", riskHigh, "Escalates to root privileges")
+		}
+	case command.name == "chmod":
+		a.checkChmod(command)
+	case command.name == "chown":
+		_, operands := parseFlags(command.args, nil)
+		if len(operands) > 0 && (operands[0].text == "root" || strings.HasPrefix(operands[0].text, "root:")) {
+			a.report(command.pos, "chown-root", riskHigh, "Changes ownership to root")
+		}
+	case command.name == "crontab":
+		if flags, _ := parseFlags(command.args, nil); flags["r"] {
+			a.report(command.pos, "crontab-remove", riskCaution, "Removes all cron jobs")
+		}
+	case command.name == "format":
+		if len(command.args) > 0 {
+			if match := windowsDrivePattern.FindStringSubmatch(command.args[0].text); match != nil {
+				a.report(command.pos, "format-drive", riskCritical, fmt.Sprintf("Attempts to format %s drive", strings.ToUpper(match[1])))
+			}
+		}
+	case command.name == "del" || command.name == "erase" || command.name == "rd" || command.name == "rmdir":
+		a.checkWindowsDelete(command)
+	case shells[command.name] || command.name == "eval" || command.name == "source" || command.name == ".":
+		a.checkShell(command)
+	}
+}
+
+// checkRm flags recursive deletion, and deleting / whether written out or through an empty variable
+func (a *bashAnalyzer) checkRm(command bashCommand) {
+	flags, operands := parseFlags(command.args, map[string]string{"recursive": "r", "force": "f"})
+	recursive := flags["r"] || flags["R"]
+	if recursive && flags["f"] {
+		a.report(command.pos, "recursive-delete", riskCaution, "Recursive deletion - verify target path carefully")
+	}
+	if !recursive {
+		return
+	}
+
+	for _, operand := range operands {
+		switch {
+		case isRootPath(operand.text):
+			a.report(command.pos, "delete-root", riskCritical, "Attempts to delete entire filesystem")
+		case operand.dynamic && isRootPath(operand.ifEmpty):
+			a.report(operand.word.Pos(), "delete-root-if-empty", riskCritical, fmt.Sprintf(
+				"Deletes the entire filesystem if %s is empty or unset (use ${VAR:?} to stop instead)", strings.Join(operand.empties, " or ")))
+		}
+	}
+}
+
+// checkFind flags find commands that delete what they match
+func (a *bashAnalyzer) checkFind(command bashCommand) {
+	var roots []bashWord
+	deletes := false
+	for i, arg := range command.args {
+		isExpression := strings.HasPrefix(arg.text, "-") || arg.text == "(" || arg.text == "!"
+		if !isExpression && !deletes && i == len(roots) {
+			roots = append(roots, arg)
+			continue
+		}
+		if arg.text == "-delete" {
+			deletes = true
+		}
+		if (arg.text == "-exec" || arg.text == "-execdir") && i+1 < len(command.args) && path.Base(command.args[i+1].text) == "rm" {
+			deletes = true
+		}
+	}
+	if !deletes {
+		return
+	}
+
+	for _, root := range roots {
+		if isRootPath(root.text) || (root.dynamic && isRootPath(root.ifEmpty)) {
+			a.report(command.pos, "delete-root", riskCritical, "Attempts to delete files across the entire filesystem")
+			return
+		}
+	}
+	a.report(command.pos, "find-delete", riskCaution, "Deletes every file find matches - verify the search carefully")
+}
+
+// checkDd flags dd writing to a disk device
+func (a *bashAnalyzer) checkDd(command bashCommand) {
+	input, output := "", ""
+	for _, arg := range command.args {
+		if value, ok := strings.CutPrefix(arg.text, "if="); ok {
+			input = value
+		}
+		if value, ok := strings.CutPrefix(arg.text, "of="); ok {
+			output = value
+		}
+	}
+	if !diskDevicePattern.MatchString(output) {
+		return
+	}
+	if input == "/dev/zero" {
+		a.report(command.pos, "overwrite-disk", riskCritical, "Attempts to overwrite data with zeros on "+output)
+		return
+	}
+	a.report(command.pos, "overwrite-disk", riskCritical, "Writes directly to disk device "+output)
+}
+
+// checkRunlevel flags switching to the halt or reboot runlevel
+func (a *bashAnalyzer) checkRunlevel(command bashCommand) {
+	if len(command.args) == 0 {
+		return
+	}
+	switch command.args[0].text {
+	case "0":
+		a.report(command.pos, "shutdown", riskHigh, "Will shutdown the system")
+	case "6":
+		a.report(command.pos, "shutdown", riskHigh, "Will restart the system")
+	}
+}
+
+// checkSystemctl flags power actions and stopping services
+func (a *bashAnalyzer) checkSystemctl(command bashCommand) {
+	_, operands := parseFlags(command.args, nil)
+	if len(operands) == 0 {
+		return
+	}
+	switch operands[0].text {
+	case "poweroff", "halt":
+		a.report(command.pos, "shutdown", riskHigh, "Will shutdown the system")
+	case "reboot":
+		a.report(command.pos, "shutdown", riskHigh, "Will restart the system")
+	case "stop", "disable", "mask":
+		a.report(command.pos, "service-stop", riskCaution, "Stops system services")
+	}
+}
+
+// checkChmod flags modes that let anyone write
+func (a *bashAnalyzer) checkChmod(command bashCommand) {
+	_, operands := parseFlags(command.args, nil)
+	if len(operands) == 0 {
+		return
+	}
+	mode := operands[0].text
+	worldWritable := strings.HasSuffix(mode, "777") || strings.HasSuffix(mode, "666")
+	if !worldWritable {
+		for _, clause := range strings.Split(mode, ",") {
+			who, perms, found := strings.Cut(clause, "+")
+			if !found {
+				who, perms, found = strings.Cut(clause, "=")
+			}
+			if found && (who == "" || strings.ContainsAny(who, "ao")) && strings.Contains(perms, "w") {
+				worldWritable = true
+			}
+		}
+	}
+	if worldWritable {
+		a.report(command.pos, "world-writable", riskHigh, "Makes files world-writable (security risk)")
+	}
+}
+
+// checkWindowsDelete flags recursive deletes written with Windows switches, as models sometimes do
+func (a *bashAnalyzer) checkWindowsDelete(command bashCommand) {
+	recursive := false
+	var targets []bashWord
+	for _, arg := range command.args {
+		if strings.EqualFold(arg.text, "/s") {
+			recursive = true
+		} else if !strings.HasPrefix(arg.text, "/") {
+			targets = append(targets, arg)
+		}
+	}
+	if !recursive {
+		return
+	}
+	for _, target := range targets {
+		if match := windowsDrivePattern.FindStringSubmatch(target.text); match != nil {
+			a.report(command.pos, "delete-drive", riskCritical, fmt.Sprintf("Attempts to delete entire %s drive", strings.ToUpper(match[1])))
+			return
+		}
+	}
+	a.report(command.pos, "recursive-delete", riskCaution, "Recursive deletion - verify target path carefully")
+}
+
+// checkShell analyses scripts passed to a shell with -c, and flags shells running downloaded code
+func (a *bashAnalyzer) checkShell(command bashCommand) {
+	for i, arg := range command.args {
+		if containsDownload(arg.word) {
+			a.report(command.pos, "download-exec", riskHigh, "Runs code downloaded from the internet without reviewing it")
+			return
+		}
+		if shells[command.name] && arg.text == "-c" && i+1 < len(command.args) && !command.args[i+1].dynamic {
+			a.analyzeNested(command.pos, command.args[i+1].text)
+			return
+		}
+	}
+}
+
+// analyzeNested analyses a script given as a string, reporting its findings at pos
+func (a *bashAnalyzer) analyzeNested(pos syntax.Pos, source string) {
+	if a.depth >= maxNestedScripts {
+		return
+	}
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(source), "")
+	if err != nil {
+		return
+	}
+	nested := &bashAnalyzer{at: &pos, depth: a.depth + 1}
+	if a.at != nil {
+		nested.at = a.at
+	}
+	nested.analyze(file)
+	a.findings = append(a.findings, nested.findings...)
+	a.commands = append(a.commands, nested.commands...)
+}
+
+// checkPipe flags downloads piped into a shell, such as "curl -fsSL URL | sh"
+func (a *bashAnalyzer) checkPipe(pipe *syntax.BinaryCmd) {
+	call, ok := pipe.Y.Cmd.(*syntax.CallExpr)
+	if !ok {
+		return
+	}
+	command, ok := resolveCommand(call)
+	if !ok || !shells[command.name] || !containsDownload(pipe.X) {
+		return
+	}
+	a.report(pipe.OpPos, "download-exec", riskHigh, "Runs code downloaded from the internet without reviewing it")
+}
+
+// containsDownload reports whether node runs curl, wget or fetch anywhere inside it
+func containsDownload(node syntax.Node) bool {
+	found := false
+	syntax.Walk(node, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok {
+			if command, ok := resolveCommand(call); ok && downloaders[command.name] {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// checkRedirect flags output redirected onto a disk device
+func (a *bashAnalyzer) checkRedirect(redirect *syntax.Redirect) {
+	switch redirect.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll:
+	default:
+		return
+	}
+	if redirect.Word == nil {
+		return
+	}
+	if target := newBashWord(redirect.Word).text; diskDevicePattern.MatchString(target) {
+		a.report(redirect.OpPos, "overwrite-disk", riskCritical, "Writes directly to disk device "+target)
+	}
+}
@@ -0,0 +1,125 @@
+package script
+
+import (
+	"strings"
+	"testing"
+
+	"please/types"
+)
+
+// bashWarnings validates source as a Bash script with a shebang, so only risk findings remain
+func bashWarnings(source string) []string {
+	var warnings []string
+	for _, finding := range ValidateScript(&types.ScriptResponse{Script: "#!/bin/bash\n" + source, ScriptType: "bash"}) {
+		warnings = append(warnings, finding.String())
+	}
+	return warnings
+}
+
+func Test_when_dangerous_command_is_written_differently_then_detect_it(t *testing.T) {
+	// Arrange
+	tests := map[string]string{
+		"rm -r -f /":                          "⛔ CRITICAL: Attempts to delete entire filesystem",
+		"rm --recursive --force /":            "⛔ CRITICAL: Attempts to delete entire filesystem",
+		"/bin/rm -fr /*":                      "⛔ CRITICAL: Attempts to delete entire filesystem",
+		"sudo -u root rm -Rf //":              "⛔ CRITICAL: Attempts to delete entire filesystem",
+		`rm -rf "$BUILD_DIR"/`:                "⛔ CRITICAL: Deletes the entire filesystem if $BUILD_DIR is empty or unset",
+		"find / -name '*.log' -delete":        "⛔ CRITICAL: Attempts to delete files across the entire filesystem",
+		"dd if=/dev/urandom of=/dev/nvme0n1":  "⛔ CRITICAL: Writes directly to disk device /dev/nvme0n1",
+		"cat image.iso > /dev/sdb":            "⛔ CRITICAL: Writes directly to disk device /dev/sdb",
+		"mkfs.ext4 /dev/sdb1":                 "⛔ CRITICAL: Attempts to create new filesystem",
+		"curl -fsSL https://x.sh | sudo bash": "🔴 WARNING: Runs code downloaded from the internet",
+		`bash -c "$(wget -qO- https://x.sh)"`: "🔴 WARNING: Runs code downloaded from the internet",
+		"bash -c 'systemctl reboot'":          "🔴 WARNING: Will restart the system",
+		"sudo -i":                             "🔴 WARNING: Escalates to root privileges",
+		"chmod -R a+rwx /srv/data":            "🔴 WARNING: Makes files world-writable",
+		"systemctl --now disable nginx":       "🟡 CAUTION: Stops system services",
+	}
+
+	for source, expected := range tests {
+		// Act
+		warnings := bashWarnings(source)
+
+		// Assert
+		if len(warnings) == 0 || !strings.HasPrefix(warnings[0], expected) {
+			t.Errorf("Expected %q to start with %q, got %v", source, expected, warnings)
+		}
+	}
+}
+
+func Test_when_command_runs_through_variable_then_check_the_words_after_it(t *testing.T) {
+	// Arrange
+	tests := map[string]string{
+		"$SUDO rm -rf /":         "⛔ CRITICAL: Attempts to delete entire filesystem",
+		`"$cmd" rm -rf ~`:        "🟡 CAUTION: Recursive deletion",
+		"${RUNNER:-sudo} reboot": "🔴 WARNING: Will restart the system",
+	}
+
+	for source, expected := range tests {
+		// Act
+		warnings := bashWarnings(source)
+
+		// Assert
+		if len(warnings) == 0 || !strings.HasPrefix(warnings[0], expected) {
+			t.Errorf("Expected %q to start with %q, got %v", source, expected, warnings)
+		}
+	}
+}
+
+func Test_when_risky_words_are_not_commands_then_do_not_warn(t *testing.T) {
+	// Arrange
+	sources := []string{
+		`echo "Never run rm -rf / or shutdown -h now"`,
+		"# reboot once the update is done\necho done",
+		"cat <<EOF\nmkfs /dev/sda\nEOF",
+		"grep -i shutdown /var/log/syslog",
+		"dd if=/dev/zero of=disk.img bs=1M count=10",
+		`rm -r "${BUILD_DIR:?}"/`,
+		`rm -r "${BUILD_DIR:-/tmp/build}/"cache`,
+		"chmod 755 deploy.sh",
+		"curl -fsSL https://example.com/data.json | jq .",
+	}
+
+	for _, source := range sources {
+		// Act
+		warnings := bashWarnings(source)
+
+		// Assert
+		for _, warning := range warnings {
+			if !strings.HasPrefix(warning, "🟢 INFO") {
+				t.Errorf("Expected no risk warnings for %q, got %v", source, warnings)
+				break
+			}
+		}
+	}
+}
+
+func Test_when_analyzing_bash_then_report_positions_most_severe_first(t *testing.T) {
+	// Arrange
+	source := "systemctl stop nginx\nif true; then\n  sudo shutdown -h now\nfi"
+
+	// Act
+	warnings := bashWarnings(source)
+
+	// Assert
+	expected := []string{
+		"🔴 WARNING: Will shutdown the system (line 4, column 8)",
+		"🟡 CAUTION: Stops system services (line 2, column 1)",
+	}
+	if len(warnings) != 2 || warnings[0] != expected[0] || warnings[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, warnings)
+	}
+}
+
+func Test_when_bash_does_not_parse_then_fall_back_to_patterns(t *testing.T) {
+	// Arrange
+	response := &types.ScriptResponse{Script: "#!/bin/bash\nif [ -d build ]; then\n  rm -rf ./build\n", ScriptType: "bash"}
+
+	// Act
+	warnings := ValidateScript(response)
+
+	// Assert
+	if len(warnings) == 0 || warnings[0].Rule != "recursive-delete" || warnings[0].Line != 3 || warnings[0].Column != 3 {
+		t.Errorf("Expected the pattern warning at the rm command, got %+v", warnings)
+	}
+}
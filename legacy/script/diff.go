@@ -0,0 +1,62 @@
+package script
+
+import "strings"
+
+// Kinds of DiffLine
+const (
+	DiffSame    = ' '
+	DiffRemoved = '-'
+	DiffAdded   = '+'
+)
+
+// DiffLine is one line of a line-by-line comparison of two scripts
+type DiffLine struct {
+	Kind rune // DiffSame, DiffRemoved or DiffAdded
+	Text string
+}
+
+// DiffScripts compares two scripts line by line, keeping the longest run of unchanged lines
+// in order and reporting everything else as removed from before or added in after
+func DiffScripts(before, after string) []DiffLine {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	diff := make([]DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Kind: DiffSame, Text: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			diff = append(diff, DiffLine{Kind: DiffRemoved, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Kind: DiffAdded, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Kind: DiffRemoved, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Kind: DiffAdded, Text: b[j]})
+	}
+	return diff
+}
@@ -92,6 +92,20 @@ func EditScript(response *types.ScriptResponse) (*types.ScriptResponse, error) {
 	return editedResponse, nil
 }
 
+// EditFile opens path in the user's preferred editor and waits for it to close
+func EditFile(path string) error {
+	editor, err := detectEditor()
+	if err != nil {
+		return fmt.Errorf("no suitable editor found: %v", err)
+	}
+
+	fmt.Printf("🔧 Opening %s in %s...\n", filepath.Base(path), editor.Name)
+	if err := launchEditor(editor, path); err != nil {
+		return fmt.Errorf("failed to launch editor: %v", err)
+	}
+	return nil
+}
+
 // EditorInfo contains information about an available editor
 type EditorInfo struct {
 	Name        string
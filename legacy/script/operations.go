@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"strings"
 
+	"please/policy"
 	"please/types"
 )
 
@@ -171,72 +172,23 @@ func GetSuggestedFilename(response *types.ScriptResponse) string {
 }
 
 // ValidateScript performs intelligent validation on the generated script with severity levels
-func ValidateScript(response *types.ScriptResponse) []string {
-	warnings := []string{}
-	script := strings.ToLower(response.Script)
+func ValidateScript(response *types.ScriptResponse) []policy.Finding {
+	return AssessScript(response)
+}
+
+// infoFindings checks the script's completeness and style, which never stops it from running
+func infoFindings(response *types.ScriptResponse) []policy.Finding {
+	findings := []policy.Finding{}
 	lines := strings.Split(response.Script, "\n")
 	
-	// Critical dangers - These will definitely cause problems
-	criticalPatterns := map[string]string{
-		`rm -rf /`:        "⛔ CRITICAL: Attempts to delete entire filesystem",
-		`rm -rf /*`:       "⛔ CRITICAL: Attempts to delete entire filesystem", 
-		`del /s /q c:\*`:  "⛔ CRITICAL: Attempts to delete entire C: drive",
-		`format c:`:       "⛔ CRITICAL: Attempts to format C: drive",
-		`format /dev/`:    "⛔ CRITICAL: Attempts to format system devices",
-		`dd if=/dev/zero`: "⛔ CRITICAL: Attempts to overwrite data with zeros",
-		`mkfs`:           "⛔ CRITICAL: Attempts to create new filesystem (destroys data)",
-	}
-	
-	// High risk warnings - Potentially dangerous but context matters
-	highRiskPatterns := map[string]string{
-		`shutdown`:     "🔴 WARNING: Will shutdown the system",
-		`reboot`:       "🔴 WARNING: Will restart the system", 
-		`halt`:         "🔴 WARNING: Will halt the system",
-		`init 0`:       "🔴 WARNING: Will shutdown the system",
-		`init 6`:       "🔴 WARNING: Will restart the system",
-		`sudo su`:      "🔴 WARNING: Escalates to root privileges",
-		`chmod 777`:    "🔴 WARNING: Makes files world-writable (security risk)",
-		`chown root`:   "🔴 WARNING: Changes ownership to root",
-	}
-	
-	// Medium risk - Things to be cautious about
-	mediumRiskPatterns := map[string]string{
-		`rm -rf`:       "🟡 CAUTION: Recursive deletion - verify target path carefully",
-		`del /s /q`:    "🟡 CAUTION: Recursive deletion - verify target path carefully",
-		`crontab -r`:   "🟡 CAUTION: Removes all cron jobs",
-		`systemctl stop`: "🟡 CAUTION: Stops system services",
-		`service stop`: "🟡 CAUTION: Stops system services",
-	}
-	
-	// Check critical patterns first
-	for pattern, warning := range criticalPatterns {
-		if containsCommand(script, pattern) {
-			warnings = append(warnings, warning)
-		}
-	}
-	
-	// Check high risk patterns
-	for pattern, warning := range highRiskPatterns {
-		if containsCommand(script, pattern) {
-			warnings = append(warnings, warning)
-		}
-	}
-	
-	// Check medium risk patterns
-	for pattern, warning := range mediumRiskPatterns {
-		if containsCommand(script, pattern) {
-			warnings = append(warnings, warning)
-		}
-	}
-	
 	// Info level checks
 	if response.ScriptType == "bash" && !strings.HasPrefix(response.Script, "#!") {
-		warnings = append(warnings, "🟢 INFO: Consider adding a shebang line (#!/bin/bash) at the top")
+		findings = append(findings, policy.Finding{Rule: "shebang", Severity: policy.SeverityInfo, Message: "Consider adding a shebang line (#!/bin/bash) at the top", Line: 1, Column: 1})
 	}
 	
 	// Check for very short scripts (might be incomplete)
 	if len(strings.TrimSpace(response.Script)) < 20 {
-		warnings = append(warnings, "🟢 INFO: Script seems very short - it might be incomplete")
+		findings = append(findings, policy.Finding{Rule: "short-script", Severity: policy.SeverityInfo, Message: "Script seems very short - it might be incomplete"})
 	}
 	
 	// Check for scripts with no error handling
@@ -255,27 +207,79 @@ func ValidateScript(response *types.ScriptResponse) []string {
 	}
 	
 	if !hasErrorHandling && len(lines) > 5 {
-		warnings = append(warnings, "🟢 INFO: Script has no error handling - consider adding try/catch or error checks")
+		findings = append(findings, policy.Finding{Rule: "no-error-handling", Severity: policy.SeverityInfo, Message: "Script has no error handling - consider adding try/catch or error checks"})
 	}
 	
-	return warnings
+	return findings
+}
+
+// riskPatterns find risky commands in scripts that can't be parsed, most severe first
+var riskPatterns = []struct {
+	pattern  string
+	rule     string
+	severity policy.Severity
+	message  string
+}{
+	// Critical dangers - These will definitely cause problems
+	{`rm -rf /`, "delete-root", riskCritical, "Attempts to delete entire filesystem"},
+	{`rm -rf /*`, "delete-root", riskCritical, "Attempts to delete entire filesystem"},
+	{`del /s /q c:\*`, "delete-drive", riskCritical, "Attempts to delete entire C: drive"},
+	{`format c:`, "format-drive", riskCritical, "Attempts to format C: drive"},
+	{`format /dev/`, "format-drive", riskCritical, "Attempts to format system devices"},
+	{`dd if=/dev/zero`, "overwrite-disk", riskCritical, "Attempts to overwrite data with zeros"},
+	{`mkfs`, "make-filesystem", riskCritical, "Attempts to create new filesystem (destroys data)"},
+
+	// High risk warnings - Potentially dangerous but context matters
+	{`shutdown`, "shutdown", riskHigh, "Will shutdown the system"},
+	{`reboot`, "shutdown", riskHigh, "Will restart the system"},
+	{`halt`, "shutdown", riskHigh, "Will halt the system"},
+	{`init 0`, "shutdown", riskHigh, "Will shutdown the system"},
+	{`init 6`, "shutdown", riskHigh, "Will restart the system"},
+	{`sudo su`, "root-shell", riskHigh, "Escalates to root privileges"},
+	{`chmod 777`, "world-writable", riskHigh, "Makes files world-writable (security risk)"},
+	{`chown root`, "chown-root", riskHigh, "Changes ownership to root"},
+
+	// Medium risk - Things to be cautious about
+	{`rm -rf`, "recursive-delete", riskCaution, "Recursive deletion - verify target path carefully"},
+	{`del /s /q`, "recursive-delete", riskCaution, "Recursive deletion - verify target path carefully"},
+	{`crontab -r`, "crontab-remove", riskCaution, "Removes all cron jobs"},
+	{`systemctl stop`, "service-stop", riskCaution, "Stops system services"},
+	{`service stop`, "service-stop", riskCaution, "Stops system services"},
+}
+
+// patternFindings finds risky commands by matching known patterns against the lowercased script
+func patternFindings(script string) []policy.Finding {
+	findings := []policy.Finding{}
+	for _, risk := range riskPatterns {
+		if line, column := commandPosition(script, risk.pattern); line > 0 {
+			findings = append(findings, policy.Finding{Rule: risk.rule, Severity: risk.severity, Message: risk.message, Line: line, Column: column})
+		}
+	}
+	return findings
 }
 
 // containsCommand checks if a command appears as an actual command, not as part of a parameter or string
 func containsCommand(script, pattern string) bool {
+	line, _ := commandPosition(script, pattern)
+	return line > 0
+}
+
+// commandPosition returns the line and column where pattern first appears as a command, or 0, 0
+func commandPosition(script, pattern string) (int, int) {
 	// Skip if it's clearly a parameter (preceded by -)
 	if strings.Contains(script, "-"+pattern) {
-		return false
+		return 0, 0
 	}
 	
 	// Skip PowerShell format parameters
 	if strings.Contains(script, "-format") && pattern == "format" {
-		return false
+		return 0, 0
 	}
 	
 	// More sophisticated quoted string detection
 	lines := strings.Split(script, "\n")
-	for _, line := range lines {
+	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
 		line = strings.TrimSpace(line)
 		
 		// Skip empty lines and comments
@@ -284,16 +288,21 @@ func containsCommand(script, pattern string) bool {
 		}
 		
 		// Check if the pattern appears outside of quotes
-		if containsPatternOutsideQuotes(line, pattern) {
-			return true
+		if index := patternIndexOutsideQuotes(line, pattern); index >= 0 {
+			return i + 1, indent + len([]rune(line[:index])) + 1
 		}
 	}
 	
-	return false
+	return 0, 0
 }
 
 // containsPatternOutsideQuotes checks if a pattern appears outside of quoted strings
 func containsPatternOutsideQuotes(line, pattern string) bool {
+	return patternIndexOutsideQuotes(line, pattern) >= 0
+}
+
+// patternIndexOutsideQuotes returns the byte offset of pattern outside quoted strings in line, or -1
+func patternIndexOutsideQuotes(line, pattern string) int {
 	inDoubleQuotes := false
 	inSingleQuotes := false
 	
@@ -313,10 +322,10 @@ func containsPatternOutsideQuotes(line, pattern string) bool {
 		// If we're not in quotes, check for pattern match
 		if !inDoubleQuotes && !inSingleQuotes {
 			if i+len(pattern) <= len(line) && strings.ToLower(line[i:i+len(pattern)]) == pattern {
-				return true
+				return i
 			}
 		}
 	}
 	
-	return false
+	return -1
 }
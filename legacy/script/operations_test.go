@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"please/policy"
 	"please/types"
 )
 
@@ -200,9 +201,9 @@ func Test_when_validating_script_with_critical_dangers_then_return_critical_warn
 			// Assert
 			found := false
 			for _, warning := range warnings {
-				if strings.Contains(warning, "⛔ CRITICAL") {
+				if warning.Severity == policy.SeverityCritical {
 					found = true
-					if !strings.Contains(warning, strings.Split(tc.expectedWarning, ":")[1]) {
+					if !strings.Contains(warning.String(), strings.Split(tc.expectedWarning, ":")[1]) {
 						t.Errorf("Expected warning about %s, got: %s", tc.expectedWarning, warning)
 					}
 					break
@@ -257,7 +258,7 @@ func Test_when_validating_script_with_high_risk_commands_then_return_high_risk_w
 			// Assert
 			found := false
 			for _, warning := range warnings {
-				if strings.Contains(warning, "🔴 WARNING") && strings.Contains(warning, tc.expectedPhrase) {
+				if warning.Severity == policy.SeverityHigh && strings.Contains(warning.Message, tc.expectedPhrase) {
 					found = true
 					break
 				}
@@ -306,7 +307,7 @@ func Test_when_validating_script_with_medium_risk_commands_then_return_caution_w
 			// Assert
 			found := false
 			for _, warning := range warnings {
-				if strings.Contains(warning, "🟡 CAUTION") && strings.Contains(warning, tc.expectedPhrase) {
+				if warning.Severity == policy.SeverityCaution && strings.Contains(warning.Message, tc.expectedPhrase) {
 					found = true
 					break
 				}
@@ -331,7 +332,7 @@ func Test_when_validating_bash_script_without_shebang_then_suggest_adding_it(t *
 	// Assert
 	found := false
 	for _, warning := range warnings {
-		if strings.Contains(warning, "🟢 INFO") && strings.Contains(warning, "shebang") {
+		if warning.Severity == policy.SeverityInfo && strings.Contains(warning.Message, "shebang") {
 			found = true
 			break
 		}
@@ -354,7 +355,7 @@ func Test_when_validating_very_short_script_then_warn_about_completeness(t *test
 	// Assert
 	found := false
 	for _, warning := range warnings {
-		if strings.Contains(warning, "🟢 INFO") && strings.Contains(warning, "very short") {
+		if warning.Severity == policy.SeverityInfo && strings.Contains(warning.Message, "very short") {
 			found = true
 			break
 		}
@@ -384,7 +385,7 @@ echo "Process complete"`
 	// Assert
 	found := false
 	for _, warning := range warnings {
-		if strings.Contains(warning, "🟢 INFO") && strings.Contains(warning, "error handling") {
+		if warning.Severity == policy.SeverityInfo && strings.Contains(warning.Message, "error handling") {
 			found = true
 			break
 		}
@@ -414,7 +415,7 @@ echo "Success"`
 
 	// Assert
 	for _, warning := range warnings {
-		if strings.Contains(warning, "error handling") {
+		if strings.Contains(warning.Message, "error handling") {
 			t.Errorf("Should not suggest error handling for script that has it, got: %v", warnings)
 		}
 	}
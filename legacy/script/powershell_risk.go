@@ -0,0 +1,563 @@
+package script
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"please/policy"
+)
+
+// analyzePowerShell tokenizes source as PowerShell and evaluates the risk rules against its
+// commands, so cmdlets are recognised through their aliases and abbreviated parameters, and text
+// in strings and comments is never mistaken for a command
+func analyzePowerShell(source string) (*scriptAnalysis, error) {
+	tokens, err := tokenizePowerShell(source)
+	if err != nil {
+		return nil, err
+	}
+	parser := &psParser{tokens: tokens}
+	pipelines, err := parser.statements("")
+	if err != nil {
+		return nil, err
+	}
+
+	analyzer := &psAnalyzer{}
+	analyzer.analyze(pipelines)
+	return &analyzer.scriptAnalysis, nil
+}
+
+// psElement is a token of a command, or a group such as (...), $(...) or a {...} script block
+type psElement struct {
+	token  psToken
+	nested []psPipeline // The statements inside a group
+}
+
+// psCommand is the elements of one command in a pipeline
+type psCommand []psElement
+
+// psPipeline is commands joined by |
+type psPipeline []psCommand
+
+// psParser groups tokens into pipelines of commands
+type psParser struct {
+	tokens []psToken
+	pos    int
+}
+
+// psClosers maps each opening token to the one that closes it
+var psClosers = map[string]string{"(": ")", "$(": ")", "@(": ")", "{": "}", "@{": "}", "[": "]"}
+
+// statements reads pipelines up to the close token, or to the end of the script when close is ""
+func (p *psParser) statements(close string) ([]psPipeline, error) {
+	var pipelines []psPipeline
+	var pipeline psPipeline
+	var command psCommand
+	endCommand := func() {
+		if len(command) > 0 {
+			pipeline = append(pipeline, command)
+		}
+		command = nil
+	}
+	endPipeline := func() {
+		endCommand()
+		if len(pipeline) > 0 {
+			pipelines = append(pipelines, pipeline)
+		}
+		pipeline = nil
+	}
+
+	for p.pos < len(p.tokens) {
+		token := p.tokens[p.pos]
+		p.pos++
+		switch {
+		case token.kind == psClose:
+			if token.text != close {
+				return nil, fmt.Errorf("unexpected %s at line %d, column %d", token.text, token.line, token.column)
+			}
+			endPipeline()
+			return pipelines, nil
+		case token.kind == psOpen:
+			nested, err := p.statements(psClosers[token.text])
+			if err != nil {
+				return nil, err
+			}
+			command = append(command, psElement{token: token, nested: nested})
+		case token.kind == psNewline && len(command) == 0 && len(pipeline) > 0:
+			// A pipeline may continue on the line after a |
+		case token.kind == psNewline || (token.kind == psOperator && (token.text == ";" || token.text == "&&" || token.text == "||")):
+			endPipeline()
+		case token.kind == psOperator && token.text == "|":
+			endCommand()
+		default:
+			command = append(command, psElement{token: token})
+		}
+	}
+	if close != "" {
+		return nil, fmt.Errorf("missing %s at end of script", close)
+	}
+	endPipeline()
+	return pipelines, nil
+}
+
+// psAliases maps the built-in aliases and Unix-style names of the cmdlets the rules look for
+var psAliases = map[string]string{
+	"rm": "remove-item", "ri": "remove-item", "del": "remove-item", "erase": "remove-item",
+	"rd": "remove-item", "rmdir": "remove-item", "rp": "remove-itemproperty",
+	"iex": "invoke-expression", "iwr": "invoke-webrequest", "curl": "invoke-webrequest",
+	"wget": "invoke-webrequest", "irm": "invoke-restmethod", "spsv": "stop-service",
+	"saps": "start-process", "start": "start-process",
+}
+
+// psInvocation is a command with its name resolved and its arguments bound
+type psInvocation struct {
+	name       string // Lowercase cmdlet or program name, e.g. "remove-item" for ri or "shutdown" for shutdown.exe
+	named      map[string]string
+	positional []psElement
+	elements   []psElement
+	token      psToken
+}
+
+// psValueParameters lists, for cmdlets where models often add Unix-style flags such as -rf, the
+// only parameters that take a value; every other parameter of theirs is a switch
+var psValueParameters = map[string][]string{
+	"remove-item": {"path", "literalpath", "filter", "include", "exclude", "credential", "stream"},
+}
+
+// psSwitches are parameters of other cmdlets that take no value, so the element after them is not their value
+var psSwitches = []string{
+	"recurse", "force", "whatif", "confirm", "verbose", "debug", "passthru", "asjob", "wait",
+	"nonewwindow", "usebasicparsing", "removedata", "removeoemdata", "full", "nologo", "noprofile",
+	"noninteractive", "disablerealtimemonitoring", "disableioavprotection",
+}
+
+// resolveInvocation finds the command an element list runs, skipping an assignment such as
+// "$x = " and the & call operator
+func resolveInvocation(command psCommand) (psInvocation, bool) {
+	elements := []psElement(command)
+	if len(elements) > 2 && elements[0].token.kind == psVariable && elements[1].token.kind == psOperator && elements[1].token.text == "=" {
+		elements = elements[2:]
+	}
+	if len(elements) > 1 && elements[0].token.kind == psOperator && elements[0].token.text == "&" {
+		elements = elements[1:]
+	}
+	if len(elements) == 0 || elements[0].nested != nil || (elements[0].token.kind != psWord && elements[0].token.kind != psString) {
+		return psInvocation{}, false
+	}
+
+	name := strings.ToLower(elements[0].token.text)
+	name = name[strings.LastIndexAny(name, `\/`)+1:]
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".exe"), ".com")
+	if alias, ok := psAliases[name]; ok {
+		name = alias
+	}
+
+	invocation := psInvocation{name: name, named: make(map[string]string), elements: elements[1:], token: elements[0].token}
+	args := elements[1:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg.token.kind != psParameter || arg.nested != nil {
+			invocation.positional = append(invocation.positional, arg)
+			continue
+		}
+		parameter, value, hasValue := strings.Cut(strings.ToLower(strings.TrimPrefix(arg.token.text, "-")), ":")
+		switch {
+		case hasValue:
+		case isPSSwitch(name, parameter) || i+1 == len(args) || args[i+1].token.kind == psParameter:
+			value = "$true"
+		default:
+			i++
+			value = psElementText(args[i])
+		}
+		invocation.named[parameter] = value
+	}
+	return invocation, true
+}
+
+// isPSSwitch reports whether parameter, which may be abbreviated, is a switch of the cmdlet
+func isPSSwitch(cmdlet, parameter string) bool {
+	if valueParameters, ok := psValueParameters[cmdlet]; ok {
+		for _, name := range valueParameters {
+			if strings.HasPrefix(name, parameter) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, name := range psSwitches {
+		if strings.HasPrefix(name, parameter) {
+			return true
+		}
+	}
+	return false
+}
+
+// param returns the value of the parameter name, which the script may abbreviate to no fewer
+// than minLength characters; switches have the value "$true"
+func (inv psInvocation) param(name string, minLength int) (string, bool) {
+	for written, value := range inv.named {
+		if len(written) >= minLength && strings.HasPrefix(name, written) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// isSet reports whether a switch is on, so -Recurse counts but -Recurse:$false doesn't
+func (inv psInvocation) isSet(name string, minLength int) bool {
+	value, ok := inv.param(name, minLength)
+	return ok && value != "$false" && value != "0"
+}
+
+// argument returns a parameter's value, or the positional argument at index when it isn't named
+func (inv psInvocation) argument(name string, minLength, index int) (string, bool) {
+	if value, ok := inv.param(name, minLength); ok {
+		return value, true
+	}
+	if index < len(inv.positional) {
+		return psElementText(inv.positional[index]), true
+	}
+	return "", false
+}
+
+// hasPositional reports whether any positional argument equals one of values, ignoring case
+func (inv psInvocation) hasPositional(values ...string) bool {
+	for _, arg := range inv.positional {
+		for _, value := range values {
+			if strings.EqualFold(psElementText(arg), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// psElementText returns the text of an element: a string's value, or "(...)" for a group
+func psElementText(element psElement) string {
+	if element.nested != nil {
+		return element.token.text + "..."
+	}
+	return element.token.text
+}
+
+// psAnalyzer collects the findings and commands of one script
+type psAnalyzer struct {
+	scriptAnalysis
+}
+
+// report records a finding of a built-in rule at the token's position
+func (a *psAnalyzer) report(token psToken, rule string, severity policy.Severity, message string) {
+	a.scriptAnalysis.report(token.line, token.column, rule, severity, message)
+}
+
+// recordCommand keeps a command, with the files it redirects to, for policy rules
+func (a *psAnalyzer) recordCommand(inv psInvocation) {
+	recorded := policy.Command{Name: inv.name, Line: int(inv.token.line), Column: int(inv.token.column)}
+	for i := 0; i < len(inv.elements); i++ {
+		element := inv.elements[i]
+		if element.token.kind == psOperator && strings.HasPrefix(element.token.text, ">") && i+1 < len(inv.elements) {
+			i++
+			recorded.Redirects = append(recorded.Redirects, psElementText(inv.elements[i]))
+			continue
+		}
+		recorded.Args = append(recorded.Args, psElementText(element))
+	}
+	a.commands = append(a.commands, recorded)
+}
+
+// analyze checks every command in pipelines and in the groups and script blocks inside them
+func (a *psAnalyzer) analyze(pipelines []psPipeline) {
+	for _, pipeline := range pipelines {
+		for i, command := range pipeline {
+			if invocation, ok := resolveInvocation(command); ok {
+				a.recordCommand(invocation)
+				a.checkInvocation(invocation, pipeline[:i])
+			}
+			for _, element := range command {
+				a.analyze(element.nested)
+			}
+		}
+	}
+}
+
+// windowsDrivePathPattern matches the root of a drive, with or without a wildcard for its contents
+var windowsDrivePathPattern = regexp.MustCompile(`^([a-z]:)?\\?\*?(\.\*)?$`)
+
+// windowsSystemPathPattern matches the Windows folder and System32, with or without their contents
+var windowsSystemPathPattern = regexp.MustCompile(`^c:\\windows(\\system32)?\\?\*?$`)
+
+// expandWindowsPath lowercases a path and replaces the environment variables for system folders
+func expandWindowsPath(value string) string {
+	value = strings.ReplaceAll(strings.ToLower(value), "/", `\`)
+	for variable, expansion := range map[string]string{
+		"$env:systemdrive": "c:", "${env:systemdrive}": "c:", "%systemdrive%": "c:",
+		"$env:systemroot": `c:\windows`, "$env:windir": `c:\windows`, "%systemroot%": `c:\windows`, "%windir%": `c:\windows`,
+	} {
+		value = strings.ReplaceAll(value, variable, expansion)
+	}
+	return value
+}
+
+// psTargetsDriveRoot returns the drive, e.g. "C:", when path is a drive's root or everything in
+// it; the drive is empty for the root of the current drive
+func psTargetsDriveRoot(path string) (string, bool) {
+	path = expandWindowsPath(path)
+	match := windowsDrivePathPattern.FindStringSubmatch(path)
+	if match == nil || path == "" || path == "*" {
+		return "", false
+	}
+	return strings.ToUpper(match[1]), true
+}
+
+// isMachineRegistryPath reports whether path is under HKEY_LOCAL_MACHINE
+func isMachineRegistryPath(path string) bool {
+	path = strings.ToLower(path)
+	return strings.HasPrefix(path, "hklm:") || strings.HasPrefix(path, `hklm\`) ||
+		strings.HasPrefix(path, "registry::hkey_local_machine") || strings.HasPrefix(path, "hkey_local_machine")
+}
+
+// checkInvocation applies the rules for the command's cmdlet or program; upstream are the
+// commands piped into it
+func (a *psAnalyzer) checkInvocation(inv psInvocation, upstream []psCommand) {
+	switch inv.name {
+	case "remove-item":
+		a.checkRemoveItem(inv, upstream)
+	case "remove-itemproperty":
+		if path, ok := inv.argument("path", 1, 0); ok && isMachineRegistryPath(path) {
+			a.report(inv.token, "registry-delete", riskHigh, "Deletes machine-wide registry values under HKLM")
+		}
+	case "reg":
+		if inv.hasPositional("delete") && len(inv.positional) > 1 && isMachineRegistryPath(psElementText(inv.positional[1])) {
+			a.report(inv.token, "registry-delete", riskHigh, "Deletes machine-wide registry keys under HKLM")
+		}
+	case "format":
+		if len(inv.positional) > 0 {
+			if match := windowsDrivePattern.FindStringSubmatch(psElementText(inv.positional[0])); match != nil {
+				a.report(inv.token, "format-drive", riskCritical, fmt.Sprintf("Attempts to format %s drive", strings.ToUpper(match[1])))
+			}
+		}
+	case "format-volume":
+		a.report(inv.token, "format-drive", riskCritical, "Attempts to format a volume (destroys data)")
+	case "clear-disk":
+		a.report(inv.token, "erase-disk", riskCritical, "Attempts to erase a disk (destroys data)")
+	case "remove-partition":
+		a.report(inv.token, "erase-disk", riskCritical, "Deletes a disk partition (destroys data)")
+	case "vssadmin":
+		if inv.hasPositional("delete") {
+			a.report(inv.token, "delete-shadow-copies", riskCritical, "Deletes volume shadow copies (backups)")
+		}
+	case "stop-computer":
+		a.report(inv.token, "shutdown", riskHigh, "Will shutdown the system")
+	case "restart-computer":
+		a.report(inv.token, "shutdown", riskHigh, "Will restart the system")
+	case "shutdown":
+		a.checkShutdown(inv)
+	case "set-executionpolicy":
+		a.checkExecutionPolicy(inv)
+	case "invoke-expression":
+		a.checkInvokeExpression(inv, upstream)
+	case "powershell", "pwsh":
+		if _, ok := inv.param("encodedcommand", 1); ok {
+			a.report(inv.token, "encoded-command", riskHigh, "Runs an encoded command that can't be reviewed")
+		}
+	case "start-process":
+		if verb, ok := inv.param("verb", 1); ok && strings.EqualFold(verb, "runas") {
+			a.report(inv.token, "root-shell", riskHigh, "Escalates to administrator privileges")
+		}
+	case "add-localgroupmember":
+		if group, ok := inv.argument("group", 1, 0); ok && strings.EqualFold(group, "administrators") {
+			a.report(inv.token, "admin-group", riskHigh, "Grants administrator rights")
+		}
+	case "stop-service":
+		a.report(inv.token, "service-stop", riskCaution, "Stops system services")
+	case "set-service":
+		startup, _ := inv.param("startuptype", 2)
+		status, _ := inv.param("status", 2)
+		if strings.EqualFold(startup, "disabled") || strings.EqualFold(status, "stopped") {
+			a.report(inv.token, "service-stop", riskCaution, "Stops system services")
+		}
+	case "set-mppreference":
+		if inv.isSet("disablerealtimemonitoring", 8) {
+			a.report(inv.token, "disable-defender", riskHigh, "Turns off Windows Defender real-time protection")
+		}
+	case "set-netfirewallprofile":
+		if enabled, ok := inv.param("enabled", 1); ok && isPSFalse(enabled) {
+			a.report(inv.token, "disable-firewall", riskHigh, "Turns off the Windows firewall")
+		}
+	case "netsh":
+		if inv.hasPositional("advfirewall") && inv.hasPositional("off") {
+			a.report(inv.token, "disable-firewall", riskHigh, "Turns off the Windows firewall")
+		}
+	case "icacls":
+		a.checkIcacls(inv)
+	}
+}
+
+// isPSFalse reports whether value is a false PowerShell or GpoBoolean value
+func isPSFalse(value string) bool {
+	switch strings.ToLower(value) {
+	case "$false", "false", "0":
+		return true
+	}
+	return false
+}
+
+// checkRemoveItem flags recursive deletes, deleting a drive or the Windows folder, and HKLM keys.
+// Without a path of its own it deletes the items piped into it, so it is checked against the
+// path they were listed from; items from a pipeline that can't be traced are a risk in themselves.
+func (a *psAnalyzer) checkRemoveItem(inv psInvocation, upstream []psCommand) {
+	var paths []string
+	if path, ok := inv.param("path", 1); ok {
+		paths = append(paths, path)
+	}
+	if path, ok := inv.param("literalpath", 1); ok {
+		paths = append(paths, path)
+	}
+	// -rf is a Unix habit, but shows what the script means to do
+	recursive := inv.isSet("recurse", 1) || inv.isSet("rf", 2) || inv.isSet("fr", 2)
+	for _, arg := range inv.positional {
+		text := psElementText(arg)
+		// Habits from cmd.exe, such as del /s /q, mean the same to a reader
+		if strings.EqualFold(text, "/s") {
+			recursive = true
+		} else if !strings.HasPrefix(text, "/") || arg.token.kind == psString {
+			paths = append(paths, text)
+		}
+	}
+	if len(paths) == 0 && len(upstream) > 0 {
+		listed, listedRecursively, ok := psPipedPaths(upstream)
+		if !ok {
+			a.report(inv.token, "piped-delete", riskHigh, "Deletes whatever the pipeline passes in - check what it lists")
+		}
+		paths = listed
+		recursive = recursive || listedRecursively
+	}
+
+	if recursive {
+		a.report(inv.token, "recursive-delete", riskCaution, "Recursive deletion - verify target path carefully")
+	}
+	for _, path := range paths {
+		drive, isDriveRoot := psTargetsDriveRoot(path)
+		switch {
+		case isMachineRegistryPath(path):
+			a.report(inv.token, "registry-delete", riskHigh, "Deletes machine-wide registry keys under HKLM")
+		case windowsSystemPathPattern.MatchString(expandWindowsPath(path)):
+			a.report(inv.token, "delete-system-folder", riskCritical, "Attempts to delete the Windows system folder")
+		case isDriveRoot && drive == "":
+			a.report(inv.token, "delete-drive", riskCritical, "Attempts to delete everything on the current drive")
+		case isDriveRoot:
+			a.report(inv.token, "delete-drive", riskCritical, fmt.Sprintf("Attempts to delete entire %s drive", drive))
+		}
+	}
+}
+
+// psItemSources list items, so a command they are piped into acts on the path they list
+var psItemSources = map[string]bool{"get-childitem": true, "gci": true, "ls": true, "dir": true, "get-item": true, "gi": true}
+
+// psItemFilters pass on some of the items piped into them unchanged
+var psItemFilters = map[string]bool{"where-object": true, "where": true, "?": true, "sort-object": true, "sort": true, "select-object": true, "select": true}
+
+// psPipedPaths finds the Get-ChildItem or Get-Item whose items reach the end of upstream, looking
+// back through filters, and returns the path it lists and whether it recurses. The path is empty
+// for the current directory; ok is false when the items come from anything else.
+func psPipedPaths(upstream []psCommand) (paths []string, recursive bool, ok bool) {
+	for i := len(upstream) - 1; i >= 0; i-- {
+		inv, resolved := resolveInvocation(upstream[i])
+		switch {
+		case resolved && psItemFilters[inv.name]:
+			continue
+		case resolved && psItemSources[inv.name]:
+			if path, ok := inv.argument("path", 1, 0); ok {
+				paths = append(paths, path)
+			}
+			if path, ok := inv.param("literalpath", 1); ok {
+				paths = append(paths, path)
+			}
+			return paths, inv.isSet("recurse", 1), true
+		}
+		return nil, false, false
+	}
+	return nil, false, false
+}
+
+// checkShutdown flags shutdown.exe when it shuts down or restarts, but not when it aborts
+func (a *psAnalyzer) checkShutdown(inv psInvocation) {
+	for _, element := range inv.elements {
+		switch strings.ToLower(strings.TrimLeft(element.token.text, "/-")) {
+		case "s", "p", "sg":
+			a.report(inv.token, "shutdown", riskHigh, "Will shutdown the system")
+			return
+		case "r", "g":
+			a.report(inv.token, "shutdown", riskHigh, "Will restart the system")
+			return
+		}
+	}
+}
+
+// checkExecutionPolicy flags policies that let unsigned scripts run
+func (a *psAnalyzer) checkExecutionPolicy(inv psInvocation) {
+	executionPolicy, _ := inv.argument("executionpolicy", 1, 0)
+	if !strings.EqualFold(executionPolicy, "unrestricted") && !strings.EqualFold(executionPolicy, "bypass") {
+		return
+	}
+	// Scope Process only lasts until the session ends
+	if scope, ok := inv.param("scope", 1); ok && strings.EqualFold(scope, "process") {
+		a.report(inv.token, "execution-policy", riskCaution, "Lets unsigned scripts run in this session (execution policy "+executionPolicy+")")
+		return
+	}
+	a.report(inv.token, "execution-policy", riskHigh, "Turns off script signing checks (execution policy "+executionPolicy+")")
+}
+
+// checkInvokeExpression flags running strings as code, above all when they were downloaded
+func (a *psAnalyzer) checkInvokeExpression(inv psInvocation, upstream []psCommand) {
+	downloads := psContainsDownload(inv.elements)
+	for _, command := range upstream {
+		downloads = downloads || psContainsDownload(command)
+	}
+	if downloads {
+		a.report(inv.token, "download-exec", riskHigh, "Runs code downloaded from the internet without reviewing it")
+		return
+	}
+	a.report(inv.token, "invoke-expression", riskCaution, "Runs a string as code - check where it comes from")
+}
+
+// checkIcacls flags granting Everyone write access
+func (a *psAnalyzer) checkIcacls(inv psInvocation) {
+	for _, element := range inv.elements {
+		grant := strings.ToLower(element.token.text)
+		if strings.HasPrefix(grant, "everyone:") && strings.ContainsAny(grant[len("everyone:"):], "fmw") {
+			a.report(inv.token, "world-writable", riskHigh, "Makes files world-writable (security risk)")
+			return
+		}
+	}
+}
+
+// psDownloadCmdlets fetch content from the network
+var psDownloadCmdlets = map[string]bool{"invoke-webrequest": true, "invoke-restmethod": true, "start-bitstransfer": true}
+
+// psDownloadPattern finds downloads inside expandable strings and method calls
+var psDownloadPattern = regexp.MustCompile(`(?i)\b(iwr|irm|invoke-webrequest|invoke-restmethod|curl|wget)\b|\.download(string|data|file)\b`)
+
+// psContainsDownload reports whether elements download something, through a cmdlet, a
+// WebClient method or a subexpression in a string
+func psContainsDownload(elements []psElement) bool {
+	if invocation, ok := resolveInvocation(psCommand(elements)); ok && psDownloadCmdlets[invocation.name] {
+		return true
+	}
+	for _, element := range elements {
+		switch {
+		case element.token.kind == psWord && psDownloadPattern.MatchString(element.token.text) && strings.HasPrefix(element.token.text, "."):
+			return true
+		case element.token.kind == psString && element.token.expandable && strings.Contains(element.token.text, "$(") && psDownloadPattern.MatchString(element.token.text):
+			return true
+		}
+		for _, pipeline := range element.nested {
+			for _, command := range pipeline {
+				if psContainsDownload(command) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
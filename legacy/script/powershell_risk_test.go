@@ -0,0 +1,100 @@
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"please/policy"
+	"please/types"
+)
+
+func Test_when_validating_powershell_fixtures_then_report_expected_findings(t *testing.T) {
+	// Arrange
+	fixtures, err := filepath.Glob("testdata/powershell/*.ps1")
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("Expected PowerShell fixtures, got %v (%v)", fixtures, err)
+	}
+
+	for _, fixture := range fixtures {
+		source, readErr := os.ReadFile(fixture)
+		expected, expectedErr := os.ReadFile(strings.TrimSuffix(fixture, ".ps1") + ".expected")
+		if readErr != nil || expectedErr != nil {
+			t.Fatalf("Expected %s and its .expected file, got %v and %v", fixture, readErr, expectedErr)
+		}
+
+		// Act
+		warnings := ValidateScript(&types.ScriptResponse{Script: string(source), ScriptType: "powershell"})
+
+		// Assert
+		var risks []string
+		for _, warning := range warnings {
+			if warning.Severity != policy.SeverityInfo {
+				risks = append(risks, warning.String())
+			}
+		}
+		got := strings.Join(risks, "\n")
+		if want := strings.TrimSpace(string(expected)); got != want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", filepath.Base(fixture), want, got)
+		}
+	}
+}
+
+func Test_when_tokenizing_powershell_then_skip_comments_and_keep_strings_whole(t *testing.T) {
+	// Arrange
+	source := "<# Remove-Item C:\\ #>\nWrite-Output 'it''s' `\n  -NoNewline # Stop-Computer\n$x = @'\nFormat-Volume\n'@"
+
+	// Act
+	tokens, err := tokenizePowerShell(source)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var got []string
+	for _, token := range tokens {
+		if token.kind != psNewline {
+			got = append(got, token.text)
+		}
+	}
+	expected := []string{"Write-Output", "it's", "-NoNewline", "$x", "=", "Format-Volume"}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected tokens %q, got %q", expected, got)
+	}
+	if tokens[2].line != 2 || tokens[2].column != 14 || tokens[3].line != 3 {
+		t.Errorf("Expected positions 2:14 and line 3, got %+v and %+v", tokens[2], tokens[3])
+	}
+}
+
+func Test_when_variable_starts_an_unquoted_path_then_tokenize_one_expandable_word(t *testing.T) {
+	// Arrange
+	source := "Remove-Item $env:windir\\Temp\\$name'.log' $wc.DownloadString($url)"
+
+	// Act
+	tokens, err := tokenizePowerShell(source)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if tokens[1].kind != psWord || !tokens[1].expandable || tokens[1].text != "$env:windir\\Temp\\$name.log" {
+		t.Errorf("Expected the path as one expandable word, got %+v", tokens[1])
+	}
+	if tokens[2].kind != psVariable || tokens[2].text != "$wc" || tokens[3].text != ".DownloadString" {
+		t.Errorf("Expected member access to stay a variable and a member, got %+v and %+v", tokens[2], tokens[3])
+	}
+}
+
+func Test_when_powershell_does_not_tokenize_then_fall_back_to_patterns(t *testing.T) {
+	// Arrange
+	response := &types.ScriptResponse{Script: "Write-Output \"unterminated\ndel /s /q C:\\temp\\old", ScriptType: "powershell"}
+
+	// Act
+	warnings := ValidateScript(response)
+
+	// Assert
+	if len(warnings) == 0 || warnings[0].String() != "🟡 CAUTION: Recursive deletion - verify target path carefully (line 2, column 1)" {
+		t.Errorf("Expected the pattern warning at the del command, got %v", warnings)
+	}
+}
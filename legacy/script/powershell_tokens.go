@@ -0,0 +1,316 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// psTokenKind is the kind of a PowerShell token
+type psTokenKind int
+
+const (
+	psWord      psTokenKind = iota // Bareword: a command name, argument, number or member such as .DownloadString
+	psParameter                    // -Name, or -Name:value
+	psString                       // Quoted string or here-string; text is its value without quotes
+	psVariable                     // $name, ${name} or $env:NAME
+	psOperator                     // | || && ; , = & > >> and other operators
+	psNewline
+	psOpen  // ( $( @( { @{ [
+	psClose // ) } ]
+)
+
+// psToken is one token of a PowerShell script
+type psToken struct {
+	kind       psTokenKind
+	text       string
+	expandable bool // A double-quoted string or a word starting with a variable, which may contain $variables
+	line       uint
+	column     uint
+}
+
+// psLexer splits PowerShell source into tokens
+type psLexer struct {
+	source []rune
+	pos    int
+	line   uint
+	column uint
+	tokens []psToken
+}
+
+// tokenizePowerShell splits source into tokens, dropping comments and line continuations. It
+// returns an error for unterminated strings, here-strings and block comments.
+func tokenizePowerShell(source string) ([]psToken, error) {
+	lexer := &psLexer{source: []rune(source), line: 1, column: 1}
+	for lexer.pos < len(lexer.source) {
+		if err := lexer.scan(); err != nil {
+			return nil, err
+		}
+	}
+	return lexer.tokens, nil
+}
+
+// peek returns the rune offset runes ahead, or 0 past the end
+func (l *psLexer) peek(offset int) rune {
+	if l.pos+offset < len(l.source) {
+		return l.source[l.pos+offset]
+	}
+	return 0
+}
+
+// advance moves past the current rune, keeping track of the line and column
+func (l *psLexer) advance() rune {
+	r := l.source[l.pos]
+	l.pos++
+	if r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return r
+}
+
+// emit adds a token that started at line and column
+func (l *psLexer) emit(kind psTokenKind, text string, line, column uint) {
+	l.tokens = append(l.tokens, psToken{kind: kind, text: text, line: line, column: column})
+}
+
+// scan reads the next token, or skips whitespace or a comment
+func (l *psLexer) scan() error {
+	line, column := l.line, l.column
+	r := l.peek(0)
+
+	switch {
+	case r == '\n':
+		l.advance()
+		l.emit(psNewline, "\n", line, column)
+	case r == ' ' || r == '\t' || r == '\r' || r == '\uFEFF':
+		l.advance()
+	case r == '`' && (l.peek(1) == '\n' || (l.peek(1) == '\r' && l.peek(2) == '\n')):
+		// A backtick at the end of a line continues the command on the next one
+		for l.advance() != '\n' {
+		}
+	case r == '<' && l.peek(1) == '#':
+		l.advance()
+		l.advance()
+		for !(l.peek(0) == '#' && l.peek(1) == '>') {
+			if l.pos >= len(l.source) {
+				return fmt.Errorf("unterminated block comment at line %d", line)
+			}
+			l.advance()
+		}
+		l.advance()
+		l.advance()
+	case r == '#':
+		for l.pos < len(l.source) && l.peek(0) != '\n' {
+			l.advance()
+		}
+	case r == '@' && (l.peek(1) == '"' || l.peek(1) == '\'') && l.isHereStringStart():
+		return l.scanHereString(line, column)
+	case isQuote(r):
+		return l.scanString(line, column)
+	case (r == '$' || r == '@') && (l.peek(1) == '(' || (r == '@' && l.peek(1) == '{')):
+		l.advance()
+		l.advance()
+		l.emit(psOpen, string(r)+string(l.source[l.pos-1]), line, column)
+	case r == '(' || r == '{' || r == '[':
+		l.advance()
+		l.emit(psOpen, string(r), line, column)
+	case r == ')' || r == '}' || r == ']':
+		l.advance()
+		l.emit(psClose, string(r), line, column)
+	case r == '$':
+		return l.scanVariable(line, column)
+	case r == '|' || r == '&' || r == ';' || r == ',' || r == '=' || r == '>':
+		l.advance()
+		text := string(r)
+		if (r == '|' || r == '&' || r == '>') && l.peek(0) == r {
+			text += string(l.advance())
+		}
+		l.emit(psOperator, text, line, column)
+	case r == '-' && unicode.IsLetter(l.peek(1)):
+		l.emit(psParameter, l.scanBareword(), line, column)
+	default:
+		l.emit(psWord, l.scanBareword(), line, column)
+	}
+	return nil
+}
+
+// isQuote reports whether r starts a string, including the typographic quotes PowerShell accepts
+func isQuote(r rune) bool {
+	return isDoubleQuote(r) || r == '\'' || r == '‘' || r == '’'
+}
+
+// isDoubleQuote reports whether r is a double quote, which starts an expandable string
+func isDoubleQuote(r rune) bool {
+	return r == '"' || r == '“' || r == '”'
+}
+
+// isHereStringStart reports whether @" or @' is followed by the end of its line
+func (l *psLexer) isHereStringStart() bool {
+	for offset := 2; ; offset++ {
+		switch l.peek(offset) {
+		case ' ', '\t', '\r':
+			continue
+		case '\n':
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// scanHereString reads a here-string, which ends with "@ or '@ at the start of a line
+func (l *psLexer) scanHereString(line, column uint) error {
+	l.advance()
+	quote := l.advance()
+	for l.advance() != '\n' {
+	}
+
+	var value strings.Builder
+	atLineStart := true
+	for l.pos < len(l.source) {
+		if atLineStart && l.peek(0) == quote && l.peek(1) == '@' {
+			l.advance()
+			l.advance()
+			l.tokens = append(l.tokens, psToken{
+				kind: psString, text: strings.TrimSuffix(value.String(), "\n"), expandable: quote == '"', line: line, column: column,
+			})
+			return nil
+		}
+		r := l.advance()
+		if r != '\r' {
+			value.WriteRune(r)
+		}
+		atLineStart = r == '\n'
+	}
+	return fmt.Errorf("unterminated here-string at line %d", line)
+}
+
+// scanString reads a single- or double-quoted string. Quotes are escaped by doubling them, and in
+// double-quoted strings by a backtick; $(...) subexpressions may contain quotes of their own.
+func (l *psLexer) scanString(line, column uint) error {
+	value, double, err := l.readString(line, column)
+	if err != nil {
+		return err
+	}
+	l.tokens = append(l.tokens, psToken{kind: psString, text: value, expandable: double, line: line, column: column})
+	return nil
+}
+
+// readString reads a quoted string as scanString does, returning its value and whether it is
+// double-quoted
+func (l *psLexer) readString(line, column uint) (string, bool, error) {
+	double := isDoubleQuote(l.advance())
+	var value strings.Builder
+	depth := 0
+	for l.pos < len(l.source) {
+		r := l.advance()
+		switch {
+		case double && r == '`' && l.pos < len(l.source):
+			value.WriteRune(r)
+			value.WriteRune(l.advance())
+		case double && r == '$' && l.peek(0) == '(':
+			depth++
+			value.WriteRune(r)
+			value.WriteRune(l.advance())
+		case depth > 0 && r == '(':
+			depth++
+			value.WriteRune(r)
+		case depth > 0 && r == ')':
+			depth--
+			value.WriteRune(r)
+		case depth == 0 && isQuote(r) && isDoubleQuote(r) == double:
+			if l.peek(0) == r {
+				value.WriteRune(l.advance())
+				continue
+			}
+			return value.String(), double, nil
+		default:
+			value.WriteRune(r)
+		}
+	}
+	return "", false, fmt.Errorf("unterminated string at line %d, column %d", line, column)
+}
+
+// scanVariable reads $name, ${any name}, $env:NAME or an automatic variable such as $_ or $?. A
+// variable followed by a path separator or a quote, as in $env:windir\Temp\*.tmp, is a single
+// argument to PowerShell, so it is read as one expandable word together with what follows.
+func (l *psLexer) scanVariable(line, column uint) error {
+	variable := l.readVariable()
+	if next := l.peek(0); next != '\\' && next != '/' && !isQuote(next) {
+		l.emit(psVariable, variable, line, column)
+		return nil
+	}
+
+	word := variable
+	for l.pos < len(l.source) {
+		r := l.peek(0)
+		if unicode.IsSpace(r) || strings.ContainsRune("|&;,(){}>", r) || (r == '$' && l.peek(1) == '(') {
+			break
+		}
+		switch {
+		case r == '$':
+			word += l.readVariable()
+		case isQuote(r):
+			value, _, err := l.readString(l.line, l.column)
+			if err != nil {
+				return err
+			}
+			word += value
+		default:
+			l.advance()
+			if r == '`' && l.pos < len(l.source) {
+				r = l.advance()
+			}
+			word += string(r)
+		}
+	}
+	l.tokens = append(l.tokens, psToken{kind: psWord, text: word, expandable: true, line: line, column: column})
+	return nil
+}
+
+// readVariable reads the variable at the current $ and returns its text
+func (l *psLexer) readVariable() string {
+	start := l.pos
+	l.advance()
+	switch {
+	case l.peek(0) == '{':
+		for l.pos < len(l.source) && l.advance() != '}' {
+		}
+	case l.peek(0) == '$' || l.peek(0) == '?' || l.peek(0) == '^':
+		l.advance()
+	default:
+		for l.pos < len(l.source) {
+			r := l.peek(0)
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != ':' {
+				break
+			}
+			l.advance()
+		}
+	}
+	return string(l.source[start:l.pos])
+}
+
+// scanBareword reads a word up to whitespace or a character that ends a command element. A
+// backtick escapes the character after it.
+func (l *psLexer) scanBareword() string {
+	var word strings.Builder
+	for l.pos < len(l.source) {
+		r := l.peek(0)
+		if unicode.IsSpace(r) || strings.ContainsRune("|&;,(){}>", r) || (isQuote(r) && word.Len() == 0) {
+			break
+		}
+		l.advance()
+		if r == '`' && l.pos < len(l.source) {
+			r = l.advance()
+		}
+		word.WriteRune(r)
+	}
+	if word.Len() == 0 {
+		// An unexpected character, such as a lone quote in a word; keep it so scanning moves on
+		word.WriteRune(l.advance())
+	}
+	return word.String()
+}
@@ -1,9 +1,9 @@
 package script
 
 import (
-	"fmt"
-	"strings"
+	"context"
 
+	"please/prompts"
 	"please/providers"
 	"please/types"
 )
@@ -14,21 +14,20 @@ func RefineScript(
 	refinementRequest string,
 	config *types.Config,
 ) (*types.ScriptResponse, error) {
-	// Create the appropriate provider
-	var provider providers.Provider
-	switch originalResponse.Provider {
-	case "ollama":
-		provider = providers.NewOllamaProvider(config)
-	case "openai":
-		provider = providers.NewOpenAIProvider(config)
-	case "anthropic":
-		provider = providers.NewAnthropicProvider(config)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", originalResponse.Provider)
-	}
+	return RefineScriptContext(context.Background(), originalResponse, refinementRequest, config)
+}
 
-	if !provider.IsConfigured(config) {
-		return nil, fmt.Errorf("provider %s is not properly configured", originalResponse.Provider)
+// RefineScriptContext is RefineScript with a context for cancellation
+func RefineScriptContext(
+	ctx context.Context,
+	originalResponse *types.ScriptResponse,
+	refinementRequest string,
+	config *types.Config,
+) (*types.ScriptResponse, error) {
+	// Create the appropriate provider
+	provider, err := providers.NewConfiguredProvider(originalResponse.Provider, config)
+	if err != nil {
+		return nil, err
 	}
 
 	// Build refinement prompt
@@ -47,7 +46,7 @@ func RefineScript(
 	}
 
 	// Generate refined script
-	response, err := provider.GenerateScript(request)
+	response, err := provider.GenerateScript(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -58,20 +57,14 @@ func RefineScript(
 	return response, nil
 }
 
-// BuildRefinementPrompt creates a prompt for script refinement
+// BuildRefinementPrompt creates a prompt for script refinement from the refine template
 func BuildRefinementPrompt(originalScript, refinementRequest, scriptType string) string {
-	scriptTypeCapitalized := strings.Title(scriptType)
-	
-	return "Please refine and improve the following " + scriptTypeCapitalized + " script based on this request: " + refinementRequest + "\n\n" +
-		"Original Script:\n" + originalScript + "\n\n" +
-		"Refinement Request: " + refinementRequest + "\n\n" +
-		"Requirements:\n" +
-		"- Keep the core functionality intact\n" +
-		"- Apply the requested improvements/changes\n" +
-		"- Maintain " + scriptTypeCapitalized + " best practices\n" +
-		"- Include clear comments explaining changes\n" +
-		"- Return ONLY the refined script, no explanations or markdown formatting\n\n" +
-		"Refined " + scriptTypeCapitalized + " Script:"
+	prompt, _ := prompts.Render(prompts.Refine, prompts.Data{
+		Script:     originalScript,
+		Request:    refinementRequest,
+		ScriptType: scriptType,
+	})
+	return prompt
 }
 
 // GetRefinementPromptSuggestions returns helpful refinement suggestions
@@ -1,6 +1,7 @@
 package script
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -213,7 +214,7 @@ func RefineScriptWithProvider(
 	}
 
 	// Generate refined script
-	response, err := provider.GenerateScript(request)
+	response, err := provider.GenerateScript(context.Background(), request)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,137 @@
+package script
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"please/policy"
+	"please/types"
+)
+
+// Severities of the built-in rules
+const (
+	riskCritical = policy.SeverityCritical
+	riskHigh     = policy.SeverityHigh
+	riskCaution  = policy.SeverityCaution
+)
+
+// remediations suggest a safer way to do what each built-in rule warns about
+var remediations = map[string]string{
+	"delete-root":          "Name the directory to delete instead of /, and check it exists before deleting",
+	"delete-root-if-empty": "Guard the variable with ${VAR:?} or check it isn't empty before deleting",
+	"recursive-delete":     "Print the files first (ls or Get-ChildItem) and delete a specific path, not a pattern",
+	"find-delete":          "Run the same find with -print first to see what it matches",
+	"overwrite-disk":       "Double-check the device with lsblk and write to a file or partition you mean to replace",
+	"make-filesystem":      "Confirm the device with lsblk or Get-Disk; creating a filesystem erases it",
+	"format-drive":         "Format only a removable or data volume you identified by label, never the system drive",
+	"delete-drive":         "Delete a specific folder instead of the whole drive",
+	"delete-system-folder": "Remove individual files with the tool that owns them instead of deleting the Windows folder",
+	"piped-delete":         "Run the pipeline without Remove-Item first to see what it lists, or give Remove-Item the path",
+	"erase-disk":           "Check the disk number with Get-Disk and back up its data first",
+	"delete-shadow-copies": "Keep shadow copies unless you are sure you no longer need these backups",
+	"shutdown":             "Run the script when you're ready to restart, or schedule it with a delay",
+	"root-shell":           "Run only the commands that need it with sudo instead of a root shell",
+	"world-writable":       "Grant only the access needed, e.g. chmod 755 or 644",
+	"chown-root":           "Keep files owned by the user that runs the service",
+	"crontab-remove":       "Back up the crontab with crontab -l first, or edit it with crontab -e",
+	"service-stop":         "Make sure nothing depends on the service, or restart it instead",
+	"download-exec":        "Download to a file, read it, then run it",
+	"registry-delete":      "Export the key with reg export before deleting it",
+	"execution-policy":     "Use -Scope Process so the change only lasts for this session",
+	"invoke-expression":    "Call the command directly instead of building it as a string",
+	"encoded-command":      "Decode the command and run it as plain text so it can be reviewed",
+	"admin-group":          "Grant the specific permission needed instead of administrator rights",
+	"disable-defender":     "Add an exclusion for a specific path instead of turning protection off",
+	"disable-firewall":     "Open the specific port with a firewall rule instead of turning the firewall off",
+	"shebang":              "Add #!/bin/bash as the first line",
+	"no-error-handling":    "Add set -e in Bash, or $ErrorActionPreference = 'Stop' and try/catch in PowerShell",
+}
+
+// messageKey is the localization key of a built-in rule's message
+func messageKey(rule string) string {
+	return "risks." + strings.ReplaceAll(rule, "-", "_")
+}
+
+// scriptAnalysis is what the syntax analysis of a script found
+type scriptAnalysis struct {
+	findings []policy.Finding
+	commands []policy.Command
+}
+
+// report records a finding of a built-in rule at line and column
+func (s *scriptAnalysis) report(line, column uint, rule string, severity policy.Severity, message string) {
+	s.findings = append(s.findings, policy.Finding{
+		Rule: rule, Severity: severity, Message: message, Line: int(line), Column: int(column),
+	})
+}
+
+// analyzeSyntax parses a Bash or PowerShell script and runs the built-in rules on its commands.
+// It returns an error for other script types and for scripts that don't parse.
+func analyzeSyntax(response *types.ScriptResponse) (*scriptAnalysis, error) {
+	switch response.ScriptType {
+	case "bash":
+		return analyzeBash(response.Script)
+	case "powershell":
+		return analyzePowerShell(response.Script)
+	default:
+		return nil, fmt.Errorf("no syntax analysis for %s scripts", response.ScriptType)
+	}
+}
+
+// AssessScript runs the built-in rules on a script and applies the risk policy files to what
+// they find, returning the findings most severe first and then in script order, each with the
+// script line it points at. A policy file that can't be read is reported as a finding, since
+// rules the user relies on may be missing.
+func AssessScript(response *types.ScriptResponse) []policy.Finding {
+	loaded, err := policy.Load()
+	findings := assessWithPolicy(response, loaded)
+	if err != nil {
+		findings = append([]policy.Finding{{
+			Rule:        "policy-error",
+			Severity:    policy.SeverityHigh,
+			MessageKey:  messageKey("policy-error"),
+			Message:     fmt.Sprintf("Some policy rules were not applied: %v", err),
+			Action:      policy.ActionTypedExecute,
+			Remediation: "Fix the policy file; please policy test <script> shows which files load",
+		}}, findings...)
+	}
+	return findings
+}
+
+// assessWithPolicy is AssessScript with the policy already loaded
+func assessWithPolicy(response *types.ScriptResponse, rules *policy.Policy) []policy.Finding {
+	input := policy.Script{Type: response.ScriptType, Source: response.Script}
+	var builtin []policy.Finding
+
+	// Scripts are checked on their syntax; other types, and scripts that don't parse, by pattern
+	if analysis, err := analyzeSyntax(response); err == nil {
+		builtin = analysis.findings
+		input.Commands = analysis.commands
+	} else {
+		builtin = patternFindings(strings.ToLower(response.Script))
+	}
+	builtin = append(builtin, infoFindings(response)...)
+	for i := range builtin {
+		builtin[i].MessageKey = messageKey(builtin[i].Rule)
+		builtin[i].Remediation = remediations[builtin[i].Rule]
+	}
+
+	findings := rules.Apply(input, builtin)
+	lines := strings.Split(response.Script, "\n")
+	for i := range findings {
+		if line := findings[i].Line; line >= 1 && line <= len(lines) {
+			findings[i].Snippet = strings.TrimRight(lines[line-1], " \t\r")
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity.Rank() > findings[j].Severity.Rank()
+		}
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings
+}
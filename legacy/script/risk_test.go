@@ -0,0 +1,72 @@
+package script
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"please/policy"
+	"please/types"
+)
+
+func Test_when_policy_matches_files_then_find_redirects_and_arguments(t *testing.T) {
+	// Arrange
+	rules, err := policy.Parse([]byte(`{"rules": [
+		{"id": "sudoers", "match": {"paths": ["/etc/sudoers", "/etc/sudoers.d/**"]}, "severity": "critical", "action": "block"},
+		{"id": "hosts", "match": {"command": "Set-Content", "paths": ["C:\\Windows\\System32\\drivers\\etc\\hosts"]}},
+		{"id": "service-stop", "severity": "info"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Expected a valid policy, got %v", err)
+	}
+	rulesPolicy := &policy.Policy{Layers: []policy.Layer{{Name: "user", Path: "policy.json", Rules: rules}}}
+	bash := &types.ScriptResponse{ScriptType: "bash", Script: "#!/bin/bash\necho 'deploy ALL=(ALL) NOPASSWD: ALL' >> /etc/sudoers\nsudo cp rules /etc/sudoers.d/deploy\nsystemctl stop nginx"}
+	powershell := &types.ScriptResponse{ScriptType: "powershell", Script: `Set-Content -Path c:\windows\system32\drivers\etc\hosts -Value "127.0.0.1 example"`}
+
+	// Act
+	bashFindings := assessWithPolicy(bash, rulesPolicy)
+	powershellFindings := assessWithPolicy(powershell, rulesPolicy)
+
+	// Assert
+	var sudoers []int
+	for _, finding := range bashFindings {
+		if finding.Rule == "sudoers" {
+			sudoers = append(sudoers, finding.Line)
+		}
+		if finding.Rule == "service-stop" && finding.Action != policy.ActionWarn {
+			t.Errorf("Expected service-stop to be downgraded to a warning, got %+v", finding)
+		}
+	}
+	if len(sudoers) != 2 || sudoers[0] != 2 || sudoers[1] != 3 || policy.Strictest(bashFindings) != policy.ActionBlock {
+		t.Errorf("Expected the redirect and the copy to be blocked, got lines %v in %+v", sudoers, bashFindings)
+	}
+	if len(powershellFindings) != 1 || powershellFindings[0].Rule != "hosts" || powershellFindings[0].Action != policy.ActionConfirm {
+		t.Errorf("Expected the hosts rule to match regardless of case, got %+v", powershellFindings)
+	}
+}
+
+func Test_when_assessing_script_then_findings_carry_snippet_key_and_remediation(t *testing.T) {
+	// Arrange
+	rules, _ := policy.Parse([]byte(`{"rules": [{"id": "service-stop", "message": "Ask ops before stopping services"}]}`))
+	rulesPolicy := &policy.Policy{Layers: []policy.Layer{{Name: "user", Path: "policy.json", Rules: rules}}}
+	response := &types.ScriptResponse{ScriptType: "bash", Script: "#!/bin/bash\nif true; then\n  chmod 777 /srv\nfi\nsystemctl stop nginx"}
+
+	// Act
+	findings := assessWithPolicy(response, rulesPolicy)
+	data, err := json.Marshal(findings)
+
+	// Assert
+	if len(findings) != 2 || err != nil {
+		t.Fatalf("Expected 2 findings that encode as JSON, got %+v (%v)", findings, err)
+	}
+	chmod := findings[0]
+	if chmod.Snippet != "  chmod 777 /srv" || chmod.Column != 3 || chmod.MessageKey != "risks.world_writable" || chmod.Remediation == "" {
+		t.Errorf("Expected the chmod line, message key and remediation, got %+v", chmod)
+	}
+	if findings[1].MessageKey != "" || findings[1].Message != "Ask ops before stopping services" {
+		t.Errorf("Expected a reworded message to drop its key, got %+v", findings[1])
+	}
+	if !strings.Contains(string(data), `"rule":"world-writable","severity":"high","message_key":"risks.world_writable"`) {
+		t.Errorf("Expected rule, severity and message key in the JSON, got %s", data)
+	}
+}
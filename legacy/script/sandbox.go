@@ -0,0 +1,40 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SandboxInitArg is the argument Please runs itself with to set up a dry run inside new namespaces
+const SandboxInitArg = "__sandbox-init"
+
+// DryRunReport is what a script did to the working directory when it ran in the sandbox
+type DryRunReport struct {
+	Sandbox  string   `json:"sandbox"`  // "bubblewrap" or "namespaces"
+	WorkDir  string   `json:"work_dir"` // The directory the script ran in
+	Created  []string `json:"created"`  // Paths relative to WorkDir; directories end in "/"
+	Modified []string `json:"modified"`
+	Deleted  []string `json:"deleted"`
+	ExitCode int      `json:"exit_code"`
+	Error    string   `json:"error,omitempty"` // Why the sandbox couldn't be set up
+}
+
+// Changed reports whether the script changed anything in the working directory
+func (r *DryRunReport) Changed() bool {
+	return len(r.Created)+len(r.Modified)+len(r.Deleted) > 0
+}
+
+// RunSandboxInit sets up the sandbox for a dry run and runs the script in it. Please calls it when
+// started with SandboxInitArg, and it sends the report to the parent on file descriptor 3.
+func RunSandboxInit(args []string) {
+	report, err := sandboxInit(args)
+	if err != nil {
+		report = &DryRunReport{Error: err.Error()}
+	}
+	if err := json.NewEncoder(os.NewFile(3, "report")).Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to send dry-run report: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,476 @@
+package script
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"please/types"
+)
+
+// Flags statfs reports for a mount; a read-only remount must keep them or the kernel refuses it
+const (
+	stNoSuid     = 0x2
+	stNoDev      = 0x4
+	stNoExec     = 0x8
+	stNoAtime    = 0x400
+	stNoDirAtime = 0x800
+	stRelAtime   = 0x1000
+)
+
+// sandboxDevices are the devices a script sees under /dev; disks and the like stay out of reach
+var sandboxDevices = []string{"null", "zero", "full", "random", "urandom", "tty"}
+
+// DryRunScript runs a Bash script in a sandbox: the system is read-only, there is no network,
+// /tmp is empty and discarded, and changes to the working directory go to a scratch overlay.
+// It reports what the script created, modified and deleted in the working directory.
+// Bubblewrap is used when it supports overlays, and user and mount namespaces otherwise.
+func DryRunScript(response *types.ScriptResponse) (*DryRunReport, error) {
+	if response.ScriptType != "bash" {
+		return nil, fmt.Errorf("dry runs only support bash scripts, not %s", response.ScriptType)
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %v", err)
+	}
+	if bwrap := bubblewrapPath(); bwrap != "" {
+		return dryRunBubblewrap(bwrap, workDir, response.Script)
+	}
+	return dryRunNamespaces(workDir, response.Script)
+}
+
+// bubblewrapPath returns the bwrap executable if it's installed and can mount overlays (0.8 or later)
+func bubblewrapPath() string {
+	path, err := exec.LookPath("bwrap")
+	if err != nil {
+		return ""
+	}
+	help, _ := exec.Command(path, "--help").CombinedOutput()
+	if !bytes.Contains(help, []byte("--overlay-src")) {
+		return ""
+	}
+	return path
+}
+
+// dryRunBubblewrap runs script with bwrap, keeping the overlay's upper directory to see what changed
+func dryRunBubblewrap(bwrap, workDir, script string) (*DryRunReport, error) {
+	scratch, err := os.MkdirTemp("", "please-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %v", err)
+	}
+	defer removeScratch(scratch)
+	upper, work := filepath.Join(scratch, "upper"), filepath.Join(scratch, "work")
+	for _, dir := range []string{upper, work} {
+		if err := os.Mkdir(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create sandbox directory: %v", err)
+		}
+	}
+
+	cmd := exec.Command(bwrap,
+		"--ro-bind", "/", "/",
+		"--dev", "/dev",
+		"--proc", "/proc",
+		"--tmpfs", "/tmp",
+		"--overlay-src", workDir, "--overlay", upper, work, workDir,
+		"--chdir", workDir,
+		"--unshare-all",
+		"--die-with-parent",
+		"--", "bash", "-c", script, "please-dry-run")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	report := &DryRunReport{Sandbox: "bubblewrap", WorkDir: workDir}
+	if report.ExitCode, err = runExitCode(cmd); err != nil {
+		return nil, fmt.Errorf("failed to start bubblewrap: %v", err)
+	}
+	report.Created, report.Modified, report.Deleted, err = overlayChanges(upper, workDir)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// dryRunNamespaces runs Please again in new user, mount, network and PID namespaces to set up the
+// sandbox and run script there, and reads back its report
+func dryRunNamespaces(workDir, script string) (*DryRunReport, error) {
+	// The helper mounts a tmpfs over the directory, so nothing the script writes reaches the disk
+	scratch, err := os.MkdirTemp("", "please-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %v", err)
+	}
+	defer os.Remove(scratch)
+
+	reportReader, reportWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report pipe: %v", err)
+	}
+	defer reportReader.Close()
+
+	uid, gid := os.Getuid(), os.Getgid()
+	cmd := exec.Command("/proc/self/exe", SandboxInitArg, workDir, scratch, strconv.Itoa(uid), strconv.Itoa(gid), script)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = []*os.File{reportWriter}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: gid, Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+
+	err = cmd.Start()
+	reportWriter.Close()
+	if err != nil {
+		return nil, fmt.Errorf("user namespaces aren't available (%v); install bubblewrap 0.8 or later for dry runs", err)
+	}
+	data, readErr := io.ReadAll(reportReader)
+	waitErr := cmd.Wait()
+
+	var report DryRunReport
+	if readErr != nil || json.Unmarshal(data, &report) != nil {
+		return nil, fmt.Errorf("sandbox exited without a report: %v", waitErr)
+	}
+	if report.Error != "" {
+		return nil, fmt.Errorf("failed to set up sandbox: %s; bubblewrap 0.8 or later may work where this doesn't", report.Error)
+	}
+	return &report, nil
+}
+
+// sandboxInit runs as root of the new user namespace. It builds a read-only copy of the mount tree
+// with fresh /dev, /proc and /tmp and an overlay on the working directory, switches to it, and runs
+// the script as the user in a further user namespace, so the script can't undo any of it.
+// args are the working directory, the scratch directory, the user's uid and gid, and the script.
+func sandboxInit(args []string) (*DryRunReport, error) {
+	if len(args) != 5 {
+		return nil, fmt.Errorf("expected 5 arguments, got %d", len(args))
+	}
+	workDir, scratch, script := args[0], args[1], args[4]
+	syscall.CloseOnExec(3) // The report pipe is for this process, not the script
+	uid, uidErr := strconv.Atoi(args[2])
+	gid, gidErr := strconv.Atoi(args[3])
+	if uidErr != nil || gidErr != nil {
+		return nil, fmt.Errorf("invalid uid or gid: %s, %s", args[2], args[3])
+	}
+
+	// Mounts made from here on stay in this namespace
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return nil, fmt.Errorf("failed to make mounts private: %v", err)
+	}
+	if err := syscall.Mount("tmpfs", scratch, "tmpfs", 0, "mode=0700"); err != nil {
+		return nil, fmt.Errorf("failed to mount scratch tmpfs: %v", err)
+	}
+	root, upper, work := filepath.Join(scratch, "root"), filepath.Join(scratch, "upper"), filepath.Join(scratch, "work")
+	for _, dir := range []string{root, upper, work} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := syscall.Mount("/", root, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return nil, fmt.Errorf("failed to bind /: %v", err)
+	}
+	if err := remountReadOnly(root); err != nil {
+		return nil, err
+	}
+	if err := mountDevices(root); err != nil {
+		return nil, err
+	}
+	if err := syscall.Mount("proc", filepath.Join(root, "proc"), "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return nil, fmt.Errorf("failed to mount /proc: %v", err)
+	}
+	for _, dir := range []string{"tmp", "var/tmp"} {
+		if _, err := os.Stat(filepath.Join(root, dir)); err != nil {
+			continue
+		}
+		if err := syscall.Mount("tmpfs", filepath.Join(root, dir), "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+			return nil, fmt.Errorf("failed to mount /%s: %v", dir, err)
+		}
+	}
+
+	// Mounted after /tmp so that a working directory under /tmp isn't hidden by it
+	target := filepath.Join(root, workDir)
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create working directory in sandbox: %v", err)
+	}
+	options := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s,userxattr", overlayPath(workDir), overlayPath(upper), overlayPath(work))
+	if err := syscall.Mount("overlay", target, "overlay", 0, options); err != nil {
+		return nil, fmt.Errorf("failed to mount overlay on %s: %v", workDir, err)
+	}
+
+	// The overlay's layers are out of the script's reach after the switch, but stay open to this process
+	upperDir, err := os.Open(upper)
+	if err != nil {
+		return nil, err
+	}
+	defer upperDir.Close()
+	lowerDir, err := os.Open(workDir)
+	if err != nil {
+		return nil, err
+	}
+	defer lowerDir.Close()
+
+	if err := pivotRoot(root); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("/bin/bash", "-c", script, "please-dry-run")
+	cmd.Dir = workDir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		// A user namespace of its own locks the mounts above, and the script runs as the user, not root
+		Cloneflags:                 syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: uid, HostID: 0, Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: gid, HostID: 0, Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+	report := &DryRunReport{Sandbox: "namespaces", WorkDir: workDir}
+	if report.ExitCode, err = runExitCode(cmd); err != nil {
+		return nil, fmt.Errorf("failed to start bash: %v", err)
+	}
+
+	report.Created, report.Modified, report.Deleted, err = overlayChanges(
+		fmt.Sprintf("/proc/self/fd/%d/", upperDir.Fd()), fmt.Sprintf("/proc/self/fd/%d/", lowerDir.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// remountReadOnly makes root and every mount under it read-only
+func remountReadOnly(root string) error {
+	mountInfo, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return fmt.Errorf("failed to read mounts: %v", err)
+	}
+	defer mountInfo.Close()
+
+	scanner := bufio.NewScanner(mountInfo)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		mountPoint := unescapeMountPath(fields[4])
+		if mountPoint != root && !strings.HasPrefix(mountPoint, root+"/") {
+			continue
+		}
+		// /proc and /dev are replaced below
+		relative := strings.TrimPrefix(mountPoint, root)
+		if relative == "/proc" || strings.HasPrefix(relative, "/proc/") || relative == "/dev" || strings.HasPrefix(relative, "/dev/") {
+			continue
+		}
+
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(mountPoint, &stat); err != nil {
+			return fmt.Errorf("failed to read mount %s: %v", relative, err)
+		}
+		flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+		for statFlag, mountFlag := range map[int64]uintptr{
+			stNoSuid: syscall.MS_NOSUID, stNoDev: syscall.MS_NODEV, stNoExec: syscall.MS_NOEXEC,
+			stNoAtime: syscall.MS_NOATIME, stNoDirAtime: syscall.MS_NODIRATIME, stRelAtime: syscall.MS_RELATIME,
+		} {
+			if stat.Flags&statFlag != 0 {
+				flags |= mountFlag
+			}
+		}
+		if err := syscall.Mount("", mountPoint, "", flags, ""); err != nil {
+			return fmt.Errorf("failed to make %s read-only: %v", relative, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes mountinfo uses for spaces and other characters
+func unescapeMountPath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if value, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// mountDevices puts a tmpfs over root/dev with only the harmless devices bound into it
+func mountDevices(root string) error {
+	dev := filepath.Join(root, "dev")
+	if err := syscall.Mount("tmpfs", dev, "tmpfs", syscall.MS_NOSUID|syscall.MS_NOEXEC, "mode=0755"); err != nil {
+		return fmt.Errorf("failed to mount /dev: %v", err)
+	}
+	for _, name := range sandboxDevices {
+		target := filepath.Join(dev, name)
+		if err := os.WriteFile(target, nil, 0644); err != nil {
+			return err
+		}
+		if err := syscall.Mount(filepath.Join("/dev", name), target, "", syscall.MS_BIND, ""); err != nil {
+			os.Remove(target)
+		}
+	}
+	for name, target := range map[string]string{"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2"} {
+		if err := os.Symlink(target, filepath.Join(dev, name)); err != nil {
+			return err
+		}
+	}
+	shm := filepath.Join(dev, "shm")
+	if err := os.Mkdir(shm, 0755); err != nil {
+		return err
+	}
+	return syscall.Mount("tmpfs", shm, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777")
+}
+
+// overlayPath escapes the characters overlayfs options treat specially
+func overlayPath(path string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ":", `\:`).Replace(path)
+}
+
+// pivotRoot makes root the root directory and detaches the old one, which chroot alone wouldn't
+func pivotRoot(root string) error {
+	if err := os.Chdir(root); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("failed to switch to sandbox root: %v", err)
+	}
+	if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to detach old root: %v", err)
+	}
+	return os.Chdir("/")
+}
+
+// runExitCode runs cmd and returns its exit code; the error is for commands that couldn't start
+func runExitCode(cmd *exec.Cmd) (int, error) {
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+// overlayChanges compares an overlay's upper directory with its lower one and returns the paths,
+// relative to both, that were created, modified and deleted. Directories end in "/".
+func overlayChanges(upper, lower string) (created, modified, deleted []string, err error) {
+	err = filepath.WalkDir(upper, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		relative, err := filepath.Rel(upper, path)
+		if err != nil || relative == "." {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		lowerInfo, lowerErr := os.Lstat(filepath.Join(lower, relative))
+		inLower := lowerErr == nil
+		name := relative
+		if entry.IsDir() {
+			name += "/"
+		}
+
+		switch {
+		case isWhiteout(info):
+			// A whiteout hides a deleted lower entry; one without a lower entry is the overlay's own bookkeeping
+			if inLower {
+				deleted = append(deleted, lowerName(relative, lowerInfo))
+			}
+		case !inLower:
+			created = append(created, name)
+		case entry.IsDir() != lowerInfo.IsDir():
+			deleted = append(deleted, lowerName(relative, lowerInfo))
+			created = append(created, name)
+		case entry.IsDir():
+			// A directory that was deleted and made again hides everything that was in it before
+			if isOpaque(path) {
+				removed, err := hiddenEntries(path, filepath.Join(lower, relative), relative)
+				if err != nil {
+					return err
+				}
+				deleted = append(deleted, removed...)
+			}
+		default:
+			modified = append(modified, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to compare working directory: %v", err)
+	}
+	sort.Strings(created)
+	sort.Strings(modified)
+	sort.Strings(deleted)
+	return created, modified, deleted, nil
+}
+
+// lowerName is relative as it names the lower entry, ending in "/" for a directory
+func lowerName(relative string, info fs.FileInfo) string {
+	if info.IsDir() {
+		return relative + "/"
+	}
+	return relative
+}
+
+// hiddenEntries returns the entries of the lower directory that an opaque upper directory lacks
+func hiddenEntries(upperDir, lowerDir, relative string) ([]string, error) {
+	entries, err := os.ReadDir(lowerDir)
+	if err != nil {
+		return nil, err
+	}
+	var hidden []string
+	for _, entry := range entries {
+		if _, err := os.Lstat(filepath.Join(upperDir, entry.Name())); err == nil {
+			continue
+		}
+		name := filepath.Join(relative, entry.Name())
+		if entry.IsDir() {
+			name += "/"
+		}
+		hidden = append(hidden, name)
+	}
+	return hidden, nil
+}
+
+// isWhiteout reports whether info is an overlay whiteout, a character device numbered 0:0
+func isWhiteout(info fs.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && info.Mode()&fs.ModeCharDevice != 0 && stat.Rdev == 0
+}
+
+// isOpaque reports whether an upper directory hides the lower directory of the same name
+func isOpaque(path string) bool {
+	value := make([]byte, 1)
+	for _, attribute := range []string{"user.overlay.opaque", "trusted.overlay.opaque"} {
+		if n, err := syscall.Getxattr(path, attribute, value); err == nil && n == 1 && value[0] == 'y' {
+			return true
+		}
+	}
+	return false
+}
+
+// removeScratch deletes the overlay's directories, which may hold entries the script made unwritable
+func removeScratch(scratch string) {
+	filepath.WalkDir(scratch, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			os.Chmod(path, 0700)
+		}
+		return nil
+	})
+	os.RemoveAll(scratch)
+}
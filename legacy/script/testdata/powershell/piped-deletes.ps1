@@ -0,0 +1,5 @@
+# Deletes items listed by an earlier command in the pipeline
+Get-ChildItem C:\ | Remove-Item -Recurse -Force
+gci $env:windir -Recurse | Where-Object { $_.Extension -eq '.log' } | Remove-Item
+Get-ChildItem -Path $env:TEMP -Filter *.tmp | Remove-Item
+Get-Content .\to-delete.txt | Remove-Item -Force
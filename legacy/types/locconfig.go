@@ -81,6 +81,8 @@ type Messages struct {
 	Menu          Menu          `json:"menu"`
 	Menus         Menus         `json:"menus"`
 	Success       Success       `json:"success"`
+	// Risks holds the messages of built-in risk rules by rule, e.g. "recursive_delete"
+	Risks map[string]string `json:"risks"`
 }
 
 // Theme defines color mappings
@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Config represents the application configuration
 type Config struct {
@@ -12,6 +15,69 @@ type Config struct {
 	AnthropicAPIKey string                    `json:"anthropic_api_key"`
 	OllamaURL       string                    `json:"ollama_url"`
 	CustomProviders map[string]ProviderConfig `json:"custom_providers"`
+	HTTP            HTTPConfig                `json:"http"`
+
+	// FallbackProviders are tried in order when Provider is unreachable or unconfigured, e.g. ["anthropic", "openai"]
+	FallbackProviders []string `json:"fallback_providers"`
+
+	// FixturesDir holds recorded responses for the offline "mock" provider (default: <config dir>/fixtures)
+	FixturesDir string `json:"fixtures_dir"`
+	// RecordProvider, when set, fills in missing mock fixtures from this real provider
+	RecordProvider string `json:"record_provider"`
+
+	Cache CacheConfig `json:"cache"`
+
+	// ModelPrices overrides or extends the built-in price table, keyed by model name or prefix
+	ModelPrices map[string]ModelPrice `json:"model_prices"`
+
+	// Environment controls the machine details added to generation prompts
+	Environment EnvironmentConfig `json:"environment_context"`
+
+	// Redaction controls the masking of secrets and personal data in prompts sent to providers
+	Redaction RedactionConfig `json:"redaction"`
+
+	// ResponseFormat is "structured" (default) to ask providers for a JSON response with an explanation
+	// and declared risks, or "text" to ask for the bare script
+	ResponseFormat string `json:"response_format"`
+}
+
+// Values of Config.ResponseFormat
+const (
+	ResponseFormatStructured = "structured"
+	ResponseFormatText       = "text"
+)
+
+// ModelPrice is the USD price per million prompt (input) and completion (output) tokens
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// CacheConfig controls the on-disk response cache; zero values use the defaults
+type CacheConfig struct {
+	Disabled  bool `json:"disabled"`
+	TTLHours  int  `json:"ttl_hours"`   // How long a cached script stays valid (default 168, one week)
+	MaxSizeMB int  `json:"max_size_mb"` // Oldest entries are evicted beyond this size (default 50)
+}
+
+// EnvironmentConfig controls the environment context sent with each request
+type EnvironmentConfig struct {
+	Disabled bool     `json:"disabled"`
+	Exclude  []string `json:"exclude"` // Sections to leave out: os, shell, package_manager, tools, cwd, git
+}
+
+// RedactionConfig controls prompt redaction; by default prompts are redacted unless the provider runs locally
+type RedactionConfig struct {
+	Disabled  bool            `json:"disabled"`
+	Providers map[string]bool `json:"providers"` // Per-provider override, e.g. {"ollama": true} for a remote Ollama
+	Exclude   []string        `json:"exclude"`   // Kinds to leave alone: private_key, password, api_key, token, email, ip
+}
+
+// HTTPConfig controls timeouts and retries for provider API calls; zero values use the defaults
+type HTTPConfig struct {
+	TimeoutSeconds        int `json:"timeout_seconds"`         // Whole request including streamed body (default 120)
+	ConnectTimeoutSeconds int `json:"connect_timeout_seconds"` // TCP/TLS connection setup (default 10)
+	MaxRetries            int `json:"max_retries"`             // Retries on 429/5xx and timeouts (default 3, negative disables)
 }
 
 // ProviderConfig represents configuration for a custom AI provider
@@ -29,9 +95,13 @@ type ModelInfo struct {
 	Size       int64     `json:"size"`
 	Digest     string    `json:"digest"`
 	Details    struct {
-		Format string `json:"format"`
-		Family string `json:"family"`
+		Format            string `json:"format"`
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
 	} `json:"details"`
+	ContextLength int  `json:"context_length,omitempty"` // From Ollama's /api/show
+	Loaded        bool `json:"loaded,omitempty"`         // Currently in memory according to Ollama's /api/ps
 }
 
 // ModelsResponse represents the response from listing models
@@ -39,17 +109,62 @@ type ModelsResponse struct {
 	Models []ModelInfo `json:"models"`
 }
 
-// OllamaRequest represents a request to the Ollama API
-type OllamaRequest struct {
-	Model   string                 `json:"model"`
-	Prompt  string                 `json:"prompt"`
-	Stream  bool                   `json:"stream"`
-	Options map[string]interface{} `json:"options"`
+// OpenAIModelsResponse represents the response from OpenAI's /v1/models endpoint
+type OpenAIModelsResponse struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"` // Unix seconds
+		OwnedBy string `json:"owned_by"`
+	} `json:"data"`
+}
+
+// AnthropicModelsResponse represents the response from Anthropic's /v1/models endpoint
+type AnthropicModelsResponse struct {
+	Data []struct {
+		ID          string    `json:"id"`
+		DisplayName string    `json:"display_name"`
+		CreatedAt   time.Time `json:"created_at"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+// OllamaRunningModelsResponse represents the response from Ollama's /api/ps endpoint
+type OllamaRunningModelsResponse struct {
+	Models []struct {
+		Name     string `json:"name"`
+		Size     int64  `json:"size"`
+		SizeVRAM int64  `json:"size_vram"`
+	} `json:"models"`
 }
 
-// OllamaResponse represents a response from the Ollama API
-type OllamaResponse struct {
-	Response string `json:"response"`
+// OllamaShowResponse represents the response from Ollama's /api/show endpoint
+type OllamaShowResponse struct {
+	Details struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+	ModelInfo map[string]interface{} `json:"model_info"` // Architecture keys such as "llama.context_length"
+}
+
+// OllamaChatRequest represents a request to Ollama's /api/chat endpoint
+type OllamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []Message              `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options"`
+	Format   interface{}            `json:"format,omitempty"` // JSON schema the response must follow
+}
+
+// OllamaChatResponse represents a response from Ollama's /api/chat endpoint
+// When streaming, each NDJSON line is one OllamaChatResponse and the last has Done set
+type OllamaChatResponse struct {
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	PromptEvalCount int     `json:"prompt_eval_count"` // Prompt tokens, reported on the final chunk
+	EvalCount       int     `json:"eval_count"`        // Generated tokens, reported on the final chunk
+	Error           string  `json:"error"`             // Set instead of a message when generation fails mid-stream
 }
 
 // Message represents a chat message for API requests
@@ -60,15 +175,47 @@ type Message struct {
 
 // OpenAIRequest represents a request to the OpenAI API
 type OpenAIRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature"`
-	MaxTokens   int       `json:"max_tokens"`
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	Temperature   float64        `json:"temperature,omitempty"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+
+	// MaxCompletionTokens replaces MaxTokens for reasoning models, which reject max_tokens
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// OpenAIResponseFormat constrains an OpenAI response, here to JSON matching a schema
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"` // "json_schema"
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// OpenAIJSONSchema names the schema a structured OpenAI response must follow
+type OpenAIJSONSchema struct {
+	Name   string      `json:"name"`
+	Strict bool        `json:"strict"`
+	Schema interface{} `json:"schema"`
+}
+
+// StreamOptions asks OpenAI to append a final chunk carrying token usage to a stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // OpenAIResponse represents a response from the OpenAI API
 type OpenAIResponse struct {
-	Choices []Choice `json:"choices"`
+	Choices []Choice     `json:"choices"`
+	Usage   *OpenAIUsage `json:"usage"`
+}
+
+// OpenAIUsage represents the token usage block of an OpenAI-compatible response
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
 }
 
 // Choice represents a choice in an OpenAI response
@@ -76,23 +223,80 @@ type Choice struct {
 	Message Message `json:"message"`
 }
 
+// OpenAIStreamChunk represents one server-sent chat.completion.chunk from the OpenAI API
+type OpenAIStreamChunk struct {
+	Choices []StreamChoice  `json:"choices"`
+	Usage   *OpenAIUsage    `json:"usage"`
+	Error   json.RawMessage `json:"error"` // Sent by some OpenAI-compatible gateways when generation fails mid-stream
+}
+
+// StreamChoice represents a choice delta in an OpenAI streaming chunk
+type StreamChoice struct {
+	Delta        Message `json:"delta"`
+	FinishReason string  `json:"finish_reason"`
+}
+
 // AnthropicRequest represents a request to the Anthropic API
 type AnthropicRequest struct {
 	Model       string    `json:"model"`
 	MaxTokens   int       `json:"max_tokens"`
+	System      string    `json:"system,omitempty"`
 	Messages    []Message `json:"messages"`
 	Temperature float64   `json:"temperature"`
+	Stream      bool      `json:"stream,omitempty"`
+
+	Tools      []AnthropicTool      `json:"tools,omitempty"`
+	ToolChoice *AnthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// AnthropicTool describes a tool the model may call, with a JSON schema for its input
+type AnthropicTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+// AnthropicToolChoice makes the model call a particular tool
+type AnthropicToolChoice struct {
+	Type string `json:"type"` // "tool"
+	Name string `json:"name"`
 }
 
 // AnthropicResponse represents a response from the Anthropic API
 type AnthropicResponse struct {
 	Content []ContentBlock `json:"content"`
+	Usage   AnthropicUsage `json:"usage"`
+}
+
+// AnthropicUsage represents the token usage block of an Anthropic response
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 // ContentBlock represents a content block in an Anthropic response
 type ContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	Input json.RawMessage `json:"input,omitempty"` // Arguments of a tool_use block
+}
+
+// AnthropicStreamEvent represents one server-sent event from the Anthropic messages API
+type AnthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"` // Set on input_json_delta, a fragment of tool input
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Message struct {
+		Usage AnthropicUsage `json:"usage"`
+	} `json:"message"` // Set on message_start, carrying the input token count
+	Usage AnthropicUsage `json:"usage"` // Set on message_delta, carrying the output token count
 }
 
 // ScriptRequest represents a request to generate a script
@@ -101,6 +305,8 @@ type ScriptRequest struct {
 	ScriptType      string
 	Provider        string
 	Model           string
+	Environment     string    // Summary of the target machine added to the prompt; empty to omit
+	History         []Message // Earlier user and assistant turns, oldest first, sent before the task
 }
 
 // ScriptResponse represents the response from script generation
@@ -110,4 +316,27 @@ type ScriptResponse struct {
 	Provider        string
 	TaskDescription string
 	ScriptType      string
+	Cached          bool  // Served from the response cache rather than a fresh provider call
+	Usage           Usage // Token counts, latency and cost of the provider call
+
+	// Set when the model gave a structured response; empty for plain-text responses
+	Summary       string       // One sentence describing the script
+	Explanation   []string     // What the script does, step by step
+	RequiredTools []string     // Commands the script needs that may not be installed
+	Risks         []ScriptRisk // Risky operations the model declared
+	Structured    bool         // The fields above came from a structured response
+}
+
+// ScriptRisk is a risky operation the model declared in its own script
+type ScriptRisk struct {
+	Operation string `json:"operation"`
+	Reason    string `json:"reason"`
+}
+
+// Usage records token counts, latency and estimated cost for one provider call
+type Usage struct {
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	Latency          time.Duration `json:"latency"`
+	CostUSD          float64       `json:"cost_usd"`
 }
@@ -149,20 +149,20 @@ func TestOpenAIRequest(t *testing.T) {
 	}
 }
 
-func TestOllamaRequest(t *testing.T) {
-	request := OllamaRequest{
-		Model:   "llama3.2",
-		Prompt:  "Hello world",
-		Stream:  false,
-		Options: make(map[string]interface{}),
+func TestOllamaChatRequest(t *testing.T) {
+	request := OllamaChatRequest{
+		Model:    "llama3.2",
+		Messages: []Message{{Role: "system", Content: "Rules"}, {Role: "user", Content: "Hello world"}},
+		Stream:   false,
+		Options:  make(map[string]interface{}),
 	}
 
 	if request.Model != "llama3.2" {
 		t.Errorf("Expected model 'llama3.2', got '%s'", request.Model)
 	}
 
-	if request.Prompt != "Hello world" {
-		t.Errorf("Expected prompt 'Hello world', got '%s'", request.Prompt)
+	if len(request.Messages) != 2 || request.Messages[1].Content != "Hello world" {
+		t.Errorf("Expected the user message after the system prompt, got %+v", request.Messages)
 	}
 
 	if request.Stream != false {
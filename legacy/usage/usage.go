@@ -0,0 +1,240 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"please/config"
+	"please/types"
+)
+
+// defaultPrices is the built-in USD price per million tokens, matched by exact name or prefix.
+// Config model_prices entries take precedence; local providers such as Ollama are always free.
+var defaultPrices = map[string]types.ModelPrice{
+	"gpt-4.1-mini":      {InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	"gpt-4.1":           {InputPerMillion: 2.00, OutputPerMillion: 8.00},
+	"gpt-4o-mini":       {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	"gpt-4o":            {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	"gpt-4-turbo":       {InputPerMillion: 10.00, OutputPerMillion: 30.00},
+	"gpt-4":             {InputPerMillion: 30.00, OutputPerMillion: 60.00},
+	"gpt-3.5-turbo":     {InputPerMillion: 0.50, OutputPerMillion: 1.50},
+	"claude-3-haiku":    {InputPerMillion: 0.25, OutputPerMillion: 1.25},
+	"claude-3-5-haiku":  {InputPerMillion: 0.80, OutputPerMillion: 4.00},
+	"claude-3-sonnet":   {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"claude-3-5-sonnet": {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"claude-3-opus":     {InputPerMillion: 15.00, OutputPerMillion: 75.00},
+	"claude-sonnet-4":   {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+}
+
+// freeProviders run locally or offline and never cost anything
+var freeProviders = map[string]bool{"ollama": true, "mock": true, "replay": true}
+
+// LookupPrice finds the price for model, preferring config overrides and exact names over the
+// longest matching prefix (so "gpt-4o-mini" wins over "gpt-4o" and "gpt-4")
+func LookupPrice(cfg *types.Config, model string) (types.ModelPrice, bool) {
+	if cfg != nil {
+		if price, ok := matchPrice(cfg.ModelPrices, model); ok {
+			return price, true
+		}
+	}
+	return matchPrice(defaultPrices, model)
+}
+
+// matchPrice looks model up in prices by exact name, then longest prefix
+func matchPrice(prices map[string]types.ModelPrice, model string) (types.ModelPrice, bool) {
+	if price, ok := prices[model]; ok {
+		return price, true
+	}
+
+	best := ""
+	for name := range prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return types.ModelPrice{}, false
+	}
+	return prices[best], true
+}
+
+// Apply fills in response.Usage.CostUSD from the price table
+func Apply(cfg *types.Config, response *types.ScriptResponse) {
+	if freeProviders[response.Provider] {
+		response.Usage.CostUSD = 0
+		return
+	}
+
+	price, ok := LookupPrice(cfg, response.Model)
+	if !ok {
+		return
+	}
+	response.Usage.CostUSD = (float64(response.Usage.PromptTokens)*price.InputPerMillion +
+		float64(response.Usage.CompletionTokens)*price.OutputPerMillion) / 1_000_000
+}
+
+// Record is one persisted line of the usage log
+type Record struct {
+	Time             time.Time `json:"time"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMS        int64     `json:"latency_ms"`
+	CostUSD          float64   `json:"cost_usd"`
+}
+
+// Tracker appends usage records to a JSON Lines log
+type Tracker struct {
+	path string
+}
+
+// NewTracker creates a tracker writing to path
+func NewTracker(path string) *Tracker {
+	return &Tracker{path: path}
+}
+
+// Open creates the tracker for usage.jsonl in the config directory
+func Open() (*Tracker, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewTracker(filepath.Join(configDir, "usage.jsonl")), nil
+}
+
+// Path returns the location of the usage log
+func (t *Tracker) Path() string {
+	return t.path
+}
+
+// Append records the usage of a fresh (non-cached) response
+func (t *Tracker) Append(response *types.ScriptResponse) error {
+	data, err := json.Marshal(Record{
+		Time:             time.Now(),
+		Provider:         response.Provider,
+		Model:            response.Model,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		LatencyMS:        response.Usage.Latency.Milliseconds(),
+		CostUSD:          response.Usage.CostUSD,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %v", err)
+	}
+
+	file, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage log: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage log: %v", err)
+	}
+	return nil
+}
+
+// Track prices a fresh response and appends it to the usage log in the config directory
+func Track(cfg *types.Config, response *types.ScriptResponse) error {
+	Apply(cfg, response)
+
+	tracker, err := Open()
+	if err != nil {
+		return err
+	}
+	return tracker.Append(response)
+}
+
+// Load returns the records at or after since, skipping lines that cannot be parsed
+func (t *Tracker) Load(since time.Time) ([]Record, error) {
+	file, err := os.Open(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open usage log: %v", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if !record.Time.Before(since) {
+			records = append(records, record)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage log: %v", err)
+	}
+	return records, nil
+}
+
+// Summary aggregates the records sharing one key
+type Summary struct {
+	Key              string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+	TotalLatency     time.Duration
+}
+
+// AverageLatency returns the mean latency per request
+func (s Summary) AverageLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Requests)
+}
+
+// Summarize groups records by key, returning summaries sorted by key
+func Summarize(records []Record, key func(Record) string) []Summary {
+	byKey := make(map[string]*Summary)
+	for _, record := range records {
+		k := key(record)
+		summary, ok := byKey[k]
+		if !ok {
+			summary = &Summary{Key: k}
+			byKey[k] = summary
+		}
+		summary.Requests++
+		summary.PromptTokens += record.PromptTokens
+		summary.CompletionTokens += record.CompletionTokens
+		summary.CostUSD += record.CostUSD
+		summary.TotalLatency += time.Duration(record.LatencyMS) * time.Millisecond
+	}
+
+	summaries := make([]Summary, 0, len(byKey))
+	for _, summary := range byKey {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Key < summaries[j].Key })
+	return summaries
+}
+
+// ByDay groups records by local calendar day
+func ByDay(record Record) string {
+	return record.Time.Local().Format(time.DateOnly)
+}
+
+// ByProvider groups records by provider
+func ByProvider(record Record) string {
+	return record.Provider
+}
+
+// ByModel groups records by provider and model
+func ByModel(record Record) string {
+	return record.Provider + "/" + record.Model
+}
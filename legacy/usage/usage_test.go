@@ -0,0 +1,130 @@
+package usage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"please/types"
+)
+
+func Test_when_looking_up_dated_model_then_use_longest_prefix_price(t *testing.T) {
+	// Act
+	mini, miniOK := LookupPrice(nil, "gpt-4o-mini-2024-07-18")
+	haiku, haikuOK := LookupPrice(nil, "claude-3-haiku-20240307")
+	_, unknownOK := LookupPrice(nil, "my-local-model")
+
+	// Assert
+	if !miniOK || mini.InputPerMillion != 0.15 {
+		t.Errorf("Expected gpt-4o-mini price, got %+v", mini)
+	}
+	if !haikuOK || haiku.OutputPerMillion != 1.25 {
+		t.Errorf("Expected claude-3-haiku price, got %+v", haiku)
+	}
+	if unknownOK {
+		t.Error("Expected unknown model to have no price")
+	}
+}
+
+func Test_when_config_overrides_price_then_use_config_price(t *testing.T) {
+	// Arrange
+	cfg := &types.Config{ModelPrices: map[string]types.ModelPrice{
+		"gpt-4": {InputPerMillion: 1, OutputPerMillion: 2},
+	}}
+	response := &types.ScriptResponse{
+		Provider: "openai",
+		Model:    "gpt-4",
+		Usage:    types.Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000},
+	}
+
+	// Act
+	Apply(cfg, response)
+
+	// Assert
+	if response.Usage.CostUSD != 2 {
+		t.Errorf("Expected cost 2.00 from config prices, got %f", response.Usage.CostUSD)
+	}
+}
+
+func Test_when_provider_is_local_then_cost_is_zero(t *testing.T) {
+	// Arrange
+	response := &types.ScriptResponse{
+		Provider: "ollama",
+		Model:    "gpt-4", // name collision must not be charged
+		Usage:    types.Usage{PromptTokens: 1000, CompletionTokens: 1000},
+	}
+
+	// Act
+	Apply(nil, response)
+
+	// Assert
+	if response.Usage.CostUSD != 0 {
+		t.Errorf("Expected local provider to be free, got %f", response.Usage.CostUSD)
+	}
+}
+
+func Test_when_tracking_response_then_price_it_and_append_to_log(t *testing.T) {
+	// Arrange
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	response := &types.ScriptResponse{
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		Usage:    types.Usage{PromptTokens: 1_000_000},
+	}
+
+	// Act
+	err := Track(nil, response)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if response.Usage.CostUSD != 0.15 {
+		t.Errorf("Expected the response to be priced, got %f", response.Usage.CostUSD)
+	}
+	tracker, _ := Open()
+	records, _ := tracker.Load(time.Now().Add(-time.Hour))
+	if len(records) != 1 || records[0].CostUSD != 0.15 {
+		t.Errorf("Expected one priced record, got %+v", records)
+	}
+}
+
+func Test_when_records_are_logged_then_summarize_by_provider(t *testing.T) {
+	// Arrange
+	tracker := NewTracker(filepath.Join(t.TempDir(), "usage.jsonl"))
+	tracker.Append(&types.ScriptResponse{Provider: "openai", Model: "gpt-4", Usage: types.Usage{PromptTokens: 10, CompletionTokens: 5, CostUSD: 0.5, Latency: time.Second}})
+	tracker.Append(&types.ScriptResponse{Provider: "openai", Model: "gpt-4o", Usage: types.Usage{PromptTokens: 20, CompletionTokens: 5, CostUSD: 0.25, Latency: 3 * time.Second}})
+	tracker.Append(&types.ScriptResponse{Provider: "ollama", Model: "llama3.2", Usage: types.Usage{PromptTokens: 7, CompletionTokens: 3}})
+
+	// Act
+	records, err := tracker.Load(time.Now().Add(-time.Hour))
+	byProvider := Summarize(records, ByProvider)
+
+	// Assert
+	if err != nil || len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d (%v)", len(records), err)
+	}
+	if len(byProvider) != 2 || byProvider[1].Key != "openai" {
+		t.Fatalf("Expected ollama and openai summaries, got %+v", byProvider)
+	}
+	openai := byProvider[1]
+	if openai.Requests != 2 || openai.PromptTokens != 30 || openai.CostUSD != 0.75 || openai.AverageLatency() != 2*time.Second {
+		t.Errorf("Unexpected openai summary: %+v", openai)
+	}
+}
+
+func Test_when_loading_with_future_cutoff_then_return_no_records(t *testing.T) {
+	// Arrange
+	tracker := NewTracker(filepath.Join(t.TempDir(), "usage.jsonl"))
+	tracker.Append(&types.ScriptResponse{Provider: "openai", Model: "gpt-4"})
+
+	// Act
+	records, _ := tracker.Load(time.Now().Add(time.Hour))
+
+	// Assert
+	if len(records) != 0 {
+		t.Errorf("Expected no records after cutoff, got %d", len(records))
+	}
+}
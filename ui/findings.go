@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"please/policy"
+)
+
+// FormatFinding renders a finding for the terminal: its message, then the script line it points
+// at, numbered as in the script listing with a caret under the column, then how to fix it
+func FormatFinding(finding policy.Finding) string {
+	color := ColorYellow
+	switch {
+	case finding.Severity.Rank() >= policy.SeverityHigh.Rank():
+		color = ColorRed
+	case finding.Severity == policy.SeverityInfo:
+		color = ColorDim
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %s%s: %s%s %s[%s]%s\n", color, finding.Severity.Label(), findingMessage(finding), ColorReset, ColorDim, finding.Rule, ColorReset)
+	if finding.Line > 0 && finding.Snippet != "" {
+		fmt.Fprintf(&b, "  %s%3d│%s %s\n", ColorDim, finding.Line, ColorReset, finding.Snippet)
+		fmt.Fprintf(&b, "  %s   │%s %s%s^%s\n", ColorDim, ColorReset, caretIndent(finding.Snippet, finding.Column), color, ColorReset)
+	}
+	if finding.Remediation != "" {
+		fmt.Fprintf(&b, "  %s💡 %s%s\n", ColorDim, finding.Remediation, ColorReset)
+	}
+	return b.String()
+}
+
+// findingMessage returns the finding's message in the active language, falling back to its own
+// wording for rules that aren't translated and messages a policy file rewrote
+func findingMessage(finding policy.Finding) string {
+	if finding.MessageKey != "" {
+		if message := GetLocalizedMessage(finding.MessageKey); message != "" {
+			return message
+		}
+	}
+	return finding.Message
+}
+
+// caretIndent returns the whitespace that lines a caret up under column of line, keeping tabs
+// so the caret moves with them
+func caretIndent(line string, column int) string {
+	var indent strings.Builder
+	for i, char := range []rune(line) {
+		if i >= column-1 {
+			break
+		}
+		if char == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	return indent.String()
+}
+
+// PrintFindings shows findings with the script lines they point at, skipping info findings
+// unless withInfo is set
+func PrintFindings(findings []policy.Finding, withInfo bool) {
+	for _, finding := range findings {
+		if finding.Severity != policy.SeverityInfo || withInfo {
+			fmt.Print(FormatFinding(finding))
+		}
+	}
+}
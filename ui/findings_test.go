@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"please/localization"
+	"please/policy"
+)
+
+func Test_when_finding_has_position_then_point_caret_at_column_of_numbered_line(t *testing.T) {
+	// Arrange
+	finding := policy.Finding{
+		Rule: "shutdown", Severity: policy.SeverityHigh, Message: "Will shutdown the system",
+		Line: 12, Column: 7, Snippet: "\tsudo shutdown -h now", Remediation: "Schedule it with a delay",
+	}
+
+	// Act
+	lines := strings.Split(FormatFinding(finding), "\n")
+
+	// Assert
+	if len(lines) != 5 || !strings.Contains(lines[0], "🔴 WARNING: Will shutdown the system") || !strings.Contains(lines[0], "[shutdown]") {
+		t.Fatalf("Expected the message, excerpt and remediation, got %q", lines)
+	}
+	if !strings.Contains(lines[1], " 12│"+ColorReset+" \tsudo shutdown -h now") {
+		t.Errorf("Expected the line numbered as in the script listing, got %q", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "│"+ColorReset+" \t     "+ColorRed+"^"+ColorReset) {
+		t.Errorf("Expected the caret under shutdown, keeping the tab, got %q", lines[2])
+	}
+	if !strings.Contains(lines[3], "💡 Schedule it with a delay") {
+		t.Errorf("Expected the remediation, got %q", lines[3])
+	}
+}
+
+func Test_when_finding_has_no_position_then_show_only_message(t *testing.T) {
+	// Arrange
+	finding := policy.Finding{Rule: "short-script", Severity: policy.SeverityInfo, Message: "Script seems very short"}
+
+	// Act
+	result := FormatFinding(finding)
+
+	// Assert
+	if strings.Count(result, "\n") != 1 || strings.Contains(result, "│") {
+		t.Errorf("Expected a single line without an excerpt, got %q", result)
+	}
+}
+
+func Test_when_language_translates_finding_then_show_translation_or_fall_back_to_message(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	langPath := filepath.Join(dir, "es-es.json")
+	os.WriteFile(langPath, []byte(`{"language":"es-es","messages":{"risks":{"shutdown":"Apagará o reiniciará el sistema"}}}`), 0644)
+	mgr, _ := localization.NewLocalizationManager(dir)
+	mgr.LoadLanguage("es-es", langPath)
+	mgr.SetLanguage("es-es")
+	SetGlobalLocalizationManager(mgr)
+	defer SetGlobalLocalizationManager(nil)
+	translated := policy.Finding{Rule: "shutdown", Severity: policy.SeverityHigh, MessageKey: "risks.shutdown", Message: "Will shutdown the system"}
+	untranslated := policy.Finding{Rule: "chown-root", Severity: policy.SeverityHigh, MessageKey: "risks.chown_root", Message: "Changes ownership to root"}
+	reworded := policy.Finding{Rule: "shutdown", Severity: policy.SeverityHigh, Message: "Ask ops before rebooting"}
+
+	// Act
+	results := []string{FormatFinding(translated), FormatFinding(untranslated), FormatFinding(reworded)}
+
+	// Assert
+	for i, expected := range []string{"Apagará o reiniciará el sistema", "Changes ownership to root", "Ask ops before rebooting"} {
+		if !strings.Contains(results[i], expected) {
+			t.Errorf("Expected %q, got %q", expected, results[i])
+		}
+	}
+}
@@ -48,6 +48,37 @@ func showHelpWithBanner(bannerFunc func()) {
 	fmt.Printf("  %s--monitor-tests%s    %sAlias for --test-monitor%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
 	fmt.Printf("  %spls --test-monitor TestName%s %sAnalyze specific test pattern%s\n\n", ColorGreen, ColorReset, ColorDim, ColorReset)
 
+	fmt.Printf("%s🧠 Models:%s\n", ColorBold+ColorYellow, ColorReset)
+	fmt.Printf("  %smodels list [provider]%s     %sList models with their rank scores (--refresh to re-query)%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %smodels explain \"<task>\"%s   %sShow the task category and why a model is chosen%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %smodels pin <category> <model>%s %sAlways use a model for a category (\"auto\" to unpin)%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %smodels stats%s               %sShow how each model's scripts fared and the score it earned%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %sranking.json%s               %sIn the config folder, overrides model scores and boosts%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %slexicons.json%s              %sIn the config folder, adds keywords used to categorize tasks%s\n\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+
+	fmt.Printf("%s🖥️  Environment Context:%s\n", ColorBold+ColorYellow, ColorReset)
+	fmt.Printf("  %scontext%s            %sPreview the machine details sent with each request%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %s--no-context%s       %sSend only the task, without OS, shell or tool details%s\n\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+
+	fmt.Printf("%s📝 Prompt Templates:%s\n", ColorBold+ColorYellow, ColorReset)
+	fmt.Printf("  %sprompts show [name]%s  %sList the templates, or print the one in use%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %sprompts edit <name>%s  %sOverride a template, e.g. house_style for team conventions%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %sprompts reset <name>%s %sGo back to the built-in template%s\n\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+
+	fmt.Printf("%s🛡️  Risk Policy:%s\n", ColorBold+ColorYellow, ColorReset)
+	fmt.Printf("  %spolicy test <script>%s %sShow which risk rules fire on a script and what Please would ask; add --json for tools%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %spolicy.json%s          %sRules in /etc/please, the config folder and .please/ add checks or change built-in ones%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %s--dry-run%s            %sRun the script in a sandbox first and list the files it would change (Linux)%s\n\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+
+	fmt.Printf("%s📦 Response Cache:%s\n", ColorBold+ColorYellow, ColorReset)
+	fmt.Printf("  %scache stats%s        %sShow cached script count, size and hit rate%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %scache clear%s        %sRemove all cached scripts%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %s--no-cache%s         %sAlways ask the provider for a fresh script%s\n\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+
+	fmt.Printf("%s📊 Usage Tracking:%s\n", ColorBold+ColorYellow, ColorReset)
+	fmt.Printf("  %susage%s              %sShow tokens and spend by day, provider and model%s\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+	fmt.Printf("  %susage --days=N%s     %sLimit the report to the last N days (default 30)%s\n\n", ColorGreen, ColorReset, ColorDim, ColorReset)
+
 	fmt.Printf("%s🎨 Features:%s\n", ColorBold+ColorYellow, ColorReset)
 	fmt.Printf("  %s🌍 Cross-platform%s (Windows PowerShell, Linux/macOS Bash)\n", ColorCyan, ColorReset)
 	fmt.Printf("  %s🧠 Multiple AI providers%s (Ollama, OpenAI, Anthropic)\n", ColorCyan, ColorReset)
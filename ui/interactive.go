@@ -2,9 +2,11 @@ package ui
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -12,9 +14,12 @@ import (
 
 	"please/config"
 	"please/localization"
+	"please/models"
+	"please/policy"
 	"please/providers"
 	"please/script"
 	"please/types"
+	"please/usage"
 )
 
 // InputProvider interface abstracts input operations for testability
@@ -225,6 +230,7 @@ func ShowScriptMenu(response *types.ScriptResponse) {
 	items := []MenuItem{
 		{Label: "Copy to clipboard", Icon: "📋", Color: ColorCyan, Action: func() bool { copyToClipboard(response); return false }},
 		{Label: "Execute script now", Icon: "▶️ ", Color: ColorYellow, Action: func() bool { executeScript(response); return false }},
+		{Label: "Execute in sandbox", Icon: "🧪", Color: ColorGreen, Action: func() bool { DryRunScript(response); return false }},
 		{Label: "Save to file", Icon: "💾", Color: ColorBlue, Action: func() bool { saveToFile(response); return false }},
 		{Label: "Edit script", Icon: "✏️ ", Color: ColorPurple, Action: func() bool { editScript(response); return false }},
 		{Label: "Refine script with AI", Icon: "🧠", Color: ColorMagenta, Action: func() bool { refineScript(response); return false }},
@@ -235,7 +241,7 @@ func ShowScriptMenu(response *types.ScriptResponse) {
 			return true
 		}},
 	}
-	renderMenu("🎯 What would you like to do with this script?", "Press 1-8: ", items, nil)
+	renderMenu("🎯 What would you like to do with this script?", "Press 1-9: ", items, nil)
 }
 
 // renderMenu displays a menu from a slice of MenuItem and handles user input
@@ -344,112 +350,117 @@ func copyToClipboard(response *types.ScriptResponse) {
 
 // executeScript executes the script with smart safety levels and automatic error recovery
 func executeScript(response *types.ScriptResponse) {
-	executed := false
-
-	// Get script warnings and determine risk level
-	warnings := script.ValidateScript(response)
-	riskLevel := determineRiskLevel(warnings)
+	// Get script findings and determine risk level from the actions the policy asks for
+	findings := script.AssessScript(response)
+	riskLevel := determineRiskLevel(findings)
+	if !confirmExecution(riskLevel, findings) {
+		return
+	}
 
-	switch riskLevel {
-	case "green":
-		// Low risk - execute immediately with brief message
-		fmt.Printf("%s✅ Executing safe script...%s\n", ColorGreen, ColorReset)
-		executed = true
-		if err := script.ExecuteScript(response); err != nil {
-			fmt.Printf("%s❌ Script execution failed: %v%s\n", ColorRed, err, ColorReset)
-			// Attempt automatic fix
+	if err := script.ExecuteScript(response); err != nil {
+		fmt.Printf("%s❌ Script execution failed: %v%s\n", ColorRed, err, ColorReset)
+		recordOutcome(response, models.OutcomeFailed)
+		// For high-risk scripts, ask before attempting auto-fix
+		attemptFix := true
+		if riskLevel == "red" {
+			fmt.Printf("%s❓ Attempt automatic fix? Press 'y' to try or any other key to skip: %s", ColorBold+ColorYellow, ColorReset)
+			fixChoice := getSingleKeyInput()
+			fmt.Printf("%c\n", fixChoice)
+			attemptFix = fixChoice == 'y' || fixChoice == 'Y'
+		}
+		if attemptFix {
 			tryAutoFix(response, err.Error())
-		} else {
-			fmt.Printf("%s✅ Script execution completed!%s\n", ColorGreen, ColorReset)
 		}
+	} else {
+		fmt.Printf("%s✅ Script execution completed!%s\n", ColorGreen, ColorReset)
+		recordOutcome(response, models.OutcomeSuccess)
+	}
+
+	// Save to history once executed (whether successful or not)
+	saveToHistory(response)
+}
+
+// confirmExecution shows the findings and asks for the confirmation the risk level needs.
+// It reports whether the script may run; scripts the policy blocks never may.
+func confirmExecution(riskLevel string, findings []policy.Finding) bool {
+	switch riskLevel {
+	case "blocked":
+		printBlockedFindings(findings)
+		return false
 
 	case "yellow":
 		// Medium risk - single confirmation
-		if len(warnings) > 0 {
-			fmt.Printf("%s⚠️  Script has some warnings:%s\n", ColorYellow, ColorReset)
-			for _, warning := range warnings {
-				if strings.HasPrefix(warning, "🟡") {
-					fmt.Printf("  %s%s%s\n", ColorYellow, warning, ColorReset)
-				}
-			}
-		}
+		printRiskFindings(findings)
 		fmt.Printf("%s❓ Press 'y' to continue or any other key to cancel: %s", ColorBold+ColorYellow, ColorReset)
 		choice := getSingleKeyInput()
 		fmt.Printf("%c\n", choice)
 
-		if choice == 'y' || choice == 'Y' {
-			fmt.Printf("%s▶️  Executing script...%s\n", ColorGreen, ColorReset)
-			executed = true
-			if err := script.ExecuteScript(response); err != nil {
-				fmt.Printf("%s❌ Script execution failed: %v%s\n", ColorRed, err, ColorReset)
-				// Attempt automatic fix
-				tryAutoFix(response, err.Error())
-			} else {
-				fmt.Printf("%s✅ Script execution completed!%s\n", ColorGreen, ColorReset)
-			}
-		} else {
+		if choice != 'y' && choice != 'Y' {
 			fmt.Printf("%s🚫 Script execution cancelled.%s\n", ColorYellow, ColorReset)
+			return false
 		}
+		fmt.Printf("%s▶️  Executing script...%s\n", ColorGreen, ColorReset)
+		return true
 
 	case "red":
 		// High risk - detailed warning flow
 		fmt.Printf("%s🚨 HIGH RISK SCRIPT DETECTED!%s\n", ColorRed+ColorBold, ColorReset)
-		for _, warning := range warnings {
-			if strings.HasPrefix(warning, "🔴") || strings.HasPrefix(warning, "⛔") {
-				fmt.Printf("  %s%s%s\n", ColorRed, warning, ColorReset)
-			}
-		}
+		PrintFindings(findings, false)
 		fmt.Printf("\n%s🛡️  SAFETY WARNING: This script contains potentially dangerous operations!%s\n", ColorRed+ColorBold, ColorReset)
 		fmt.Printf("%s❓ Type 'EXECUTE' to proceed or anything else to cancel: %s", ColorBold+ColorRed, ColorReset)
 
 		reader := bufio.NewReader(os.Stdin)
 		input, _ := reader.ReadString('\n')
 
-		if strings.TrimSpace(input) == "EXECUTE" {
-			fmt.Printf("%s⚠️  Executing high-risk script...%s\n", ColorRed, ColorReset)
-			executed = true
-			if err := script.ExecuteScript(response); err != nil {
-				fmt.Printf("%s❌ Script execution failed: %v%s\n", ColorRed, err, ColorReset)
-				// For high-risk scripts, ask before attempting auto-fix
-				fmt.Printf("%s❓ Attempt automatic fix? Press 'y' to try or any other key to skip: %s", ColorBold+ColorYellow, ColorReset)
-				fixChoice := getSingleKeyInput()
-				fmt.Printf("%c\n", fixChoice)
-				if fixChoice == 'y' || fixChoice == 'Y' {
-					tryAutoFix(response, err.Error())
-				}
-			} else {
-				fmt.Printf("%s✅ Script execution completed!%s\n", ColorGreen, ColorReset)
-			}
-		} else {
+		if strings.TrimSpace(input) != "EXECUTE" {
 			fmt.Printf("%s🚫 Script execution cancelled for safety.%s\n", ColorYellow, ColorReset)
+			return false
 		}
-	}
+		fmt.Printf("%s⚠️  Executing high-risk script...%s\n", ColorRed, ColorReset)
+		return true
 
-	// Save to history if executed (whether successful or not)
-	if executed {
-		saveToHistory(response)
+	default:
+		printRiskFindings(findings)
+		// Low risk - execute immediately with brief message
+		fmt.Printf("%s✅ Executing safe script...%s\n", ColorGreen, ColorReset)
+		return true
 	}
 }
 
-// determineRiskLevel analyzes warnings to determine overall risk level
-func determineRiskLevel(warnings []string) string {
-	hasRed := false
-	hasYellow := false
+// determineRiskLevel picks the execution flow for the strictest action the findings ask for
+func determineRiskLevel(findings []policy.Finding) string {
+	switch policy.Strictest(findings) {
+	case policy.ActionBlock:
+		return "blocked"
+	case policy.ActionTypedExecute:
+		return "red"
+	case policy.ActionConfirm:
+		return "yellow"
+	default:
+		return "green"
+	}
+}
 
-	for _, warning := range warnings {
-		if strings.HasPrefix(warning, "⛔") || strings.HasPrefix(warning, "🔴") {
-			hasRed = true
-		} else if strings.HasPrefix(warning, "🟡") {
-			hasYellow = true
+// printBlockedFindings explains that the policy forbids running the script, listing the findings that block it
+func printBlockedFindings(findings []policy.Finding) {
+	fmt.Printf("%s🚫 BLOCKED BY POLICY%s\n", ColorRed+ColorBold, ColorReset)
+	for _, finding := range findings {
+		if finding.Action == policy.ActionBlock {
+			fmt.Print(FormatFinding(finding))
 		}
 	}
+	fmt.Printf("%s💡 See which rules fire and where they come from with: please policy test <script>%s\n", ColorDim, ColorReset)
+}
 
-	if hasRed {
-		return "red"
-	} else if hasYellow {
-		return "yellow"
+// printRiskFindings lists the findings above info level before the user decides to run the script
+func printRiskFindings(findings []policy.Finding) {
+	for _, finding := range findings {
+		if finding.Severity != policy.SeverityInfo {
+			fmt.Printf("%s⚠️  Script has some warnings:%s\n", ColorYellow, ColorReset)
+			PrintFindings(findings, false)
+			return
+		}
 	}
-	return "green"
 }
 
 // saveToFile saves the script to a file
@@ -490,6 +501,7 @@ func editScript(response *types.ScriptResponse) {
 			} else if editedResponse != response {
 				*response = *editedResponse
 				fmt.Printf("%s🎯 Updated script is now active in the menu%s\n", ColorGreen, ColorReset)
+				recordOutcome(response, models.OutcomeEdited)
 			}
 			return true
 		}},
@@ -499,6 +511,7 @@ func editScript(response *types.ScriptResponse) {
 			} else if editedResponse != response {
 				*response = *editedResponse
 				fmt.Printf("%s🎯 Updated script is now active in the menu%s\n", ColorGreen, ColorReset)
+				recordOutcome(response, models.OutcomeEdited)
 			}
 			return true
 		}},
@@ -507,9 +520,23 @@ func editScript(response *types.ScriptResponse) {
 	renderMenu("✏️  Edit Script", "Press 1-3: ", items, nil)
 }
 
-// Stub for refineScript to allow menu to compile and work
-func refineScript(response *types.ScriptResponse) {
-	fmt.Printf("%s🧠 Refine script with AI feature coming soon!%s\n", ColorMagenta, ColorReset)
+// PrintScriptNotes shows the model's summary and declared risks for a structured response
+func PrintScriptNotes(response *types.ScriptResponse) {
+	if response.Summary != "" {
+		fmt.Printf("\n%s💬 %s%s\n", ColorCyan, response.Summary, ColorReset)
+	}
+	printDeclaredRisks(response)
+}
+
+// printDeclaredRisks lists the risky operations the model declared in its own script
+func printDeclaredRisks(response *types.ScriptResponse) {
+	if len(response.Risks) == 0 {
+		return
+	}
+	fmt.Printf("\n%s⚠️  Risks declared by the model:%s\n", ColorBold+ColorYellow, ColorReset)
+	for _, risk := range response.Risks {
+		fmt.Printf("  %s• %s%s %s— %s%s\n", ColorYellow, risk.Operation, ColorReset, ColorDim, risk.Reason, ColorReset)
+	}
 }
 
 // showDetailedExplanation shows a detailed breakdown of the script
@@ -521,6 +548,9 @@ func showDetailedExplanation(response *types.ScriptResponse) {
 	fmt.Printf("  %s• Original request:%s %s\n", ColorDim, ColorReset, response.TaskDescription)
 	fmt.Printf("  %s• Script type:%s %s\n", ColorDim, ColorReset, response.ScriptType)
 	fmt.Printf("  %s• AI model used:%s %s (%s)\n", ColorDim, ColorReset, response.Model, response.Provider)
+	if response.Summary != "" {
+		fmt.Printf("  %s• Summary:%s %s\n", ColorDim, ColorReset, response.Summary)
+	}
 
 	fmt.Printf("\n%s🔍 Script Analysis:%s\n", ColorBold+ColorYellow, ColorReset)
 
@@ -544,6 +574,17 @@ func showDetailedExplanation(response *types.ScriptResponse) {
 	fmt.Printf("  %s• Comment lines:%s %d\n", ColorDim, ColorReset, commentCount)
 	fmt.Printf("  %s• Command lines:%s %d\n", ColorDim, ColorReset, commandCount)
 
+	if len(response.Explanation) > 0 {
+		fmt.Printf("\n%s🪜 Step by Step:%s\n", ColorBold+ColorYellow, ColorReset)
+		for i, step := range response.Explanation {
+			fmt.Printf("  %s%d.%s %s\n", ColorDim, i+1, ColorReset, step)
+		}
+	}
+	if len(response.RequiredTools) > 0 {
+		fmt.Printf("\n%s🧰 Required Tools:%s %s\n", ColorBold+ColorYellow, ColorReset, strings.Join(response.RequiredTools, ", "))
+	}
+	printDeclaredRisks(response)
+
 	fmt.Printf("\n%s💡 Usage Tips:%s\n", ColorBold+ColorYellow, ColorReset)
 	if response.ScriptType == "powershell" {
 		fmt.Printf("  %s• Run in PowerShell with:%s ./script.ps1\n", ColorDim, ColorReset)
@@ -858,10 +899,14 @@ func tryAutoFix(originalResponse *types.ScriptResponse, errorMessage string) {
 		return
 	}
 
-	stopProgress := ShowProviderProgress(originalResponse.Provider, "Auto-fixing script")
-	defer stopProgress()
+	// Ctrl+C cancels the provider call instead of killing Please
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	stopProgress := ShowCancellableProviderProgress(ctx, originalResponse.Provider, "Auto-fixing script")
 
-	fixedScript, err := providers.GenerateFixedScript(
+	fixed, err := providers.GenerateFixedResponseContext(
+		ctx,
 		originalResponse.Script,
 		errorMessage,
 		originalResponse.ScriptType,
@@ -870,37 +915,75 @@ func tryAutoFix(originalResponse *types.ScriptResponse, errorMessage string) {
 		cfg,
 	)
 
-	stopProgress()
+	stopProgress(err)
+	cancel()
 
 	if err != nil {
 		printAutoFixError(err, originalResponse)
 		return
 	}
+	recordUsage(cfg, fixed)
+
 	fixedResponse := &types.ScriptResponse{
 		TaskDescription: "Auto-fix for: " + originalResponse.TaskDescription,
-		Script:          fixedScript,
+		Script:          fixed.Script,
 		ScriptType:      originalResponse.ScriptType,
 		Model:           originalResponse.Model,
 		Provider:        originalResponse.Provider,
 	}
 
 	printAutoFixSuccess(fixedResponse)
+	runFixedScript(originalResponse, fixedResponse, script.AssessScript(fixedResponse))
+}
+
+// runFixedScript runs an auto-fixed script once the policy gate allows it. The fix is a new
+// script, so it gets the same assessment and confirmation as the one it replaces.
+func runFixedScript(originalResponse, fixedResponse *types.ScriptResponse, findings []policy.Finding) {
+	if !confirmExecution(determineRiskLevel(findings), findings) {
+		return
+	}
 
 	if err := script.ExecuteScript(fixedResponse); err != nil {
 		printAutoFixError(err, fixedResponse)
 	} else {
 		fmt.Printf("%s✅ Fixed script executed successfully!%s\n", ColorGreen, ColorReset)
+		recordOutcome(originalResponse, models.OutcomeAutoFixed)
 		originalResponse.Script = fixedResponse.Script
 		originalResponse.TaskDescription = fixedResponse.TaskDescription
 	}
 }
 
+// recordOutcome feeds what happened to a script back into model selection; failures only cost the data point
+func recordOutcome(response *types.ScriptResponse, outcome models.Outcome) {
+	models.RecordOutcome(response.Model, response.Provider, response.TaskDescription, outcome)
+}
+
+// recordUsage prices a provider call made from the menu and logs it for "please usage"
+func recordUsage(cfg *types.Config, response *types.ScriptResponse) {
+	if err := usage.Track(cfg, response); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not record usage (%v)\n", err)
+	}
+}
+
 // Print auto-fix error and suggestions, then show next-step menu
 func printAutoFixError(err error, response *types.ScriptResponse) {
 	fmt.Printf("%s❌ Auto-fix failed: %v%s\n", ColorRed, err, ColorReset)
 	fmt.Printf("%s💡 Suggestions:%s\n", ColorBold+ColorYellow, ColorReset)
+	aiAvailable := true
+	if providerErr, ok := providers.AsProviderError(err); ok {
+		if remediation := providerErr.Remediation(); remediation != "" {
+			fmt.Printf("  • %s\n", remediation)
+		}
+		// Asking the AI again won't help if the provider itself is unusable
+		switch providerErr.Kind {
+		case providers.ErrorNotConfigured, providers.ErrorAuthentication, providers.ErrorQuotaExceeded, providers.ErrorNetwork:
+			aiAvailable = false
+		}
+	}
 	fmt.Printf("  • Try editing the script manually\n")
-	fmt.Printf("  • Ask AI to explain the error\n")
+	if aiAvailable {
+		fmt.Printf("  • Ask AI to explain the error\n")
+	}
 	fmt.Printf("  • View documentation or help\n")
 	showPostActionMenu(response)
 }
@@ -914,7 +997,6 @@ func printAutoFixSuccess(fixedResponse *types.ScriptResponse) {
 		fmt.Printf("%s%3d│%s %s\n", ColorDim, i+1, ColorReset, line)
 	}
 	fmt.Printf("%s%s%s\n", ColorDim, strings.Repeat("─", 60), ColorReset)
-	fmt.Printf("%s🚀 Testing fixed script...%s\n", ColorGreen, ColorReset)
 }
 
 // Show a next-step menu after auto-fix or error
@@ -1,18 +1,24 @@
 package ui
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"please/policy"
+	"please/types"
+)
 
 // Test determineRiskLevel function
-func Test_when_warnings_contain_red_prefix_then_return_red(t *testing.T) {
+func Test_when_findings_require_typed_execute_then_return_red(t *testing.T) {
 	// Arrange
-	warnings := []string{
-		"🟡 Medium risk operation",
-		"⛔ CRITICAL: High risk detected",
-		"🟡 Another medium warning",
+	findings := []policy.Finding{
+		{Severity: policy.SeverityCaution, Action: policy.ActionConfirm},
+		{Severity: policy.SeverityCritical, Action: policy.ActionTypedExecute},
+		{Severity: policy.SeverityCaution, Action: policy.ActionConfirm},
 	}
 
 	// Act
-	result := determineRiskLevel(warnings)
+	result := determineRiskLevel(findings)
 
 	// Assert
 	if result != "red" {
@@ -20,60 +26,59 @@ func Test_when_warnings_contain_red_prefix_then_return_red(t *testing.T) {
 	}
 }
 
-func Test_when_warnings_contain_only_red_color_prefix_then_return_red(t *testing.T) {
+func Test_when_findings_only_require_confirm_then_return_yellow(t *testing.T) {
 	// Arrange
-	warnings := []string{
-		"🔴 High risk operation detected",
+	findings := []policy.Finding{
+		{Severity: policy.SeverityCaution, Action: policy.ActionConfirm},
+		{Severity: policy.SeverityInfo, Action: policy.ActionWarn},
 	}
 
 	// Act
-	result := determineRiskLevel(warnings)
+	result := determineRiskLevel(findings)
 
 	// Assert
-	if result != "red" {
-		t.Errorf("Expected 'red', got '%s'", result)
+	if result != "yellow" {
+		t.Errorf("Expected 'yellow', got '%s'", result)
 	}
 }
 
-func Test_when_warnings_contain_only_yellow_prefix_then_return_yellow(t *testing.T) {
+func Test_when_policy_downgrades_high_finding_to_warn_then_return_green(t *testing.T) {
 	// Arrange
-	warnings := []string{
-		"🟡 Medium risk operation",
-		"🟡 Another medium warning",
+	findings := []policy.Finding{
+		{Severity: policy.SeverityHigh, Action: policy.ActionWarn},
 	}
 
 	// Act
-	result := determineRiskLevel(warnings)
+	result := determineRiskLevel(findings)
 
 	// Assert
-	if result != "yellow" {
-		t.Errorf("Expected 'yellow', got '%s'", result)
+	if result != "green" {
+		t.Errorf("Expected the action, not the severity, to decide; got '%s'", result)
 	}
 }
 
-func Test_when_warnings_contain_no_risk_prefixes_then_return_green(t *testing.T) {
+func Test_when_any_finding_blocks_then_return_blocked(t *testing.T) {
 	// Arrange
-	warnings := []string{
-		"✅ Safe operation",
-		"ℹ️ Information message",
-		"This is a normal warning",
+	findings := []policy.Finding{
+		{Severity: policy.SeverityCritical, Action: policy.ActionTypedExecute},
+		{Severity: policy.SeverityCaution, Action: policy.ActionBlock},
 	}
 
 	// Act
-	result := determineRiskLevel(warnings)
+	result := determineRiskLevel(findings)
 
 	// Assert
-	if result != "green" {
-		t.Errorf("Expected 'green', got '%s'", result)
+	if result != "blocked" {
+		t.Errorf("Expected 'blocked', got '%s'", result)
 	}
 }
 
-func Test_when_warnings_is_empty_then_return_green(t *testing.T) {
+func Test_when_findings_is_empty_then_return_green(t *testing.T) {
 	// Arrange
-	warnings := []string{}
+	findings := []policy.Finding{}
 
 	// Act
-	result := determineRiskLevel(warnings)
+	result := determineRiskLevel(findings)
 
 	// Assert
 	if result != "green" {
@@ -81,12 +86,12 @@ func Test_when_warnings_is_empty_then_return_green(t *testing.T) {
 	}
 }
 
-func Test_when_warnings_is_nil_then_return_green(t *testing.T) {
+func Test_when_findings_is_nil_then_return_green(t *testing.T) {
 	// Arrange
-	var warnings []string = nil
+	var findings []policy.Finding = nil
 
 	// Act
-	result := determineRiskLevel(warnings)
+	result := determineRiskLevel(findings)
 
 	// Assert
 	if result != "green" {
@@ -94,20 +99,20 @@ func Test_when_warnings_is_nil_then_return_green(t *testing.T) {
 	}
 }
 
-func Test_when_warnings_contain_both_red_and_yellow_then_return_red(t *testing.T) {
+func Test_when_policy_blocks_auto_fixed_script_then_refuse_to_run_it(t *testing.T) {
 	// Arrange
-	warnings := []string{
-		"🟡 Medium risk operation",
-		"⛔ CRITICAL: High risk detected",
-		"🟡 Another medium warning",
-		"🔴 High risk operation",
-	}
+	original := &types.ScriptResponse{Script: "rm -rf ./build", ScriptType: "bash", TaskDescription: "clean build"}
+	fixed := &types.ScriptResponse{Script: "rm -rf /", ScriptType: "bash", TaskDescription: "Auto-fix for: clean build"}
+	findings := []policy.Finding{{Rule: "delete-root", Severity: policy.SeverityCritical, Message: "Attempts to delete entire filesystem", Action: policy.ActionBlock}}
 
 	// Act
-	result := determineRiskLevel(warnings)
+	output := captureStdout(func() { runFixedScript(original, fixed, findings) })
 
 	// Assert
-	if result != "red" {
-		t.Errorf("Expected 'red' when both red and yellow present, got '%s'", result)
+	if !strings.Contains(output, "BLOCKED BY POLICY") || !strings.Contains(output, "Attempts to delete entire filesystem") {
+		t.Errorf("Expected the blocking finding, got:\n%s", output)
+	}
+	if strings.Contains(output, "Executing") || original.Script != "rm -rf ./build" {
+		t.Errorf("Expected the blocked fix not to run or replace the original, got:\n%s", output)
 	}
 }
@@ -1,8 +1,11 @@
 package ui
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	"please/script"
 	"please/types"
 )
 
@@ -27,6 +30,43 @@ func Test_when_showing_detailed_explanation_then_display_script_analysis(t *test
 	}()
 }
 
+func Test_when_showing_detailed_explanation_of_structured_response_then_display_steps_tools_and_risks(t *testing.T) {
+	// Arrange
+	response := &types.ScriptResponse{
+		TaskDescription: "clean old logs",
+		Script:          "find /var/log -name '*.gz' -delete",
+		ScriptType:      "bash",
+		Summary:         "Deletes compressed logs",
+		Explanation:     []string{"Find compressed logs under /var/log", "Delete each one"},
+		RequiredTools:   []string{"find"},
+		Risks:           []types.ScriptRisk{{Operation: "find -delete", Reason: "removes files permanently"}},
+		Structured:      true,
+	}
+
+	// Act
+	output := captureOutput(func() { showDetailedExplanation(response) })
+
+	// Assert
+	for _, expected := range []string{"Deletes compressed logs", "2.\033[0m Delete each one", "Required Tools:", "find -delete", "removes files permanently"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func Test_when_printing_notes_of_text_response_then_print_nothing(t *testing.T) {
+	// Arrange
+	response := &types.ScriptResponse{Script: "echo hi", ScriptType: "bash"}
+
+	// Act
+	output := captureOutput(func() { PrintScriptNotes(response) })
+
+	// Assert
+	if output != "" {
+		t.Errorf("Expected no notes for a plain-text response, got %q", output)
+	}
+}
+
 func Test_when_copying_to_clipboard_then_handle_success_and_failure(t *testing.T) {
 	// Given: A script response
 	response := &types.ScriptResponse{
@@ -313,23 +353,48 @@ func Test_when_edit_script_then_show_options_menu(t *testing.T) {
 	}
 }
 
-func Test_when_refine_script_then_show_coming_soon_message(t *testing.T) {
-	// Given: A script response
-	response := &types.ScriptResponse{
-		Script: "echo 'refine test'",
+func Test_when_refining_in_session_then_show_diff_and_undo_to_earlier_version(t *testing.T) {
+	// Arrange
+	session := script.NewRefinementSession(&types.ScriptResponse{Script: "echo one", ScriptType: "bash", TaskDescription: "print"})
+	input := &TestInputProvider{
+		Keys:  []rune{'1', '3', '4'},
+		Lines: []string{"print two as well\n", "1\n"},
+	}
+	var requests []string
+	refine := func(session *script.RefinementSession, request string) error {
+		requests = append(requests, request)
+		_, err := session.Refine(context.Background(), &stubProvider{script: "echo one\necho two"}, request)
+		return err
 	}
 
-	// When: Refining script (should not panic)
-	func() {
-		defer func() {
-			if r := recover(); r != nil {
-				t.Errorf("refineScript panicked: %v", r)
-			}
-		}()
-		refineScript(response)
-	}()
+	// Act
+	output := captureOutput(func() { runRefinementSession(session, input, refine) })
+
+	// Assert
+	if len(requests) != 1 || requests[0] != "print two as well" {
+		t.Errorf("Expected one refinement request, got %q", requests)
+	}
+	if !strings.Contains(output, "+ echo two") {
+		t.Errorf("Expected the diff to show the added line, got:\n%s", output)
+	}
+	if len(session.Versions) != 2 || session.CurrentIndex() != 0 {
+		t.Errorf("Expected two versions with the original current after undo, got %d at %d", len(session.Versions), session.CurrentIndex())
+	}
+}
+
+// stubProvider returns the same script for every request
+type stubProvider struct {
+	script string
 }
 
+func (p *stubProvider) GenerateScript(ctx context.Context, request *types.ScriptRequest) (*types.ScriptResponse, error) {
+	return &types.ScriptResponse{Script: p.script}, nil
+}
+
+func (p *stubProvider) Name() string { return "stub" }
+
+func (p *stubProvider) IsConfigured(config *types.Config) bool { return true }
+
 // Test UIService dependency injection functionality
 func Test_when_creating_ui_service_with_valid_config_dir_then_initialize_successfully(t *testing.T) {
 	// Given: A temporary config directory
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -193,6 +194,47 @@ func ShowProgressWithSteps(messages []string) {
 
 // ShowProviderProgress displays provider-specific progress indication
 func ShowProviderProgress(provider, operation string) func() {
+	progress := NewProgressIndicator(providerProgressMessage(provider, operation))
+	progress.Start()
+
+	// Return a function to stop the progress
+	return func() {
+		progress.Stop()
+		fmt.Printf("%s✓ %s completed%s\n", ColorGreen, operation, ColorReset)
+	}
+}
+
+// ShowCancellableProviderProgress displays provider progress that also stops as soon as ctx is cancelled.
+// The returned function stops the indicator and reports completion for a nil error or cancellation when ctx is done.
+func ShowCancellableProviderProgress(ctx context.Context, provider, operation string) func(err error) {
+	progress := NewProgressIndicator(providerProgressMessage(provider, operation))
+	progress.Start()
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			progress.Stop()
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			close(done)
+			progress.Stop()
+			if err == nil {
+				fmt.Printf("%s✓ %s completed%s\n", ColorGreen, operation, ColorReset)
+			} else if ctx.Err() != nil {
+				fmt.Printf("%s✗ %s cancelled%s\n", ColorYellow, operation, ColorReset)
+			}
+		})
+	}
+}
+
+// providerProgressMessage builds the progress message with provider-specific context
+func providerProgressMessage(provider, operation string) string {
 	message := fmt.Sprintf("🤖 %s using %s", operation, provider)
 
 	// Add provider-specific context
@@ -205,14 +247,7 @@ func ShowProviderProgress(provider, operation string) func() {
 		message += " (via Anthropic API)"
 	}
 
-	progress := NewProgressIndicator(message)
-	progress.Start()
-
-	// Return a function to stop the progress
-	return func() {
-		progress.Stop()
-		fmt.Printf("%s✓ %s completed%s\n", ColorGreen, operation, ColorReset)
-	}
+	return message
 }
 
 // ShowSimpleProgress shows a simple progress indicator with message
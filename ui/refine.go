@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"please/config"
+	"please/models"
+	"please/script"
+	"please/types"
+)
+
+// refineFunc asks for a new version of the session's current script
+type refineFunc func(session *script.RefinementSession, request string) error
+
+// refineScript runs a refinement session on response; the version the user ends on replaces it
+func refineScript(response *types.ScriptResponse) {
+	session := script.NewRefinementSession(response)
+	runRefinementSession(session, &DefaultInputProvider{}, refineWithProvider)
+
+	if session.CurrentIndex() != 0 {
+		*response = *session.Current()
+		fmt.Printf("%s🎯 Version %d is now active in the menu%s\n", ColorGreen, session.CurrentIndex()+1, ColorReset)
+		recordOutcome(response, models.OutcomeRefined)
+	}
+}
+
+// refineWithProvider sends a refinement request through the provider that generated the script
+func refineWithProvider(session *script.RefinementSession, request string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	// Ctrl+C cancels the provider call instead of killing Please
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	stopProgress := ShowCancellableProviderProgress(ctx, session.Current().Provider, "Refining script")
+	response, err := session.RefineWithConfiguredProvider(ctx, request, cfg)
+	stopProgress(err)
+	if err != nil {
+		return err
+	}
+
+	recordUsage(cfg, response)
+	return nil
+}
+
+// runRefinementSession shows the refinement menu until the user is done
+func runRefinementSession(session *script.RefinementSession, input InputProvider, refine refineFunc) {
+	fmt.Printf("\n%s💡 Describe a change, e.g. \"%s\". Each request builds on the conversation so far.%s\n",
+		ColorDim, script.GetRefinementPromptSuggestions()[0], ColorReset)
+
+	for {
+		again := false
+		title := fmt.Sprintf("🧠 Refine Script (version %d of %d)", session.CurrentIndex()+1, len(session.Versions))
+		items := []MenuItem{
+			{Label: "Ask for a change", Icon: "💬", Color: ColorMagenta, Action: func() bool {
+				askForRefinement(session, input, refine)
+				again = true
+				return true
+			}},
+			{Label: "Show changes from previous version", Icon: "🔀", Color: ColorCyan, Action: func() bool {
+				printVersionDiff(session)
+				again = true
+				return true
+			}},
+			{Label: "Undo to an earlier version", Icon: "↩️ ", Color: ColorYellow, Action: func() bool {
+				chooseVersion(session, input)
+				again = true
+				return true
+			}},
+			{Label: "Done refining", Icon: "✅", Color: ColorGreen, Action: func() bool { return true }},
+		}
+		// Each action returns to the menu, so the title shows the current version; Enter or Done leaves
+		renderMenu(title, "Press 1-4: ", items, input.GetSingleKey)
+		if !again {
+			return
+		}
+	}
+}
+
+// askForRefinement reads the user's instruction, refines the script and shows what changed
+func askForRefinement(session *script.RefinementSession, input InputProvider, refine refineFunc) {
+	fmt.Printf("%sWhat should change? (press Enter to go back): %s", ColorYellow, ColorReset)
+	line, _ := input.GetLine()
+	request := strings.TrimSpace(line)
+	if request == "" {
+		return
+	}
+
+	if err := refine(session, request); err != nil {
+		fmt.Printf("%s❌ Refinement failed: %v%s\n", ColorRed, err, ColorReset)
+		return
+	}
+	fmt.Printf("%s✨ Version %d created%s\n", ColorGreen, session.CurrentIndex()+1, ColorReset)
+	printVersionDiff(session)
+	PrintScriptNotes(session.Current())
+}
+
+// printVersionDiff shows the current version against the one it was refined from
+func printVersionDiff(session *script.RefinementSession) {
+	previous := session.Previous()
+	if previous == nil {
+		fmt.Printf("%sThis is the original script; there is no earlier version to compare with%s\n", ColorDim, ColorReset)
+		return
+	}
+
+	fmt.Printf("\n%s🔀 Changes in version %d%s\n", ColorBold+ColorCyan, session.CurrentIndex()+1, ColorReset)
+	fmt.Printf("%s%s%s\n", ColorDim, strings.Repeat("─", 60), ColorReset)
+	for _, line := range script.DiffScripts(previous.Script, session.Current().Script) {
+		switch line.Kind {
+		case script.DiffAdded:
+			fmt.Printf("%s+ %s%s\n", ColorGreen, line.Text, ColorReset)
+		case script.DiffRemoved:
+			fmt.Printf("%s- %s%s\n", ColorRed, line.Text, ColorReset)
+		default:
+			fmt.Printf("%s  %s%s\n", ColorDim, line.Text, ColorReset)
+		}
+	}
+	fmt.Printf("%s%s%s\n", ColorDim, strings.Repeat("─", 60), ColorReset)
+}
+
+// chooseVersion lists the versions and makes the one the user picks current
+func chooseVersion(session *script.RefinementSession, input InputProvider) {
+	fmt.Printf("\n%s📚 Versions%s\n", ColorBold+ColorCyan, ColorReset)
+	for i, version := range session.Versions {
+		label := "original script"
+		if version.Parent >= 0 {
+			label = fmt.Sprintf("%q (from version %d)", version.Request, version.Parent+1)
+		}
+		marker := " "
+		if i == session.CurrentIndex() {
+			marker = "*"
+		}
+		fmt.Printf("  %s%s %d.%s %s\n", ColorGreen, marker, i+1, ColorReset, label)
+	}
+
+	fmt.Printf("%sVersion to go back to (press Enter to keep version %d): %s", ColorYellow, session.CurrentIndex()+1, ColorReset)
+	line, _ := input.GetLine()
+	choice := strings.TrimSpace(line)
+	if choice == "" {
+		return
+	}
+	number, err := strconv.Atoi(choice)
+	if err == nil {
+		err = session.Restore(number - 1)
+	}
+	if err != nil {
+		fmt.Printf("%s❌ %s is not a version in this session%s\n", ColorRed, choice, ColorReset)
+		return
+	}
+	fmt.Printf("%s↩️  Version %d is current; new changes will build on it%s\n", ColorGreen, number, ColorReset)
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"please/policy"
+	"please/script"
+	"please/types"
+)
+
+// dryRunListLimit is how many paths of each kind the dry-run report lists before summarizing
+const dryRunListLimit = 20
+
+// DryRunScript shows the script's findings, then runs it in the sandbox and shows what it would
+// have changed in the working directory. Scripts the risk policy blocks are never run, not even
+// in the sandbox.
+func DryRunScript(response *types.ScriptResponse) {
+	dryRunAssessed(response, script.AssessScript(response))
+}
+
+// dryRunAssessed is DryRunScript with the script's findings already assessed
+func dryRunAssessed(response *types.ScriptResponse, findings []policy.Finding) {
+	if policy.Strictest(findings) == policy.ActionBlock {
+		printBlockedFindings(findings)
+		return
+	}
+	printRiskFindings(findings)
+
+	fmt.Printf("%s🧪 Running in a sandbox: the system is read-only, there is no network, and changes are thrown away%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s%s%s\n", ColorDim, strings.Repeat("─", 50), ColorReset)
+
+	report, err := script.DryRunScript(response)
+	fmt.Printf("%s%s%s\n", ColorDim, strings.Repeat("─", 50), ColorReset)
+	if err != nil {
+		fmt.Printf("%s❌ Dry run failed: %v%s\n", ColorRed, err, ColorReset)
+		return
+	}
+	printDryRunReport(report)
+}
+
+// printDryRunReport lists the files the script created, modified and deleted
+func printDryRunReport(report *script.DryRunReport) {
+	status := ColorGreen + "✅ Dry run finished"
+	if report.ExitCode != 0 {
+		status = fmt.Sprintf("%s⚠️  Dry run exited with code %d", ColorYellow, report.ExitCode)
+	}
+	fmt.Printf("%s%s (%s sandbox)%s\n", ColorBold, status, report.Sandbox, ColorReset)
+
+	if !report.Changed() {
+		fmt.Printf("  %sNo files in %s would change%s\n", ColorDim, report.WorkDir, ColorReset)
+	} else {
+		fmt.Printf("  %sChanges the script would make in %s:%s\n", ColorDim, report.WorkDir, ColorReset)
+		printDryRunPaths("+ created ", ColorGreen, report.Created)
+		printDryRunPaths("~ modified", ColorYellow, report.Modified)
+		printDryRunPaths("- deleted ", ColorRed, report.Deleted)
+	}
+	fmt.Printf("  %s💡 Writes outside the working directory failed or went to a temporary /tmp, so they aren't listed%s\n", ColorDim, ColorReset)
+}
+
+// printDryRunPaths lists paths of one kind of change, summarizing beyond dryRunListLimit
+func printDryRunPaths(label, color string, paths []string) {
+	for i, path := range paths {
+		if i == dryRunListLimit {
+			fmt.Printf("    %s… and %d more%s\n", ColorDim, len(paths)-dryRunListLimit, ColorReset)
+			return
+		}
+		fmt.Printf("    %s%s%s %s\n", color, label, ColorReset, path)
+	}
+}
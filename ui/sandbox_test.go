@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"please/policy"
+	"please/script"
+	"please/types"
+)
+
+func Test_when_policy_blocks_script_then_dry_run_does_not_run_it(t *testing.T) {
+	// Arrange
+	response := &types.ScriptResponse{Script: "rm -rf /", ScriptType: "bash"}
+	findings := []policy.Finding{{Rule: "delete-root", Severity: policy.SeverityCritical, Message: "Attempts to delete entire filesystem", Action: policy.ActionBlock}}
+
+	// Act
+	output := captureStdout(func() { dryRunAssessed(response, findings) })
+
+	// Assert
+	if !strings.Contains(output, "BLOCKED BY POLICY") || !strings.Contains(output, "Attempts to delete entire filesystem") {
+		t.Errorf("Expected the blocking finding, got:\n%s", output)
+	}
+	if strings.Contains(output, "Running in a sandbox") {
+		t.Errorf("Expected the blocked script not to run in the sandbox, got:\n%s", output)
+	}
+}
+
+func Test_when_dry_run_changed_files_then_list_each_kind(t *testing.T) {
+	// Arrange
+	report := &script.DryRunReport{
+		Sandbox: "namespaces", WorkDir: "/home/user/project", ExitCode: 1,
+		Created: []string{"logs/"}, Modified: []string{"config.yaml"}, Deleted: []string{"old/", "notes.txt"},
+	}
+
+	// Act
+	output := captureStdout(func() { printDryRunReport(report) })
+
+	// Assert
+	for _, expected := range []string{"exited with code 1 (namespaces sandbox)", "+ created " + ColorReset + " logs/", "~ modified" + ColorReset + " config.yaml", "- deleted " + ColorReset + " notes.txt"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in the report, got:\n%s", expected, output)
+		}
+	}
+}
+
+func Test_when_dry_run_deleted_many_files_then_summarize_the_rest(t *testing.T) {
+	// Arrange
+	report := &script.DryRunReport{Sandbox: "bubblewrap", WorkDir: "/srv"}
+	for i := 0; i < dryRunListLimit+5; i++ {
+		report.Deleted = append(report.Deleted, fmt.Sprintf("file%d", i))
+	}
+
+	// Act
+	output := captureStdout(func() { printDryRunReport(report) })
+
+	// Assert
+	if strings.Count(output, "- deleted") != dryRunListLimit || !strings.Contains(output, "… and 5 more") {
+		t.Errorf("Expected %d deletions and a summary, got:\n%s", dryRunListLimit, output)
+	}
+}
+
+func Test_when_dry_run_changed_nothing_then_say_so(t *testing.T) {
+	// Arrange
+	report := &script.DryRunReport{Sandbox: "namespaces", WorkDir: "/srv"}
+
+	// Act
+	output := captureStdout(func() { printDryRunReport(report) })
+
+	// Assert
+	if !strings.Contains(output, "Dry run finished") || !strings.Contains(output, "No files in /srv would change") {
+		t.Errorf("Expected a clean report, got:\n%s", output)
+	}
+}